@@ -14,6 +14,21 @@ type FilterConfig struct {
 		MinPrice   float64 `yaml:"min_price"`
 		MaxPrice   float64 `yaml:"max_price"`
 		MinStars   float64 `yaml:"min_stars"`
+
+		// Case-insensitive substring of the card location; listings without a location pass
+		LocationContains string `yaml:"location_contains"`
+
+		// Keep only listings whose card shows a discount badge
+		DiscountedOnly bool `yaml:"discounted_only"`
+
+		// Applied after enrichment, to listings whose detail page was parsed
+		SuperhostOnly     bool `yaml:"superhost_only"`
+		GuestFavoriteOnly bool `yaml:"guest_favorite_only"`
+		MaxMinimumNights  int  `yaml:"max_minimum_nights"` // 0 = no limit
+
+		// Per-person nightly cap in USD (price in USD / guest capacity), applied after
+		// enrichment; listings without a USD price or guest count pass. 0 = no limit
+		MaxPricePerGuest float64 `yaml:"max_price_per_guest"`
 	} `yaml:"filters"`
 }
 
@@ -0,0 +1,296 @@
+package currency
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bnb-fetcher/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSymbolCodes maps the currency symbols recognized out of the box to ISO codes
+var defaultSymbolCodes = map[string]string{
+	"$": "USD",
+	"€": "EUR",
+	"£": "GBP",
+	"¥": "JPY",
+	"฿": "THB",
+	"₫": "VND",
+}
+
+// symbolCodes maps the currency symbols the parser recognizes to ISO codes: the defaults,
+// or the defaults merged with the file at CURRENCY_MAP_PATH once Configure is called
+var symbolCodes = defaultCodes()
+
+// Settings are the currency options taken from the environment
+type Settings struct {
+	SymbolCodes map[string]string // recognized symbol -> ISO code, see SettingsFromEnv
+}
+
+// SettingsFromEnv reads the currency settings from CURRENCY_MAP_PATH
+func SettingsFromEnv() Settings {
+	return Settings{SymbolCodes: symbolCodesFromEnv()}
+}
+
+// Configure replaces the currency settings. Call it once at startup, before prices are
+// parsed or formatted: the symbol map is read without locking, and parsers built
+// earlier keep the symbols they were built with.
+func Configure(s Settings) {
+	symbolCodes = s.SymbolCodes
+	if symbolCodes == nil {
+		symbolCodes = defaultCodes()
+	}
+}
+
+// defaultCodes returns a copy of defaultSymbolCodes
+func defaultCodes() map[string]string {
+	codes := make(map[string]string, len(defaultSymbolCodes))
+	for symbol, code := range defaultSymbolCodes {
+		codes[symbol] = code
+	}
+	return codes
+}
+
+// symbolCodesFromEnv returns defaultSymbolCodes merged with the map in the file at
+// CURRENCY_MAP_PATH, if set. The file is a JSON or YAML object of symbol -> ISO code,
+// e.g. {"₱": "PHP", "R$": "BRL", "zł": "PLN"}; its entries replace defaults for the same
+// symbol. Prices in new currencies only get a USD price once USD_RATES has their rate.
+func symbolCodesFromEnv() map[string]string {
+	codes := defaultCodes()
+	path := os.Getenv("CURRENCY_MAP_PATH")
+	if path == "" {
+		return codes
+	}
+	extra, err := LoadSymbolCodes(path)
+	if err != nil {
+		log.Printf("Warning: Invalid CURRENCY_MAP_PATH=%q (%v), using the built-in currency symbols\n", path, err)
+		return codes
+	}
+	for symbol, code := range extra {
+		codes[symbol] = code
+	}
+	log.Printf("Using CURRENCY_MAP_PATH=%s (%d symbols)\n", path, len(extra))
+	return codes
+}
+
+// LoadSymbolCodes reads a symbol -> ISO code map from a JSON or YAML file. Entries with
+// an empty symbol or a code that is not three letters are logged and skipped.
+func LoadSymbolCodes(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read currency map: %w", err)
+	}
+	// JSON is valid YAML, so one decoder reads both
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse currency map: %w", err)
+	}
+
+	codes := make(map[string]string, len(raw))
+	for symbol, code := range raw {
+		symbol = strings.TrimSpace(symbol)
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if symbol == "" || !isCode(code) {
+			log.Printf("Warning: Ignoring invalid currency map entry %q: %q\n", symbol, code)
+			continue
+		}
+		codes[symbol] = code
+	}
+	return codes, nil
+}
+
+// isCode reports whether s looks like an ISO 4217 code: three upper-case ASCII letters
+func isCode(s string) bool {
+	return len(s) == 3 && strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// Symbols returns the recognized currency symbols, longest first so that a pattern built
+// from them matches "R$" before "$"
+func Symbols() []string {
+	symbols := make([]string, 0, len(symbolCodes))
+	for symbol := range symbolCodes {
+		symbols = append(symbols, symbol)
+	}
+	sortLongestFirst(symbols)
+	return symbols
+}
+
+// Codes returns the ISO codes of the recognized currencies
+func Codes() []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, code := range symbolCodes {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sortLongestFirst(codes)
+	return codes
+}
+
+// sortLongestFirst sorts by length in bytes, then alphabetically for a stable order
+func sortLongestFirst(values []string) {
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+}
+
+// DefaultRates returns approximate USD values of one unit of each currency the parser
+// recognizes. They only need to be close enough to make prices comparable; set
+// USD_RATES to override them.
+func DefaultRates() map[string]float64 {
+	return map[string]float64{
+		"USD": 1,
+		"EUR": 1.08,
+		"GBP": 1.27,
+		"THB": 0.028,
+		"VND": 0.00004,
+	}
+}
+
+// Code normalizes a currency symbol or code ("$", "usd") to an upper-case ISO code
+func Code(currency string) string {
+	currency = strings.TrimSpace(currency)
+	if code, ok := symbolCodes[currency]; ok {
+		return code
+	}
+	return strings.ToUpper(currency)
+}
+
+// priceFormat is how prices in one currency are displayed
+type priceFormat struct {
+	symbol   string
+	decimals int
+}
+
+// priceFormats maps ISO codes to their display format. Currencies without minor units in
+// practice (baht, dong, yen) are shown without decimals.
+var priceFormats = map[string]priceFormat{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"THB": {"฿", 0},
+	"VND": {"₫", 0},
+}
+
+// zeroDecimalCodes are the ISO 4217 currencies without minor units that have no entry
+// in priceFormats; they are shown with their code but without decimals ("KRW 45000")
+var zeroDecimalCodes = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "KMF": true,
+	"KRW": true, "PYG": true, "RWF": true, "UGX": true, "VUV": true, "XAF": true,
+	"XOF": true, "XPF": true,
+}
+
+// FormatPrice formats a price for display ("฿1250", "$85.50"). Prices without a currency
+// are assumed to be in THB; other currencies are shown with their symbol from the symbol
+// map ("₱1500.00"), or with their ISO code when it has none ("CHF 120.00", "KRW 45000").
+func FormatPrice(price float64, currency string) string {
+	code := Code(currency)
+	if code == "" {
+		code = "THB"
+	}
+	format, ok := priceFormats[code]
+	if !ok {
+		decimals := 2
+		if zeroDecimalCodes[code] {
+			decimals = 0
+		}
+		if symbol := symbolOf(code); symbol != "" {
+			return fmt.Sprintf("%s%.*f", symbol, decimals, price)
+		}
+		return fmt.Sprintf("%s %.*f", code, decimals, price)
+	}
+	return fmt.Sprintf("%s%.*f", format.symbol, format.decimals, price)
+}
+
+// symbolOf returns the symbol that maps to code in the symbol map, or "" when none or
+// several do: with several, none is clearly the one to show. Codes mapped to themselves
+// ("CHF": "CHF") are not symbols.
+func symbolOf(code string) string {
+	symbol := ""
+	for s, c := range symbolCodes {
+		if c != code || s == code {
+			continue
+		}
+		if symbol != "" {
+			return ""
+		}
+		symbol = s
+	}
+	return symbol
+}
+
+// Converter converts prices to USD using fixed rates
+type Converter struct {
+	rates map[string]float64 // ISO code -> USD per unit
+}
+
+// NewConverter creates a Converter from ISO code -> USD-per-unit rates. USD is always
+// convertible.
+func NewConverter(rates map[string]float64) *Converter {
+	c := &Converter{rates: map[string]float64{"USD": 1}}
+	for code, rate := range rates {
+		if rate > 0 {
+			c.rates[Code(code)] = rate
+		}
+	}
+	return c
+}
+
+// NewConverterFromEnv creates a Converter from DefaultRates, overridden by USD_RATES
+// ("EUR=1.08,THB=0.028"). Malformed entries are logged and skipped.
+func NewConverterFromEnv() *Converter {
+	rates := DefaultRates()
+	if value := os.Getenv("USD_RATES"); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			code, rateStr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+			if !ok || err != nil || rate <= 0 {
+				log.Printf("Warning: Ignoring invalid USD_RATES entry %q\n", entry)
+				continue
+			}
+			rates[Code(code)] = rate
+		}
+	}
+	return NewConverter(rates)
+}
+
+// ToUSD converts amount in the given currency (symbol or code) to USD.
+// Returns false if the currency has no known rate.
+func (c *Converter) ToUSD(amount float64, currency string) (float64, bool) {
+	rate, ok := c.rates[Code(currency)]
+	if !ok {
+		return 0, false
+	}
+	return amount * rate, true
+}
+
+// ApplyUSDPrices sets PriceUSD on each listing with a known price. Listings in a
+// currency without a rate are left at 0 and logged once per currency.
+func (c *Converter) ApplyUSDPrices(listings []models.Listing) {
+	unknown := make(map[string]bool)
+	for i := range listings {
+		if listings[i].Price <= 0 {
+			continue
+		}
+		usd, ok := c.ToUSD(listings[i].Price, listings[i].Currency)
+		if !ok {
+			if !unknown[listings[i].Currency] {
+				unknown[listings[i].Currency] = true
+				log.Printf("Warning: No USD rate for currency %q, leaving Price (USD) blank\n", listings[i].Currency)
+			}
+			continue
+		}
+		listings[i].PriceUSD = usd
+	}
+}
@@ -0,0 +1,152 @@
+package currency
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToUSD(t *testing.T) {
+	c := NewConverter(map[string]float64{"THB": 0.03, "eur": 1.1})
+
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		want     float64
+		wantOK   bool
+	}{
+		{"usd symbol", 100, "$", 100, true},
+		{"usd code", 100, "USD", 100, true},
+		{"baht symbol", 1000, "฿", 30, true},
+		{"lower-case rate key", 10, "€", 11, true},
+		{"unknown currency", 100, "JPY", 0, false},
+		{"empty currency", 100, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.ToUSD(tt.amount, tt.currency)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ToUSD(%v, %q) = %v, %v; want %v, %v", tt.amount, tt.currency, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewConverterFromEnv(t *testing.T) {
+	t.Setenv("USD_RATES", "THB=0.05, bogus, JPY=0.0067")
+	c := NewConverterFromEnv()
+
+	if got, _ := c.ToUSD(100, "฿"); math.Abs(got-5) > 1e-9 {
+		t.Errorf("THB override: got %v, want 5", got)
+	}
+	if _, ok := c.ToUSD(100, "JPY"); !ok {
+		t.Error("JPY from USD_RATES should be convertible")
+	}
+	if _, ok := c.ToUSD(100, "EUR"); !ok {
+		t.Error("EUR default rate should still apply")
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$", "USD"},
+		{"¥", "JPY"},
+		{"฿", "THB"}, // baht sign
+		{"₫", "VND"}, // dong sign
+		{" jpy ", "JPY"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Code(tt.input); got != tt.want {
+			t.Errorf("Code(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		price    float64
+		currency string
+		want     string
+	}{
+		{85.5, "USD", "$85.50"},
+		{85.5, "$", "$85.50"},
+		{120, "€", "€120.00"},
+		{99.99, "GBP", "£99.99"},
+		{1250, "THB", "฿1250"},
+		{950000, "₫", "₫950000"},
+		{12000, "JPY", "¥12000"},
+		{12000, "¥", "¥12000"},
+		{45000, "krw", "KRW 45000"},
+		{3500.4, "CLP", "CLP 3500"},
+		{1250, "", "฿1250"}, // no currency parsed
+		{120, "CHF", "CHF 120.00"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatPrice(tt.price, tt.currency); got != tt.want {
+			t.Errorf("FormatPrice(%v, %q) = %q, want %q", tt.price, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestSymbolCodesFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"json", `{"₱": "PHP", "R$": "brl", "zł": "PLN", "CHF": "CHF", "$": "USD", "bad": "dollars"}`},
+		{"yaml", "₱: PHP\nR$: brl\nzł: PLN\nCHF: CHF\nbad: dollars\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "currencies")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("CURRENCY_MAP_PATH", path)
+
+			Configure(SettingsFromEnv())
+			defer Configure(Settings{})
+
+			for symbol, want := range map[string]string{"₱": "PHP", "R$": "BRL", "zł": "PLN", "€": "EUR", "bad": "BAD"} {
+				if got := Code(symbol); got != want {
+					t.Errorf("Code(%q) = %q, want %q", symbol, got, want)
+				}
+			}
+			if symbols := Symbols(); indexOf(symbols, "R$") > indexOf(symbols, "$") {
+				t.Errorf("Symbols() = %q, want R$ before $", symbols)
+			}
+			if got := FormatPrice(1500, "PHP"); got != "₱1500.00" {
+				t.Errorf("FormatPrice(1500, PHP) = %q, want the symbol from the map", got)
+			}
+			if got := FormatPrice(120, "CHF"); got != "CHF 120.00" {
+				t.Errorf("FormatPrice(120, CHF) = %q, want the code", got)
+			}
+		})
+	}
+}
+
+func TestSymbolCodesFromEnvInvalidFile(t *testing.T) {
+	t.Setenv("CURRENCY_MAP_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	if codes := symbolCodesFromEnv(); len(codes) != len(defaultSymbolCodes) || codes["$"] != "USD" {
+		t.Errorf("symbolCodesFromEnv() with a missing file = %v, want the defaults", codes)
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ConfigExport is the portable form of a user's settings used by /exportconfig and
+// /importconfig, and stored by presets and saved searches. Fields missing from an import
+// keep their current value.
+type ConfigExport struct {
+	MaxPages              *int     `json:"max_pages,omitempty"`
+	MaxTotalPages         *int     `json:"max_total_pages,omitempty"`
+	MaxListings           *int     `json:"max_listings,omitempty"`
+	MinReviews            *int     `json:"min_reviews,omitempty"`
+	MinPrice              *float64 `json:"min_price,omitempty"`
+	MaxPrice              *float64 `json:"max_price,omitempty"`
+	MinStars              *float64 `json:"min_stars,omitempty"`
+	SuperhostOnly         *bool    `json:"superhost_only,omitempty"`
+	GuestFavoriteOnly     *bool    `json:"guest_favorite_only,omitempty"`
+	MaxMinimumNights      *int     `json:"max_minimum_nights,omitempty"`
+	LocationContains      *string  `json:"location_contains,omitempty"`
+	SortBy                *string  `json:"sort_by,omitempty"`
+	DedupScope            *string  `json:"dedup_scope,omitempty"`
+	AutoWidenMin          *int     `json:"auto_widen_min,omitempty"`
+	PriceStep             *int     `json:"price_step,omitempty"`
+	IncludeSimilarDates   *bool    `json:"include_similar_dates,omitempty"`
+	DiscountedOnly        *bool    `json:"discounted_only,omitempty"`
+	EnrichFields          *string  `json:"enrich_fields,omitempty"`
+	MaxPricePerGuest      *float64 `json:"max_price_per_guest,omitempty"`
+	AppendSavedSearches   *bool    `json:"append_saved_searches,omitempty"`
+	SpreadsheetPerRequest *bool    `json:"spreadsheet_per_request,omitempty"`
+}
+
+// ExportUserConfig encodes all of the user's settings as compact JSON
+func ExportUserConfig(userConfig *UserConfig) string {
+	export := ConfigExport{
+		MaxPages:              &userConfig.MaxPages,
+		MaxTotalPages:         &userConfig.MaxTotalPages,
+		MaxListings:           &userConfig.MaxListings,
+		MinReviews:            &userConfig.MinReviews,
+		MinPrice:              &userConfig.MinPrice,
+		MaxPrice:              &userConfig.MaxPrice,
+		MinStars:              &userConfig.MinStars,
+		SuperhostOnly:         &userConfig.SuperhostOnly,
+		GuestFavoriteOnly:     &userConfig.GuestFavoriteOnly,
+		MaxMinimumNights:      &userConfig.MaxMinimumNights,
+		LocationContains:      &userConfig.LocationContains,
+		SortBy:                &userConfig.SortBy,
+		DedupScope:            &userConfig.DedupScope,
+		IncludeSimilarDates:   &userConfig.IncludeSimilarDates,
+		DiscountedOnly:        &userConfig.DiscountedOnly,
+		EnrichFields:          &userConfig.EnrichFields,
+		MaxPricePerGuest:      &userConfig.MaxPricePerGuest,
+		AppendSavedSearches:   &userConfig.AppendSavedSearches,
+		SpreadsheetPerRequest: &userConfig.SpreadsheetPerRequest,
+		AutoWidenMin:          &userConfig.AutoWidenMin,
+		PriceStep:             &userConfig.PriceStep,
+	}
+	data, _ := json.Marshal(export) // plain values only, cannot fail
+	return string(data)
+}
+
+// ApplySnapshot overrides the settings with the ones of an ExportUserConfig text, such
+// as the settings a saved search was made with. Settings left out of it are kept.
+func (c *UserConfig) ApplySnapshot(snapshot string) error {
+	var export ConfigExport
+	if err := json.Unmarshal([]byte(snapshot), &export); err != nil {
+		return fmt.Errorf("malformed config snapshot: %w", err)
+	}
+	if export.MaxPages != nil {
+		c.MaxPages = *export.MaxPages
+	}
+	if export.MaxTotalPages != nil {
+		c.MaxTotalPages = *export.MaxTotalPages
+	}
+	if export.MaxListings != nil {
+		c.MaxListings = *export.MaxListings
+	}
+	if export.MinReviews != nil {
+		c.MinReviews = *export.MinReviews
+	}
+	if export.MinPrice != nil {
+		c.MinPrice = *export.MinPrice
+	}
+	if export.MaxPrice != nil {
+		c.MaxPrice = *export.MaxPrice
+	}
+	if export.MinStars != nil {
+		c.MinStars = *export.MinStars
+	}
+	if export.SuperhostOnly != nil {
+		c.SuperhostOnly = *export.SuperhostOnly
+	}
+	if export.GuestFavoriteOnly != nil {
+		c.GuestFavoriteOnly = *export.GuestFavoriteOnly
+	}
+	if export.MaxMinimumNights != nil {
+		c.MaxMinimumNights = *export.MaxMinimumNights
+	}
+	if export.LocationContains != nil {
+		c.LocationContains = *export.LocationContains
+	}
+	if export.SortBy != nil {
+		c.SortBy = *export.SortBy
+	}
+	if export.DedupScope != nil {
+		c.DedupScope = *export.DedupScope
+	}
+	if export.AutoWidenMin != nil {
+		c.AutoWidenMin = *export.AutoWidenMin
+	}
+	if export.PriceStep != nil {
+		c.PriceStep = *export.PriceStep
+	}
+	if export.IncludeSimilarDates != nil {
+		c.IncludeSimilarDates = *export.IncludeSimilarDates
+	}
+	if export.DiscountedOnly != nil {
+		c.DiscountedOnly = *export.DiscountedOnly
+	}
+	if export.EnrichFields != nil {
+		c.EnrichFields = *export.EnrichFields
+	}
+	if export.MaxPricePerGuest != nil {
+		c.MaxPricePerGuest = *export.MaxPricePerGuest
+	}
+	if export.AppendSavedSearches != nil {
+		c.AppendSavedSearches = *export.AppendSavedSearches
+	}
+	if export.SpreadsheetPerRequest != nil {
+		c.SpreadsheetPerRequest = *export.SpreadsheetPerRequest
+	}
+	return nil
+}
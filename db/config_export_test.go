@@ -0,0 +1,25 @@
+package db
+
+import "testing"
+
+func TestApplySnapshot(t *testing.T) {
+	saved := &UserConfig{MaxPages: 3, MinPrice: 20, MaxPrice: 120, SortBy: "price_asc", PriceStep: 25, SuperhostOnly: true}
+	snapshot := ExportUserConfig(saved)
+
+	// Settings changed after the search was saved, and one the export doesn't carry
+	current := &UserConfig{MaxPages: 10, MinPrice: 50, MaxPrice: 500, SortBy: "rating", PriceStep: 100, ForwardTo: 42}
+	if err := current.ApplySnapshot(snapshot); err != nil {
+		t.Fatalf("ApplySnapshot() error = %v", err)
+	}
+	if current.MaxPages != 3 || current.MinPrice != 20 || current.MaxPrice != 120 || current.SortBy != "price_asc" ||
+		current.PriceStep != 25 || !current.SuperhostOnly {
+		t.Errorf("ApplySnapshot() = %+v, want the saved settings", current)
+	}
+	if current.ForwardTo != 42 {
+		t.Errorf("ApplySnapshot() ForwardTo = %d, want 42 kept", current.ForwardTo)
+	}
+
+	if err := current.ApplySnapshot("not json"); err == nil {
+		t.Error("ApplySnapshot() of a malformed snapshot succeeded")
+	}
+}
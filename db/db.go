@@ -11,7 +11,8 @@ import (
 
 // DB wraps the database connection
 type DB struct {
-	conn *sql.DB
+	conn     *sql.DB
+	flatView string // kind of the listings_flat view for BI tools (FLAT_VIEW env)
 }
 
 // NewDB creates a new database connection
@@ -40,7 +41,7 @@ func NewDB() (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, flatView: flatViewFromEnv()}
 
 	// Initialize schema
 	if err := db.initSchema(); err != nil {
@@ -88,6 +89,23 @@ func (db *DB) initSchema() error {
 			min_price DOUBLE PRECISION NOT NULL DEFAULT 0,
 			max_price DOUBLE PRECISION NOT NULL DEFAULT 2000,
 			min_stars DOUBLE PRECISION NOT NULL DEFAULT 4.0,
+			sort_by VARCHAR(20) NOT NULL DEFAULT 'none',
+			superhost_only BOOLEAN NOT NULL DEFAULT FALSE,
+			guest_favorite_only BOOLEAN NOT NULL DEFAULT FALSE,
+			max_total_pages INTEGER NOT NULL DEFAULT 0,
+			max_listings INTEGER NOT NULL DEFAULT 0,
+			location_contains TEXT NOT NULL DEFAULT '',
+			max_minimum_nights INTEGER NOT NULL DEFAULT 0,
+			dedup_scope VARCHAR(20) NOT NULL DEFAULT 'request',
+			auto_widen_min INTEGER NOT NULL DEFAULT 0,
+			price_step INTEGER NOT NULL DEFAULT 50,
+			include_similar_dates BOOLEAN NOT NULL DEFAULT FALSE,
+			discounted_only BOOLEAN NOT NULL DEFAULT FALSE,
+			enrich_fields TEXT NOT NULL DEFAULT '',
+			max_price_per_guest DOUBLE PRECISION NOT NULL DEFAULT 0,
+			append_saved_searches BOOLEAN NOT NULL DEFAULT FALSE,
+			spreadsheet_per_request BOOLEAN NOT NULL DEFAULT FALSE,
+			forward_to BIGINT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
@@ -160,6 +178,8 @@ func (db *DB) initSchema() error {
 			score DOUBLE PRECISION,
 			full_text TEXT NOT NULL,
 			time_on_airbnb VARCHAR(255),
+			reviewer_name VARCHAR(255),
+			reviewer_location VARCHAR(255),
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
@@ -187,6 +207,100 @@ func (db *DB) initSchema() error {
 		return fmt.Errorf("failed to create search_links table: %w", err)
 	}
 
+	// Create filter_presets table for named user config snapshots
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS filter_presets (
+			user_id BIGINT NOT NULL,
+			name VARCHAR(32) NOT NULL,
+			settings TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, name)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create filter_presets table: %w", err)
+	}
+
+	// Create service_settings table for service-wide switches set by the admin
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS service_settings (
+			key VARCHAR(64) PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create service_settings table: %w", err)
+	}
+
+	// Create saved_searches table for searches re-run daily at a fixed time or hourly
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id SERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			url TEXT NOT NULL,
+			run_at VARCHAR(5) NOT NULL,
+			run_every VARCHAR(10) NOT NULL DEFAULT 'daily',
+			last_run_date DATE,
+			last_run_at TIMESTAMP,
+			config TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create saved_searches table: %w", err)
+	}
+
+	// Create saved_search_listings table: every listing a saved search's runs kept, with
+	// the price it was last seen at, to tell a run's new listings and price drops
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_search_listings (
+			saved_search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+			listing_path TEXT NOT NULL,
+			url TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			price DOUBLE PRECISION NOT NULL DEFAULT 0,
+			currency VARCHAR(10) NOT NULL DEFAULT '',
+			seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (saved_search_id, listing_path)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create saved_search_listings table: %w", err)
+	}
+
+	// Create run_listings table: the listings a saved search run kept so far, repeats of
+	// earlier runs included, so a paused or restarted run is still compared as a whole
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS run_listings (
+			id SERIAL PRIMARY KEY,
+			request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			price DOUBLE PRECISION NOT NULL DEFAULT 0,
+			currency VARCHAR(10) NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create run_listings table: %w", err)
+	}
+
+	// Create feedback table for /feedback reports, with the request and settings they refer to
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS feedback (
+			id SERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			request_id INTEGER REFERENCES requests(id) ON DELETE SET NULL,
+			message TEXT NOT NULL,
+			config TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create feedback table: %w", err)
+	}
+
 	// Add link_number column to listings table if it doesn't exist
 	_, err = db.conn.Exec(`
 		ALTER TABLE listings ADD COLUMN IF NOT EXISTS link_number INTEGER
@@ -195,6 +309,267 @@ func (db *DB) initSchema() error {
 		log.Printf("Warning: Failed to add link_number column to listings (may already exist): %v\n", err)
 	}
 
+	// Add fetch_ms column to search_links table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE search_links ADD COLUMN IF NOT EXISTS fetch_ms BIGINT
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add fetch_ms column to search_links (may already exist): %v\n", err)
+	}
+
+	// Add sheet_gid column to requests table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS sheet_gid BIGINT
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add sheet_gid column to requests (may already exist): %v\n", err)
+	}
+
+	// Add skip_enrichment column to requests table if it doesn't exist (/quick requests)
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS skip_enrichment BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add skip_enrichment column to requests (may already exist): %v\n", err)
+	}
+
+	// Add run_every and last_run_at columns to saved_searches table if they don't exist (hourly runs)
+	_, err = db.conn.Exec(`
+		ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS run_every VARCHAR(10) NOT NULL DEFAULT 'daily'
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add run_every column to saved_searches (may already exist): %v\n", err)
+	}
+	_, err = db.conn.Exec(`
+		ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS last_run_at TIMESTAMP
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add last_run_at column to saved_searches (may already exist): %v\n", err)
+	}
+
+	// Add config column to saved_searches table if it doesn't exist (settings snapshot)
+	_, err = db.conn.Exec(`
+		ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS config TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add config column to saved_searches (may already exist): %v\n", err)
+	}
+
+	// Add saved_search_id column to requests table if it doesn't exist (scheduled runs)
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS saved_search_id INTEGER
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add saved_search_id column to requests (may already exist): %v\n", err)
+	}
+
+	// Add check_in and check_out columns to requests table if they don't exist (stay dates from the URL)
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS check_in DATE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add check_in column to requests (may already exist): %v\n", err)
+	}
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS check_out DATE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add check_out column to requests (may already exist): %v\n", err)
+	}
+
+	// Add spreadsheet_id column to requests table if it doesn't exist (own spreadsheet of the request)
+	_, err = db.conn.Exec(`
+		ALTER TABLE requests ADD COLUMN IF NOT EXISTS spreadsheet_id TEXT
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add spreadsheet_id column to requests (may already exist): %v\n", err)
+	}
+
+	// Create request_metrics table (stage durations per request, summed across resumes)
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS request_metrics (
+			request_id INTEGER PRIMARY KEY REFERENCES requests(id) ON DELETE CASCADE,
+			browser_launch_ms BIGINT NOT NULL DEFAULT 0,
+			fetch_ms BIGINT NOT NULL DEFAULT 0,
+			enrich_ms BIGINT NOT NULL DEFAULT 0,
+			sheets_ms BIGINT NOT NULL DEFAULT 0,
+			total_ms BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to create request_metrics table: %v\n", err)
+	}
+
+	// Add max_guests column to listings table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE listings ADD COLUMN IF NOT EXISTS max_guests INTEGER
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add max_guests column to listings (may already exist): %v\n", err)
+	}
+
+	// Add min_nights column to listings table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE listings ADD COLUMN IF NOT EXISTS min_nights INTEGER
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add min_nights column to listings (may already exist): %v\n", err)
+	}
+
+	// Add check-in/check-out columns to listings table if they don't exist
+	for _, column := range []string{"check_in TEXT", "check_out TEXT", "self_check_in BOOLEAN"} {
+		_, err = db.conn.Exec(`ALTER TABLE listings ADD COLUMN IF NOT EXISTS ` + column)
+		if err != nil {
+			log.Printf("Warning: Failed to add %s column to listings (may already exist): %v\n", column, err)
+		}
+	}
+
+	// Add sort_by column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS sort_by VARCHAR(20) NOT NULL DEFAULT 'none'
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add sort_by column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add superhost_only column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS superhost_only BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add superhost_only column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add guest_favorite_only column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS guest_favorite_only BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add guest_favorite_only column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add max_total_pages column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS max_total_pages INTEGER NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add max_total_pages column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add max_listings column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS max_listings INTEGER NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add max_listings column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add location_contains column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS location_contains TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add location_contains column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add max_minimum_nights column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS max_minimum_nights INTEGER NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add max_minimum_nights column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add dedup_scope column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS dedup_scope VARCHAR(20) NOT NULL DEFAULT 'request'
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add dedup_scope column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add auto_widen_min column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS auto_widen_min INTEGER NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add auto_widen_min column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add price_step column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS price_step INTEGER NOT NULL DEFAULT 50
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add price_step column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add include_similar_dates column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS include_similar_dates BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add include_similar_dates column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add discounted_only column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS discounted_only BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add discounted_only column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add enrich_fields column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS enrich_fields TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add enrich_fields column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add max_price_per_guest column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS max_price_per_guest DOUBLE PRECISION NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add max_price_per_guest column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add append_saved_searches column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS append_saved_searches BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add append_saved_searches column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add spreadsheet_per_request column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS spreadsheet_per_request BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add spreadsheet_per_request column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add forward_to column to user_configs table if it doesn't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE user_configs ADD COLUMN IF NOT EXISTS forward_to BIGINT NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add forward_to column to user_configs (may already exist): %v\n", err)
+	}
+
+	// Add reviewer_name and reviewer_location columns to listing_reviews table if they don't exist
+	_, err = db.conn.Exec(`
+		ALTER TABLE listing_reviews ADD COLUMN IF NOT EXISTS reviewer_name VARCHAR(255),
+			ADD COLUMN IF NOT EXISTS reviewer_location VARCHAR(255)
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to add reviewer columns to listing_reviews (may already exist): %v\n", err)
+	}
+
 	// Create indexes
 	_, err = db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_requests_status ON requests(status)`)
 	if err != nil {
@@ -206,11 +581,32 @@ func (db *DB) initSchema() error {
 		log.Printf("Warning: Failed to create index on requests.user_id: %v\n", err)
 	}
 
+	_, err = db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_run_listings_request_id ON run_listings(request_id)`)
+	if err != nil {
+		log.Printf("Warning: Failed to create index on run_listings.request_id: %v\n", err)
+	}
+
 	_, err = db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_listings_request_id ON listings(request_id)`)
 	if err != nil {
 		log.Printf("Warning: Failed to create index on listings.request_id: %v\n", err)
 	}
 
+	// Listings are saved with upserts on (request_id, url), so a retried link updates its
+	// rows instead of adding duplicates. Duplicates saved before the index existed are
+	// removed first, keeping the oldest row (reviews of the others go with them).
+	_, err = db.conn.Exec(`
+		DELETE FROM listings l
+		USING listings keep
+		WHERE l.request_id = keep.request_id AND l.url = keep.url AND l.id > keep.id
+	`)
+	if err != nil {
+		log.Printf("Warning: Failed to remove duplicate listings: %v\n", err)
+	}
+	_, err = db.conn.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_listings_request_url ON listings(request_id, url)`)
+	if err != nil {
+		log.Printf("Warning: Failed to create unique index on listings(request_id, url): %v\n", err)
+	}
+
 	_, err = db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_listing_reviews_listing_id ON listing_reviews(listing_id)`)
 	if err != nil {
 		log.Printf("Warning: Failed to create index on listing_reviews.listing_id: %v\n", err)
@@ -236,6 +632,11 @@ func (db *DB) initSchema() error {
 		log.Printf("Warning: Failed to create index on listings.link_number: %v\n", err)
 	}
 
+	// Denormalized view for BI tools; the bot works without it
+	if err := db.createFlatView(); err != nil {
+		log.Printf("Warning: Failed to create listings_flat view: %v\n", err)
+	}
+
 	log.Println("Database schema initialized successfully")
 	return nil
 }
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// The listings_flat view denormalizes the scraped data for BI tools (Metabase, Looker, ...)
+// pointed at the database: one row per stored listing with its request and search link.
+// Its kind is chosen with the FLAT_VIEW environment variable:
+//
+//	view          a plain view, always current (default)
+//	materialized  a materialized view, fast to query, refreshed after every finished request
+//	off           no view
+//
+// Columns:
+//
+//	listing_id, request_id, user_id   ids of the listing, its request and the Telegram user
+//	request_status, requested_at      status and creation time of the request
+//	saved_search_id                   saved search the request is a run of, or null
+//	sheet_name                        sheet tab the request was written to
+//	link_number, search_url           the price range link the listing was found by
+//	title, url, price, currency       search card fields; price is per night as shown
+//	stars, review_count               rating and number of reviews from the search card
+//	is_superhost, is_guest_favorite,  detail page fields, null when the listing was not
+//	bedrooms, bathrooms, beds,        enriched or the field was not extracted
+//	max_guests, min_nights,
+//	check_in, check_out, self_check_in,
+//	description, house_rules
+//	latest_review_date                date of the newest review seen on the detail page
+//	stored_reviews                    number of reviews stored for the listing
+//	listing_status                    pending, saved or failed
+//	scraped_at                        when the listing was stored
+//
+// The same SQL is in migrations/20261016_listings_flat_view.sql for databases the bot
+// can't create objects in.
+const flatViewQuery = `
+	SELECT
+		l.id AS listing_id,
+		r.id AS request_id,
+		r.user_id,
+		r.status AS request_status,
+		r.created_at AS requested_at,
+		r.saved_search_id,
+		r.sheet_name,
+		l.link_number,
+		sl.url AS search_url,
+		l.title,
+		l.url,
+		l.price,
+		l.currency,
+		l.stars,
+		l.review_count,
+		l.is_superhost,
+		l.is_guest_favorite,
+		l.bedrooms,
+		l.bathrooms,
+		l.beds,
+		l.max_guests,
+		l.min_nights,
+		l.check_in,
+		l.check_out,
+		l.self_check_in,
+		l.description,
+		l.house_rules,
+		COALESCE(lr.latest_review_date, l.newest_review_date) AS latest_review_date,
+		COALESCE(lr.stored_reviews, 0) AS stored_reviews,
+		l.status AS listing_status,
+		l.created_at AS scraped_at
+	FROM listings l
+	JOIN requests r ON r.id = l.request_id
+	LEFT JOIN search_links sl ON sl.request_id = l.request_id AND sl.link_number = l.link_number
+	LEFT JOIN (
+		SELECT listing_id, MAX(date) AS latest_review_date, COUNT(*) AS stored_reviews
+		FROM listing_reviews
+		GROUP BY listing_id
+	) lr ON lr.listing_id = l.id`
+
+// Kinds of the listings_flat view, from FLAT_VIEW
+const (
+	flatViewPlain        = "view"
+	flatViewMaterialized = "materialized"
+	flatViewOff          = "off"
+)
+
+// flatViewFromEnv reads FLAT_VIEW, falling back to a plain view when it is unset or invalid
+func flatViewFromEnv() string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("FLAT_VIEW")))
+	switch value {
+	case "":
+		return flatViewPlain
+	case flatViewPlain, flatViewMaterialized, flatViewOff:
+		log.Printf("Using FLAT_VIEW=%s\n", value)
+		return value
+	}
+	log.Printf("Warning: Unknown FLAT_VIEW=%q (expected view, materialized or off), using %s\n", value, flatViewPlain)
+	return flatViewPlain
+}
+
+// createFlatView (re)creates listings_flat as the kind db.flatView asks for, so a new
+// column list or a changed FLAT_VIEW takes effect on startup
+func (db *DB) createFlatView() error {
+	// DROP VIEW fails on a materialized view and the other way round: look up what exists
+	var kind string
+	err := db.conn.QueryRow(`
+		SELECT c.relkind FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = 'listings_flat' AND n.nspname = current_schema()
+	`).Scan(&kind)
+	switch {
+	case err == sql.ErrNoRows:
+		err = nil
+	case err != nil:
+		return fmt.Errorf("failed to look up listings_flat: %w", err)
+	case kind == "v":
+		_, err = db.conn.Exec(`DROP VIEW listings_flat`)
+	case kind == "m":
+		_, err = db.conn.Exec(`DROP MATERIALIZED VIEW listings_flat`)
+	default:
+		return fmt.Errorf("listings_flat exists and is not a view")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to drop listings_flat: %w", err)
+	}
+
+	switch db.flatView {
+	case flatViewPlain:
+		_, err = db.conn.Exec(`CREATE VIEW listings_flat AS` + flatViewQuery)
+	case flatViewMaterialized:
+		_, err = db.conn.Exec(`CREATE MATERIALIZED VIEW listings_flat AS` + flatViewQuery)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create listings_flat: %w", err)
+	}
+	return nil
+}
+
+// RefreshFlatView updates the materialized listings_flat view with the listings stored
+// since the last refresh. Plain views are always current; it does nothing for them.
+func (db *DB) RefreshFlatView() error {
+	if db.flatView != flatViewMaterialized {
+		return nil
+	}
+	if _, err := db.conn.Exec(`REFRESH MATERIALIZED VIEW listings_flat`); err != nil {
+		return fmt.Errorf("failed to refresh listings_flat: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFlatViewMigrationMatchesQuery(t *testing.T) {
+	data, err := os.ReadFile("migrations/20261016_listings_flat_view.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Compare without whitespace differences: the migration is indented with spaces
+	normalize := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	if !strings.Contains(normalize(string(data)), normalize("CREATE VIEW listings_flat AS"+flatViewQuery+";")) {
+		t.Error("the migration's listings_flat view differs from flatViewQuery")
+	}
+}
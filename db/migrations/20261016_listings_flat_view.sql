@@ -0,0 +1,53 @@
+-- Migration: Denormalized listings view for BI tools
+-- Date: 2026-10-16
+-- Description: One row per stored listing with its request, search link and review stats,
+--              for Metabase, Looker and the like. The bot creates it on startup (FLAT_VIEW,
+--              see db/flat_view.go for the columns); run this where it can't create objects.
+--              For a materialized view use CREATE MATERIALIZED VIEW and refresh it with
+--              REFRESH MATERIALIZED VIEW listings_flat.
+
+SET search_path TO telegram_bnb_helper;
+
+DROP VIEW IF EXISTS listings_flat;
+
+CREATE VIEW listings_flat AS
+SELECT
+    l.id AS listing_id,
+    r.id AS request_id,
+    r.user_id,
+    r.status AS request_status,
+    r.created_at AS requested_at,
+    r.saved_search_id,
+    r.sheet_name,
+    l.link_number,
+    sl.url AS search_url,
+    l.title,
+    l.url,
+    l.price,
+    l.currency,
+    l.stars,
+    l.review_count,
+    l.is_superhost,
+    l.is_guest_favorite,
+    l.bedrooms,
+    l.bathrooms,
+    l.beds,
+    l.max_guests,
+    l.min_nights,
+    l.check_in,
+    l.check_out,
+    l.self_check_in,
+    l.description,
+    l.house_rules,
+    COALESCE(lr.latest_review_date, l.newest_review_date) AS latest_review_date,
+    COALESCE(lr.stored_reviews, 0) AS stored_reviews,
+    l.status AS listing_status,
+    l.created_at AS scraped_at
+FROM listings l
+JOIN requests r ON r.id = l.request_id
+LEFT JOIN search_links sl ON sl.request_id = l.request_id AND sl.link_number = l.link_number
+LEFT JOIN (
+    SELECT listing_id, MAX(date) AS latest_review_date, COUNT(*) AS stored_reviews
+    FROM listing_reviews
+    GROUP BY listing_id
+) lr ON lr.listing_id = l.id;
@@ -0,0 +1,15 @@
+-- Migration: Make listings unique per request and URL
+-- Date: 2026-10-16
+-- Description: Removes duplicate listings saved when a link was retried, keeping the oldest row,
+--              and adds the unique index the listing upserts rely on
+
+SET search_path TO telegram_bnb_helper;
+
+-- Reviews of the removed rows are deleted with them (ON DELETE CASCADE)
+DELETE FROM listings l
+USING listings keep
+WHERE l.request_id = keep.request_id
+  AND l.url = keep.url
+  AND l.id > keep.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_listings_request_url ON listings(request_id, url);
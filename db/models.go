@@ -3,22 +3,43 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"bnb-fetcher/models"
+	"bnb-fetcher/searchurl"
+
+	"github.com/lib/pq"
 )
 
 // UserConfig represents user-specific configuration
 type UserConfig struct {
-	UserID     int64
-	MaxPages   int
-	MinReviews int
-	MinPrice   float64
-	MaxPrice   float64
-	MinStars   float64
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	UserID                int64
+	MaxPages              int
+	MaxTotalPages         int // page budget shared by all links of a request, 0 = unlimited
+	MaxListings           int // filtered listings enriched per request, 0 = no cap
+	MinReviews            int
+	MinPrice              float64
+	MaxPrice              float64
+	MinStars              float64
+	SuperhostOnly         bool    // drop enriched listings whose host is not a superhost
+	GuestFavoriteOnly     bool    // drop enriched listings that are not Guest Favorites
+	SortBy                string  // sort option key, see filter.SortOptions
+	LocationContains      string  // keep listings whose location contains this text, "" = any
+	MaxMinimumNights      int     // drop enriched listings requiring a longer stay, 0 = no limit
+	DedupScope            string  // which repeats are dropped, see filter.DedupScopeOptions
+	AutoWidenMin          int     // widen the price range once when fewer listings are kept, 0 = off
+	PriceStep             int     // width in dollars of the price bands a search URL is split into
+	IncludeSimilarDates   bool    // keep listings Airbnb shows under "Available for similar dates"
+	DiscountedOnly        bool    // keep only listings whose card shows a discount badge
+	EnrichFields          string  // comma-separated detail field groups to extract, "" = all
+	MaxPricePerGuest      float64 // USD per guest per night cap applied after enrichment, 0 = no limit
+	AppendSavedSearches   bool    // runs of a saved search append to one tab instead of creating a tab each
+	SpreadsheetPerRequest bool    // each request writes to a new spreadsheet instead of a tab of the shared one
+	ForwardTo             int64   // Telegram user the success messages are also sent to, 0 = nobody
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
 }
 
 // Request represents a scraping request
@@ -31,6 +52,12 @@ type Request struct {
 	ListingsCount     int
 	PagesCount        int
 	SheetName         sql.NullString
+	SheetGID          sql.NullInt64  // numeric sheet ID of SheetName, for deep links and deletion
+	SpreadsheetID     sql.NullString // spreadsheet created for the request; null for a tab of the shared spreadsheet
+	SkipEnrichment    bool           // quick request: write search results without visiting detail pages
+	SavedSearchID     sql.NullInt64  // saved search this request is a scheduled run of
+	CheckIn           sql.NullTime   // stay dates from the search URL; null when prices are "from" estimates
+	CheckOut          sql.NullTime
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
@@ -52,6 +79,11 @@ type Listing struct {
 	Bedrooms         sql.NullFloat64
 	Bathrooms        sql.NullFloat64
 	Beds             sql.NullFloat64
+	MaxGuests        sql.NullInt64
+	MinNights        sql.NullInt64
+	CheckIn          sql.NullString
+	CheckOut         sql.NullString
+	SelfCheckIn      sql.NullBool
 	Description      sql.NullString
 	HouseRules       sql.NullString
 	NewestReviewDate sql.NullTime
@@ -66,7 +98,10 @@ type Review struct {
 	Score        sql.NullFloat64
 	FullText     string
 	TimeOnAirbnb sql.NullString
-	CreatedAt    time.Time
+	// Empty when the detail page didn't show them
+	ReviewerName     sql.NullString
+	ReviewerLocation sql.NullString
+	CreatedAt        time.Time
 }
 
 // SearchLink represents a single search URL within a multi-link request
@@ -83,16 +118,64 @@ type SearchLink struct {
 	UpdatedAt     time.Time
 }
 
+// FilterPreset is a named snapshot of a user's config
+type FilterPreset struct {
+	UserID    int64
+	Name      string
+	Settings  string // config as exported by /exportconfig
+	UpdatedAt time.Time
+}
+
+// How often a saved search runs
+const (
+	RunDaily  = "daily"  // once a day at RunAt
+	RunHourly = "hourly" // an hour after the previous run was queued
+)
+
+// SavedSearch is a search URL re-run every day at RunAt, or every hour
+type SavedSearch struct {
+	ID          int
+	UserID      int64
+	URL         string
+	RunAt       string       // "HH:MM", server time; when the search was saved for hourly ones
+	Every       string       // RunDaily or RunHourly
+	LastRunDate sql.NullTime // day the search was last queued
+	LastRunAt   sql.NullTime // when the search was last queued
+	Config      string       // the user's settings when the search was saved (ExportUserConfig), "" = current ones
+	CreatedAt   time.Time
+}
+
+// RequestMetrics holds how long each stage of processing a request took
+type RequestMetrics struct {
+	BrowserLaunch time.Duration
+	Fetch         time.Duration // search result pages, all links
+	Enrich        time.Duration // detail pages, all links
+	SheetsWrite   time.Duration
+	Total         time.Duration
+}
+
+// UserStats aggregates a user's usage of the bot over all their requests
+type UserStats struct {
+	Requests       int
+	DoneRequests   int
+	FailedRequests int
+	EmptyRequests  int          // finished requests that kept no listings, e.g. after a selector broke
+	Listings       int          // listings stored over all requests, repeats included
+	TopCurrency    string       // most common listing currency, "" when no listing has one
+	LastRequestAt  sql.NullTime // null when the user made no requests
+	LastEmptyAt    sql.NullTime // when the latest empty request was created
+}
+
 // GetUserConfig retrieves user configuration, creating default if not exists
 func (db *DB) GetUserConfig(userID int64) (*UserConfig, error) {
 	var cfg UserConfig
 	err := db.conn.QueryRow(`
-		SELECT user_id, max_pages, min_reviews, min_price, max_price, min_stars, created_at, updated_at
+		SELECT user_id, max_pages, min_reviews, min_price, max_price, min_stars, sort_by, superhost_only, guest_favorite_only, max_total_pages, max_listings, location_contains, max_minimum_nights, dedup_scope, auto_widen_min, price_step, include_similar_dates, discounted_only, enrich_fields, max_price_per_guest, append_saved_searches, spreadsheet_per_request, forward_to, created_at, updated_at
 		FROM user_configs
 		WHERE user_id = $1
 	`, userID).Scan(
 		&cfg.UserID, &cfg.MaxPages, &cfg.MinReviews, &cfg.MinPrice,
-		&cfg.MaxPrice, &cfg.MinStars, &cfg.CreatedAt, &cfg.UpdatedAt,
+		&cfg.MaxPrice, &cfg.MinStars, &cfg.SortBy, &cfg.SuperhostOnly, &cfg.GuestFavoriteOnly, &cfg.MaxTotalPages, &cfg.MaxListings, &cfg.LocationContains, &cfg.MaxMinimumNights, &cfg.DedupScope, &cfg.AutoWidenMin, &cfg.PriceStep, &cfg.IncludeSimilarDates, &cfg.DiscountedOnly, &cfg.EnrichFields, &cfg.MaxPricePerGuest, &cfg.AppendSavedSearches, &cfg.SpreadsheetPerRequest, &cfg.ForwardTo, &cfg.CreatedAt, &cfg.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -104,6 +187,9 @@ func (db *DB) GetUserConfig(userID int64) (*UserConfig, error) {
 			MinPrice:   0,
 			MaxPrice:   2000,
 			MinStars:   4.0,
+			SortBy:     "none",
+			DedupScope: "request",
+			PriceStep:  50,
 		}
 		_, err = db.conn.Exec(`
 			INSERT INTO user_configs (user_id, max_pages, min_reviews, min_price, max_price, min_stars)
@@ -122,40 +208,68 @@ func (db *DB) GetUserConfig(userID int64) (*UserConfig, error) {
 	return &cfg, nil
 }
 
-// CreateRequest creates a new scraping request
-func (db *DB) CreateRequest(userID int64, telegramMessageID int, url string) (*Request, error) {
+// requestColumns is the column list read by scanRequest, in scan order
+const requestColumns = `id, user_id, telegram_message_id, url, status, listings_count, pages_count, sheet_name, sheet_gid, spreadsheet_id, skip_enrichment, saved_search_id, check_in, check_out, created_at, updated_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRequest scans a row selected with requestColumns into a Request
+func scanRequest(row rowScanner) (*Request, error) {
 	var req Request
-	var sheetName sql.NullString
-	err := db.conn.QueryRow(`
-		INSERT INTO requests (user_id, telegram_message_id, url, status)
-		VALUES ($1, $2, $3, 'created')
-		RETURNING id, user_id, telegram_message_id, url, status, listings_count, pages_count, sheet_name, created_at, updated_at
-	`, userID, telegramMessageID, url).Scan(
+	err := row.Scan(
 		&req.ID, &req.UserID, &req.TelegramMessageID, &req.URL, &req.Status,
-		&req.ListingsCount, &req.PagesCount, &sheetName, &req.CreatedAt, &req.UpdatedAt,
+		&req.ListingsCount, &req.PagesCount, &req.SheetName, &req.SheetGID, &req.SpreadsheetID, &req.SkipEnrichment, &req.SavedSearchID,
+		&req.CheckIn, &req.CheckOut, &req.CreatedAt, &req.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
-	req.SheetName = sheetName
 	return &req, nil
 }
 
+// requestDates returns the stay dates of a request's URLs (one per line), taken from the
+// first URL that has them
+func requestDates(urls string) (checkIn, checkOut sql.NullTime) {
+	for _, line := range strings.Split(urls, "\n") {
+		if in, out, ok := searchurl.Dates(line); ok {
+			return sql.NullTime{Time: in, Valid: true}, sql.NullTime{Time: out, Valid: true}
+		}
+	}
+	return sql.NullTime{}, sql.NullTime{}
+}
+
+// CreateRequest creates a new scraping request. skipEnrichment marks a quick request
+// whose listings are written without visiting their detail pages.
+func (db *DB) CreateRequest(userID int64, telegramMessageID int, url string, skipEnrichment bool) (*Request, error) {
+	checkIn, checkOut := requestDates(url)
+	return scanRequest(db.conn.QueryRow(`
+		INSERT INTO requests (user_id, telegram_message_id, url, status, skip_enrichment, check_in, check_out)
+		VALUES ($1, $2, $3, 'created', $4, $5, $6)
+		RETURNING `+requestColumns, userID, telegramMessageID, url, skipEnrichment, checkIn, checkOut))
+}
+
+// CreateScheduledRequest creates the request for one scheduled run of a saved search
+func (db *DB) CreateScheduledRequest(userID int64, telegramMessageID int, url string, savedSearchID int) (*Request, error) {
+	checkIn, checkOut := requestDates(url)
+	return scanRequest(db.conn.QueryRow(`
+		INSERT INTO requests (user_id, telegram_message_id, url, status, saved_search_id, check_in, check_out)
+		VALUES ($1, $2, $3, 'created', $4, $5, $6)
+		RETURNING `+requestColumns, userID, telegramMessageID, url, savedSearchID, checkIn, checkOut))
+}
+
 // GetNextCreatedRequest gets the next request with status 'created'
 func (db *DB) GetNextCreatedRequest() (*Request, error) {
-	var req Request
-	var sheetName sql.NullString
-	err := db.conn.QueryRow(`
-		SELECT id, user_id, telegram_message_id, url, status, listings_count, pages_count, sheet_name, created_at, updated_at
+	req, err := scanRequest(db.conn.QueryRow(`
+		SELECT ` + requestColumns + `
 		FROM requests
 		WHERE status = 'created'
 		ORDER BY created_at ASC
 		LIMIT 1
 		FOR UPDATE SKIP LOCKED
-	`).Scan(
-		&req.ID, &req.UserID, &req.TelegramMessageID, &req.URL, &req.Status,
-		&req.ListingsCount, &req.PagesCount, &sheetName, &req.CreatedAt, &req.UpdatedAt,
-	)
+	`))
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -165,8 +279,30 @@ func (db *DB) GetNextCreatedRequest() (*Request, error) {
 		return nil, err
 	}
 
-	req.SheetName = sheetName
-	return &req, nil
+	return req, nil
+}
+
+// FindActiveRequestByURL returns the user's most recent request for the given URL that
+// hasn't finished yet (created, in progress or paused), or nil if there is none.
+// The URL must be normalized the same way it was when the request was created.
+func (db *DB) FindActiveRequestByURL(userID int64, url string) (*Request, error) {
+	req, err := scanRequest(db.conn.QueryRow(`
+		SELECT `+requestColumns+`
+		FROM requests
+		WHERE user_id = $1 AND url = $2 AND status IN ('created', 'in_progress', 'paused')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID, url))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up active request: %w", err)
+	}
+
+	return req, nil
 }
 
 // UpdateRequestStatus updates the status of a request
@@ -189,16 +325,68 @@ func (db *DB) UpdateRequestCounts(requestID int, listingsCount, pagesCount int)
 	return err
 }
 
-// UpdateRequestSheetName updates the sheet name for a request
-func (db *DB) UpdateRequestSheetName(requestID int, sheetName string) error {
+// UpdateRequestSheetName stores the name and gid of the sheet created for a request
+func (db *DB) UpdateRequestSheetName(requestID int, sheetName string, sheetGID int64) error {
+	_, err := db.conn.Exec(`
+		UPDATE requests
+		SET sheet_name = $1, sheet_gid = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, sheetName, sheetGID, requestID)
+	return err
+}
+
+// UpdateRequestSpreadsheet stores the ID of the spreadsheet created for a request, so a
+// resumed request keeps writing to it
+func (db *DB) UpdateRequestSpreadsheet(requestID int, spreadsheetID string) error {
 	_, err := db.conn.Exec(`
 		UPDATE requests
-		SET sheet_name = $1, updated_at = CURRENT_TIMESTAMP
+		SET spreadsheet_id = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
-	`, sheetName, requestID)
+	`, spreadsheetID, requestID)
+	return err
+}
+
+// ClearRequestSheet forgets the request's sheet after it was deleted, so a retry
+// writes to a new sheet instead of the missing one. Other requests of the user that
+// wrote to the same tab (runs of a saved search appending to one tab) forget it too.
+func (db *DB) ClearRequestSheet(requestID int) error {
+	_, err := db.conn.Exec(`
+		UPDATE requests
+		SET sheet_name = NULL, sheet_gid = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 OR (sheet_gid, user_id, COALESCE(spreadsheet_id, '')) =
+			(SELECT sheet_gid, user_id, COALESCE(spreadsheet_id, '') FROM requests WHERE id = $1)
+	`, requestID)
 	return err
 }
 
+// ClearSharedSpreadsheetSheets forgets the sheets of the requests written to the shared
+// spreadsheet whose tabs were deleted. Tabs are matched by title, which is unique within
+// the spreadsheet, so requests from before gids were stored are cleared too.
+func (db *DB) ClearSharedSpreadsheetSheets(sheetNames []string) error {
+	if len(sheetNames) == 0 {
+		return nil
+	}
+	_, err := db.conn.Exec(`
+		UPDATE requests
+		SET sheet_name = NULL, sheet_gid = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE spreadsheet_id IS NULL AND sheet_name = ANY($1)
+	`, pq.Array(sheetNames))
+	return err
+}
+
+// CountRequestsSharingSheet counts the requests of the user whose rows are in the tab of
+// a request, the request included: runs of a saved search appending to one tab share it.
+// Requests from before gids were stored count only themselves.
+func (db *DB) CountRequestsSharingSheet(requestID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM requests
+		WHERE id = $1 OR (sheet_gid, user_id, COALESCE(spreadsheet_id, '')) =
+			(SELECT sheet_gid, user_id, COALESCE(spreadsheet_id, '') FROM requests WHERE id = $1)
+	`, requestID).Scan(&count)
+	return count, err
+}
+
 // SaveListing saves a listing to the database
 func (db *DB) SaveListing(requestID int, title, url string, price *float64, currency *string, stars *float64, reviewCount *int) error {
 	return db.SaveListingWithStatus(requestID, title, url, price, currency, stars, reviewCount, "pending")
@@ -227,6 +415,9 @@ func (db *DB) SaveListingWithStatus(requestID int, title, url string, price *flo
 	_, err := db.conn.Exec(`
 		INSERT INTO listings (request_id, title, url, price, currency, stars, review_count, status)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (request_id, url) DO UPDATE SET
+			title = EXCLUDED.title, price = EXCLUDED.price, currency = EXCLUDED.currency, stars = EXCLUDED.stars,
+			review_count = EXCLUDED.review_count, status = EXCLUDED.status
 	`, requestID, title, url, priceVal, currencyVal, starsVal, reviewCountVal, status)
 	return err
 }
@@ -301,6 +492,11 @@ func (db *DB) SaveEnrichedListing(requestID int, title, url string, price *float
 		INSERT INTO listings (request_id, title, url, price, currency, stars, review_count, 
 			is_superhost, is_guest_favorite, bedrooms, bathrooms, beds, description, house_rules, newest_review_date, status)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, 'saved')
+		ON CONFLICT (request_id, url) DO UPDATE SET
+			title = EXCLUDED.title, price = EXCLUDED.price, currency = EXCLUDED.currency, stars = EXCLUDED.stars,
+			review_count = EXCLUDED.review_count, is_superhost = EXCLUDED.is_superhost, is_guest_favorite = EXCLUDED.is_guest_favorite,
+			bedrooms = EXCLUDED.bedrooms, bathrooms = EXCLUDED.bathrooms, beds = EXCLUDED.beds, description = EXCLUDED.description,
+			house_rules = EXCLUDED.house_rules, newest_review_date = EXCLUDED.newest_review_date, status = EXCLUDED.status
 		RETURNING id
 	`, requestID, title, url, priceVal, currencyVal, starsVal, reviewCountVal,
 		isSuperhostVal, isGuestFavoriteVal, bedroomsVal, bathroomsVal, bedsVal,
@@ -310,7 +506,7 @@ func (db *DB) SaveEnrichedListing(requestID int, title, url string, price *float
 
 // UpdateListingDetails updates an existing listing with detail page information
 func (db *DB) UpdateListingDetails(listingID int, isSuperhost *bool, isGuestFavorite *bool, bedrooms *float64, bathrooms *float64, beds *float64,
-	description *string, houseRules *string, newestReviewDate *time.Time) error {
+	maxGuests *int, minNights *int, checkIn *string, checkOut *string, selfCheckIn *bool, description *string, houseRules *string, newestReviewDate *time.Time) error {
 	updates := []string{}
 	args := []interface{}{}
 	argIndex := 1
@@ -340,6 +536,31 @@ func (db *DB) UpdateListingDetails(listingID int, isSuperhost *bool, isGuestFavo
 		args = append(args, *beds)
 		argIndex++
 	}
+	if maxGuests != nil {
+		updates = append(updates, fmt.Sprintf("max_guests = $%d", argIndex))
+		args = append(args, *maxGuests)
+		argIndex++
+	}
+	if minNights != nil {
+		updates = append(updates, fmt.Sprintf("min_nights = $%d", argIndex))
+		args = append(args, *minNights)
+		argIndex++
+	}
+	if checkIn != nil {
+		updates = append(updates, fmt.Sprintf("check_in = $%d", argIndex))
+		args = append(args, *checkIn)
+		argIndex++
+	}
+	if checkOut != nil {
+		updates = append(updates, fmt.Sprintf("check_out = $%d", argIndex))
+		args = append(args, *checkOut)
+		argIndex++
+	}
+	if selfCheckIn != nil {
+		updates = append(updates, fmt.Sprintf("self_check_in = $%d", argIndex))
+		args = append(args, *selfCheckIn)
+		argIndex++
+	}
 	if description != nil {
 		updates = append(updates, fmt.Sprintf("description = $%d", argIndex))
 		args = append(args, *description)
@@ -406,23 +627,93 @@ func (db *DB) GetListingURLsAndLinkNumbers(requestID int) ([]struct{ URL string;
 	return result, rows.Err()
 }
 
+// GetRequestListings returns the listings saved for a request in link and scrape order.
+// Only the search result fields (title, URL, price, rating, reviews) are filled in.
+func (db *DB) GetRequestListings(requestID int) ([]models.Listing, error) {
+	rows, err := db.conn.Query(`
+		SELECT title, url, COALESCE(price, 0), COALESCE(currency, ''), COALESCE(stars, 0), COALESCE(review_count, 0), COALESCE(link_number, 0)
+		FROM listings
+		WHERE request_id = $1
+		ORDER BY link_number ASC, id ASC
+	`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Listing
+	for rows.Next() {
+		var listing models.Listing
+		if err := rows.Scan(&listing.Title, &listing.URL, &listing.Price, &listing.Currency,
+			&listing.Stars, &listing.ReviewCount, &listing.LinkNumber); err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
+// SearchListings returns the user's stored listings whose title or description contains
+// query (case-insensitive), newest first and at most limit of them. A listing found by
+// several requests is returned once, as last seen.
+func (db *DB) SearchListings(userID int64, query string, limit int) ([]models.Listing, error) {
+	// Match the query literally: escape the LIKE wildcards
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	rows, err := db.conn.Query(`
+		SELECT title, url, price, currency, stars, review_count FROM (
+			SELECT DISTINCT ON (l.url) l.id, l.title, l.url, COALESCE(l.price, 0) AS price, COALESCE(l.currency, '') AS currency,
+				COALESCE(l.stars, 0) AS stars, COALESCE(l.review_count, 0) AS review_count
+			FROM listings l
+			JOIN requests r ON r.id = l.request_id
+			WHERE r.user_id = $1 AND (l.title ILIKE $2 OR l.description ILIKE $2)
+			ORDER BY l.url, l.id DESC
+		) matches
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Listing
+	for rows.Next() {
+		var listing models.Listing
+		if err := rows.Scan(&listing.Title, &listing.URL, &listing.Price, &listing.Currency,
+			&listing.Stars, &listing.ReviewCount); err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
 // SaveReviews saves multiple reviews for a listing
 // Accepts models.Review slice and converts to database format
+//
+// The reviews replace the ones saved for the listing before: a detail page always yields
+// the listing's full current review set, so re-enriching a listing (a retried link) must
+// not add the same reviews again. Replacing is used rather than a natural-key unique index
+// because reviews without a parsed date get the current time and would never match.
 func (db *DB) SaveReviews(listingID int, reviews []models.Review) error {
 	if len(reviews) == 0 {
 		return nil
 	}
 
-	// Use a transaction for bulk insert
+	// Use a transaction so the old reviews are only removed if the new ones are saved
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if _, err := tx.Exec(`DELETE FROM listing_reviews WHERE listing_id = $1`, listingID); err != nil {
+		return fmt.Errorf("failed to delete previous reviews (listingID=%d): %w", listingID, err)
+	}
+
 	stmt, err := tx.Prepare(`
-		INSERT INTO listing_reviews (listing_id, date, score, full_text, time_on_airbnb)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO listing_reviews (listing_id, date, score, full_text, time_on_airbnb, reviewer_name, reviewer_location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -440,6 +731,8 @@ func (db *DB) SaveReviews(listingID int, reviews []models.Review) error {
 		if review.TimeOnAirbnb != "" {
 			timeOnAirbnbVal = sql.NullString{String: review.TimeOnAirbnb, Valid: true}
 		}
+		reviewerNameVal := sql.NullString{String: review.ReviewerName, Valid: review.ReviewerName != ""}
+		reviewerLocationVal := sql.NullString{String: review.ReviewerLocation, Valid: review.ReviewerLocation != ""}
 
 		// Ensure date is not zero
 		reviewDate := review.Date
@@ -447,7 +740,7 @@ func (db *DB) SaveReviews(listingID int, reviews []models.Review) error {
 			reviewDate = time.Now()
 		}
 
-		_, err := stmt.Exec(listingID, reviewDate, scoreVal, review.FullText, timeOnAirbnbVal)
+		_, err := stmt.Exec(listingID, reviewDate, scoreVal, review.FullText, timeOnAirbnbVal, reviewerNameVal, reviewerLocationVal)
 		if err != nil {
 			return fmt.Errorf("failed to insert review (listingID=%d, date=%v): %w", listingID, reviewDate, err)
 		}
@@ -463,21 +756,106 @@ func (db *DB) SaveReviews(listingID int, reviews []models.Review) error {
 
 // GetRequestByID retrieves a request by ID
 func (db *DB) GetRequestByID(requestID int) (*Request, error) {
-	var req Request
-	var sheetName sql.NullString
-	err := db.conn.QueryRow(`
-		SELECT id, user_id, telegram_message_id, url, status, listings_count, pages_count, sheet_name, created_at, updated_at
+	return scanRequest(db.conn.QueryRow(`
+		SELECT `+requestColumns+`
 		FROM requests
 		WHERE id = $1
-	`, requestID).Scan(
-		&req.ID, &req.UserID, &req.TelegramMessageID, &req.URL, &req.Status,
-		&req.ListingsCount, &req.PagesCount, &sheetName, &req.CreatedAt, &req.UpdatedAt,
-	)
+	`, requestID))
+}
+
+// GetLatestRequest returns the user's most recent request, or nil if they have none
+func (db *DB) GetLatestRequest(userID int64) (*Request, error) {
+	req, err := scanRequest(db.conn.QueryRow(`
+		SELECT `+requestColumns+`
+		FROM requests
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up latest request: %w", err)
+	}
+	return req, nil
+}
+
+// GetLastSheetRequest returns the user's most recent request that has a sheet tab and is
+// done or still running, or nil if there is none
+func (db *DB) GetLastSheetRequest(userID int64) (*Request, error) {
+	req, err := scanRequest(db.conn.QueryRow(`
+		SELECT `+requestColumns+`
+		FROM requests
+		WHERE user_id = $1 AND status IN ('done', 'in_progress', 'paused') AND sheet_name IS NOT NULL AND sheet_name != ''
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up last sheet: %w", err)
+	}
+	return req, nil
+}
+
+// GetUserSheetTabs returns a page of the user's done requests that have a sheet tab,
+// newest first, together with how many such requests there are in total
+func (db *DB) GetUserSheetTabs(userID int64, limit, offset int) ([]Request, int, error) {
+	var total int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM requests
+		WHERE user_id = $1 AND status = 'done' AND sheet_gid IS NOT NULL
+	`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT `+requestColumns+`
+		FROM requests
+		WHERE user_id = $1 AND status = 'done' AND sheet_gid IS NOT NULL
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var requests []Request
+	for rows.Next() {
+		req, err := scanRequest(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		requests = append(requests, *req)
+	}
+	return requests, total, rows.Err()
+}
+
+// GetUnfinishedRequestSheetNames returns the sheet names of requests that are not done or failed,
+// so maintenance tasks don't delete a tab that is still being written or will be resumed
+func (db *DB) GetUnfinishedRequestSheetNames() ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT sheet_name FROM requests
+		WHERE sheet_name IS NOT NULL AND status IN ('created', 'in_progress', 'paused')
+	`)
 	if err != nil {
 		return nil, err
 	}
-	req.SheetName = sheetName
-	return &req, nil
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
 }
 
 // UpdateUserConfig updates user configuration
@@ -531,26 +909,462 @@ func (db *DB) UpdateUserConfig(userID int64, maxPages *int, minReviews *int, min
 	return err
 }
 
+// UpdateUserConfigSortBy updates the sort order applied to written listings
+func (db *DB) UpdateUserConfigSortBy(userID int64, sortBy string) error {
+	return db.updateUserConfigColumn(userID, "sort_by", sortBy)
+}
+
+// UpdateUserConfigMaxTotalPages updates the per-request page budget (0 = unlimited)
+func (db *DB) UpdateUserConfigMaxTotalPages(userID int64, maxTotalPages int) error {
+	return db.updateUserConfigColumn(userID, "max_total_pages", maxTotalPages)
+}
+
+// UpdateUserConfigMaxListings updates the per-request cap on enriched listings (0 = no cap)
+func (db *DB) UpdateUserConfigMaxListings(userID int64, maxListings int) error {
+	return db.updateUserConfigColumn(userID, "max_listings", maxListings)
+}
+
+// UpdateUserConfigSuperhostOnly updates whether only superhost listings are kept
+func (db *DB) UpdateUserConfigSuperhostOnly(userID int64, superhostOnly bool) error {
+	return db.updateUserConfigColumn(userID, "superhost_only", superhostOnly)
+}
+
+// UpdateUserConfigIncludeSimilarDates updates whether listings for similar dates are kept
+func (db *DB) UpdateUserConfigIncludeSimilarDates(userID int64, include bool) error {
+	return db.updateUserConfigColumn(userID, "include_similar_dates", include)
+}
+
+// UpdateUserConfigDiscountedOnly updates whether only discounted listings are kept
+func (db *DB) UpdateUserConfigDiscountedOnly(userID int64, discountedOnly bool) error {
+	return db.updateUserConfigColumn(userID, "discounted_only", discountedOnly)
+}
+
+// UpdateUserConfigMaxPricePerGuest updates the USD per-guest price cap (0 = no limit)
+func (db *DB) UpdateUserConfigMaxPricePerGuest(userID int64, maxPricePerGuest float64) error {
+	return db.updateUserConfigColumn(userID, "max_price_per_guest", maxPricePerGuest)
+}
+
+// UpdateUserConfigAppendSavedSearches updates whether saved search runs share one tab
+func (db *DB) UpdateUserConfigAppendSavedSearches(userID int64, appendSavedSearches bool) error {
+	return db.updateUserConfigColumn(userID, "append_saved_searches", appendSavedSearches)
+}
+
+// UpdateUserConfigSpreadsheetPerRequest updates whether each request gets its own spreadsheet
+func (db *DB) UpdateUserConfigSpreadsheetPerRequest(userID int64, spreadsheetPerRequest bool) error {
+	return db.updateUserConfigColumn(userID, "spreadsheet_per_request", spreadsheetPerRequest)
+}
+
+// UpdateUserConfigForwardTo updates who the user's success messages are also sent to, 0 = nobody
+func (db *DB) UpdateUserConfigForwardTo(userID int64, forwardTo int64) error {
+	return db.updateUserConfigColumn(userID, "forward_to", forwardTo)
+}
+
+// UpdateUserConfigEnrichFields updates which detail field groups are extracted
+func (db *DB) UpdateUserConfigEnrichFields(userID int64, enrichFields string) error {
+	return db.updateUserConfigColumn(userID, "enrich_fields", enrichFields)
+}
+
+// UpdateUserConfigDedupScope updates which repeated listings are dropped
+func (db *DB) UpdateUserConfigDedupScope(userID int64, dedupScope string) error {
+	return db.updateUserConfigColumn(userID, "dedup_scope", dedupScope)
+}
+
+// UpdateUserConfigMaxMinimumNights updates the longest minimum stay accepted (0 = no limit)
+func (db *DB) UpdateUserConfigMaxMinimumNights(userID int64, maxMinimumNights int) error {
+	return db.updateUserConfigColumn(userID, "max_minimum_nights", maxMinimumNights)
+}
+
+// UpdateUserConfigAutoWidenMin updates the listing floor below which a search is widened (0 = off)
+func (db *DB) UpdateUserConfigAutoWidenMin(userID int64, autoWidenMin int) error {
+	return db.updateUserConfigColumn(userID, "auto_widen_min", autoWidenMin)
+}
+
+// UpdateUserConfigPriceStep updates the width of the price bands search URLs are split into
+func (db *DB) UpdateUserConfigPriceStep(userID int64, priceStep int) error {
+	return db.updateUserConfigColumn(userID, "price_step", priceStep)
+}
+
+// UpdateUserConfigLocationContains updates the location filter ("" keeps all locations)
+func (db *DB) UpdateUserConfigLocationContains(userID int64, locationContains string) error {
+	return db.updateUserConfigColumn(userID, "location_contains", locationContains)
+}
+
+// UpdateUserConfigGuestFavoriteOnly updates whether only Guest Favorite listings are kept
+func (db *DB) UpdateUserConfigGuestFavoriteOnly(userID int64, guestFavoriteOnly bool) error {
+	return db.updateUserConfigColumn(userID, "guest_favorite_only", guestFavoriteOnly)
+}
+
+// updateUserConfigColumn sets a single user_configs column. column must be a trusted
+// identifier, never user input.
+func (db *DB) updateUserConfigColumn(userID int64, column string, value interface{}) error {
+	_, err := db.conn.Exec(fmt.Sprintf(`
+		UPDATE user_configs
+		SET %s = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2
+	`, column), value, userID)
+	return err
+}
+
 // ============================================================================
 // Search Links Methods (Multi-Link Support)
 // ============================================================================
 
+// SaveFilterPreset stores a preset, replacing an existing preset with the same name
+func (db *DB) SaveFilterPreset(userID int64, name, settings string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO filter_presets (user_id, name, settings)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name) DO UPDATE SET settings = EXCLUDED.settings, updated_at = CURRENT_TIMESTAMP
+	`, userID, name, settings)
+	return err
+}
+
+// GetFilterPreset returns the user's preset with the given name, or nil if there is none
+func (db *DB) GetFilterPreset(userID int64, name string) (*FilterPreset, error) {
+	var preset FilterPreset
+	err := db.conn.QueryRow(`
+		SELECT user_id, name, settings, updated_at
+		FROM filter_presets
+		WHERE user_id = $1 AND name = $2
+	`, userID, name).Scan(&preset.UserID, &preset.Name, &preset.Settings, &preset.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// GetFilterPresets returns the user's presets ordered by name
+func (db *DB) GetFilterPresets(userID int64) ([]FilterPreset, error) {
+	rows, err := db.conn.Query(`
+		SELECT user_id, name, settings, updated_at
+		FROM filter_presets
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []FilterPreset
+	for rows.Next() {
+		var preset FilterPreset
+		if err := rows.Scan(&preset.UserID, &preset.Name, &preset.Settings, &preset.UpdatedAt); err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+	return presets, rows.Err()
+}
+
+// CreateSavedSearch saves a search to re-run every (RunDaily or RunHourly); daily
+// searches run at runAt ("HH:MM"). config is the snapshot of the user's settings its
+// runs use.
+func (db *DB) CreateSavedSearch(userID int64, url string, runAt string, every string, config string) (*SavedSearch, error) {
+	search := SavedSearch{UserID: userID, URL: url, RunAt: runAt, Every: every, Config: config}
+	err := db.conn.QueryRow(`
+		INSERT INTO saved_searches (user_id, url, run_at, run_every, config)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userID, url, runAt, every, config).Scan(&search.ID, &search.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// GetSavedSearches returns the user's saved searches, oldest first
+func (db *DB) GetSavedSearches(userID int64) ([]SavedSearch, error) {
+	return db.querySavedSearches(`WHERE user_id = $1 ORDER BY id ASC`, userID)
+}
+
+// GetDueSavedSearches returns the daily saved searches whose time of day has come at now
+// and that were not queued yet today, and the hourly ones last queued an hour ago or more
+func (db *DB) GetDueSavedSearches(now time.Time) ([]SavedSearch, error) {
+	return db.querySavedSearches(`
+		WHERE (run_every = $4 AND (last_run_at IS NULL OR last_run_at <= $3))
+			OR (run_every <> $4 AND run_at <= $1 AND (last_run_date IS NULL OR last_run_date < $2::date))
+		ORDER BY run_at ASC, id ASC`,
+		now.Format("15:04"), now.Format("2006-01-02"), now.Add(-time.Hour), RunHourly)
+}
+
+// querySavedSearches selects saved searches with the given WHERE/ORDER BY clause
+func (db *DB) querySavedSearches(clause string, args ...interface{}) ([]SavedSearch, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, user_id, url, run_at, run_every, last_run_date, last_run_at, config, created_at
+		FROM saved_searches
+		`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		var search SavedSearch
+		if err := rows.Scan(&search.ID, &search.UserID, &search.URL, &search.RunAt, &search.Every, &search.LastRunDate, &search.LastRunAt, &search.Config, &search.CreatedAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+// GetSavedSearchConfig returns the settings snapshot of a saved search, "" when it was
+// saved without one or no longer exists
+func (db *DB) GetSavedSearchConfig(savedSearchID int) (string, error) {
+	var config string
+	err := db.conn.QueryRow(`SELECT config FROM saved_searches WHERE id = $1`, savedSearchID).Scan(&config)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return config, err
+}
+
+// MarkSavedSearchRun records that the saved search was queued at now
+func (db *DB) MarkSavedSearchRun(savedSearchID int, now time.Time) error {
+	_, err := db.conn.Exec(`
+		UPDATE saved_searches SET last_run_date = $1::date, last_run_at = $2 WHERE id = $3
+	`, now.Format("2006-01-02"), now, savedSearchID)
+	return err
+}
+
+// DeleteSavedSearch deletes the user's saved search. It returns false if the user has
+// no saved search with that ID.
+func (db *DB) DeleteSavedSearch(userID int64, savedSearchID int) (bool, error) {
+	result, err := db.conn.Exec(`
+		DELETE FROM saved_searches WHERE id = $1 AND user_id = $2
+	`, savedSearchID, userID)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// GetSavedSearchHistory returns how many runs of the saved search were created before
+// the given request, and the URLs of the listings they reported
+func (db *DB) GetSavedSearchHistory(savedSearchID int, beforeRequestID int) (int, []string, error) {
+	var runs int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM requests WHERE saved_search_id = $1 AND id < $2
+	`, savedSearchID, beforeRequestID).Scan(&runs)
+	if err != nil || runs == 0 {
+		return 0, nil, err
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT DISTINCT l.url
+		FROM listings l
+		JOIN requests r ON r.id = l.request_id
+		WHERE r.saved_search_id = $1 AND r.id < $2
+	`, savedSearchID, beforeRequestID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return 0, nil, err
+		}
+		urls = append(urls, url)
+	}
+	return runs, urls, rows.Err()
+}
+
+// GetSavedSearchListings returns the listings kept by earlier runs of the saved search,
+// each with the price it was last seen at
+func (db *DB) GetSavedSearchListings(savedSearchID int) ([]models.Listing, error) {
+	rows, err := db.conn.Query(`
+		SELECT url, title, price, currency FROM saved_search_listings WHERE saved_search_id = $1
+	`, savedSearchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Listing
+	for rows.Next() {
+		var listing models.Listing
+		if err := rows.Scan(&listing.URL, &listing.Title, &listing.Price, &listing.Currency); err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
+// SaveSavedSearchListings records the listings kept by a run of the saved search, keyed
+// by searchurl.ListingKey. Listings of earlier runs stay; a listing without a price
+// keeps the one it was seen at before.
+func (db *DB) SaveSavedSearchListings(savedSearchID int, listings []models.Listing) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO saved_search_listings (saved_search_id, listing_path, url, title, price, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (saved_search_id, listing_path) DO UPDATE SET
+			url = EXCLUDED.url, title = EXCLUDED.title,
+			price = CASE WHEN EXCLUDED.price > 0 THEN EXCLUDED.price ELSE saved_search_listings.price END,
+			currency = CASE WHEN EXCLUDED.price > 0 THEN EXCLUDED.currency ELSE saved_search_listings.currency END,
+			seen_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, listing := range listings {
+		if _, err := stmt.Exec(savedSearchID, searchurl.ListingKey(listing.URL), listing.URL, listing.Title,
+			listing.Price, listing.Currency); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AddRunListings records listings a saved search run kept, for GetRunListings once the
+// run finished
+func (db *DB) AddRunListings(requestID int, listings []models.Listing) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO run_listings (request_id, url, title, price, currency)
+		VALUES ($1, $2, $3, $4, $5)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, listing := range listings {
+		if _, err := stmt.Exec(requestID, listing.URL, listing.Title, listing.Price, listing.Currency); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRunListings returns the listings recorded with AddRunListings for a request, in the
+// order they were added
+func (db *DB) GetRunListings(requestID int) ([]models.Listing, error) {
+	rows, err := db.conn.Query(`
+		SELECT url, title, price, currency FROM run_listings WHERE request_id = $1 ORDER BY id
+	`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.Listing
+	for rows.Next() {
+		var listing models.Listing
+		if err := rows.Scan(&listing.URL, &listing.Title, &listing.Price, &listing.Currency); err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
+// DeleteRunListings forgets the listings recorded for a request once its run was compared
+func (db *DB) DeleteRunListings(requestID int) error {
+	_, err := db.conn.Exec(`DELETE FROM run_listings WHERE request_id = $1`, requestID)
+	return err
+}
+
+// CreateFeedback stores a /feedback message with the user's latest request, if any, and a
+// snapshot of their settings as exported by /exportconfig. It returns the feedback ID.
+func (db *DB) CreateFeedback(userID int64, requestID sql.NullInt64, message string, config string) (int, error) {
+	var id int
+	err := db.conn.QueryRow(`
+		INSERT INTO feedback (user_id, request_id, message, config)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, userID, requestID, message, config).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store feedback: %w", err)
+	}
+	return id, nil
+}
+
+// processingPausedKey is the service_settings key of the admin's processing kill switch
+const processingPausedKey = "processing_paused"
+
+// GetProcessingPaused reports whether the admin paused request processing
+func (db *DB) GetProcessingPaused() (bool, error) {
+	var value string
+	err := db.conn.QueryRow(`SELECT value FROM service_settings WHERE key = $1`, processingPausedKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// SetProcessingPaused stores whether request processing is paused
+func (db *DB) SetProcessingPaused(paused bool) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO service_settings (key, value)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP
+	`, processingPausedKey, strconv.FormatBool(paused))
+	return err
+}
+
 // CreateSearchLinks creates multiple search links for a request
 func (db *DB) CreateSearchLinks(requestID int, urls []string) ([]SearchLink, error) {
+	return db.insertSearchLinks(requestID, 1, urls)
+}
+
+// AppendSearchLinks adds search links to a request, numbered after its existing links
+func (db *DB) AppendSearchLinks(requestID int, urls []string) ([]SearchLink, error) {
+	var lastNumber int
+	err := db.conn.QueryRow(`
+		SELECT COALESCE(MAX(link_number), 0) FROM search_links WHERE request_id = $1
+	`, requestID).Scan(&lastNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last link number: %w", err)
+	}
+	return db.insertSearchLinks(requestID, lastNumber+1, urls)
+}
+
+// insertSearchLinks creates pending search links numbered from firstNumber
+func (db *DB) insertSearchLinks(requestID int, firstNumber int, urls []string) ([]SearchLink, error) {
 	links := make([]SearchLink, 0, len(urls))
 
 	for i, url := range urls {
+		linkNumber := firstNumber + i
 		var link SearchLink
 		err := db.conn.QueryRow(`
 			INSERT INTO search_links (request_id, link_number, url, status)
 			VALUES ($1, $2, $3, 'pending')
 			RETURNING id, request_id, link_number, url, status, retry_count, listings_count, last_error, created_at, updated_at
-		`, requestID, i+1, url).Scan(
+		`, requestID, linkNumber, url).Scan(
 			&link.ID, &link.RequestID, &link.LinkNumber, &link.URL, &link.Status,
 			&link.RetryCount, &link.ListingsCount, &link.LastError, &link.CreatedAt, &link.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create search link %d: %w", i+1, err)
+			return nil, fmt.Errorf("failed to create search link %d: %w", linkNumber, err)
 		}
 		links = append(links, link)
 	}
@@ -639,17 +1453,155 @@ func (db *DB) GetSearchLinkByID(linkID int) (*SearchLink, error) {
 	return &link, nil
 }
 
+// ResetFailedSearchLinks puts the failed links of a request back to 'pending' with a fresh
+// retry budget and re-queues the request as 'created', so the scheduler resumes it into the
+// same sheet and only processes those links. Returns the number of links reset; when there
+// are none the request is left untouched.
+func (db *DB) ResetFailedSearchLinks(requestID int) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE search_links
+		SET status = 'pending', retry_count = 0, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE request_id = $1 AND status = 'failed'
+	`, requestID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset search links: %w", err)
+	}
+	reset, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reset search links: %w", err)
+	}
+	if reset == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE requests
+		SET status = 'created', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, requestID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-queue request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(reset), nil
+}
+
+// UpdateSearchLinkFetchDuration records how long fetching a search link's pages took
+func (db *DB) UpdateSearchLinkFetchDuration(linkID int, d time.Duration) error {
+	_, err := db.conn.Exec(`
+		UPDATE search_links
+		SET fetch_ms = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, d.Milliseconds(), linkID)
+	return err
+}
+
+// AddRequestMetrics adds the stage durations of one run to a request's metrics.
+// Durations are summed so a resumed or retried request reports its total cost.
+func (db *DB) AddRequestMetrics(requestID int, m RequestMetrics) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO request_metrics (request_id, browser_launch_ms, fetch_ms, enrich_ms, sheets_ms, total_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (request_id) DO UPDATE SET
+			browser_launch_ms = request_metrics.browser_launch_ms + EXCLUDED.browser_launch_ms,
+			fetch_ms = request_metrics.fetch_ms + EXCLUDED.fetch_ms,
+			enrich_ms = request_metrics.enrich_ms + EXCLUDED.enrich_ms,
+			sheets_ms = request_metrics.sheets_ms + EXCLUDED.sheets_ms,
+			total_ms = request_metrics.total_ms + EXCLUDED.total_ms,
+			updated_at = CURRENT_TIMESTAMP
+	`, requestID, m.BrowserLaunch.Milliseconds(), m.Fetch.Milliseconds(), m.Enrich.Milliseconds(),
+		m.SheetsWrite.Milliseconds(), m.Total.Milliseconds())
+	return err
+}
+
+// GetAverageTimePerPage returns the average fetch time and total processing time per
+// search page over the last 20 finished requests, or zeros if there are none yet
+func (db *DB) GetAverageTimePerPage() (fetch time.Duration, total time.Duration, err error) {
+	var fetchMs, totalMs, pages int64
+	err = db.conn.QueryRow(`
+		SELECT COALESCE(SUM(fetch_ms), 0), COALESCE(SUM(total_ms), 0), COALESCE(SUM(pages_count), 0)
+		FROM (
+			SELECT m.fetch_ms, m.total_ms, r.pages_count
+			FROM request_metrics m
+			JOIN requests r ON r.id = m.request_id
+			WHERE r.status = 'done' AND r.pages_count > 0
+			ORDER BY r.id DESC
+			LIMIT 20
+		) recent
+	`).Scan(&fetchMs, &totalMs, &pages)
+	if err != nil || pages == 0 {
+		return 0, 0, err
+	}
+	return time.Duration(fetchMs/pages) * time.Millisecond, time.Duration(totalMs/pages) * time.Millisecond, nil
+}
+
+// GetUserStats aggregates the user's requests and the listings stored for them
+func (db *DB) GetUserStats(userID int64) (*UserStats, error) {
+	var stats UserStats
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'done'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COUNT(*) FILTER (WHERE status = 'done' AND listings_count = 0),
+			MAX(created_at),
+			MAX(created_at) FILTER (WHERE status = 'done' AND listings_count = 0)
+		FROM requests
+		WHERE user_id = $1
+	`, userID).Scan(&stats.Requests, &stats.DoneRequests, &stats.FailedRequests, &stats.EmptyRequests,
+		&stats.LastRequestAt, &stats.LastEmptyAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count requests: %w", err)
+	}
+	if stats.Requests == 0 {
+		return &stats, nil
+	}
+
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*) FROM listings l JOIN requests r ON r.id = l.request_id WHERE r.user_id = $1
+	`, userID).Scan(&stats.Listings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count listings: %w", err)
+	}
+
+	err = db.conn.QueryRow(`
+		SELECT l.currency
+		FROM listings l
+		JOIN requests r ON r.id = l.request_id
+		WHERE r.user_id = $1 AND COALESCE(l.currency, '') <> ''
+		GROUP BY l.currency
+		ORDER BY COUNT(*) DESC, l.currency
+		LIMIT 1
+	`, userID).Scan(&stats.TopCurrency)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to find the most common currency: %w", err)
+	}
+	return &stats, nil
+}
+
 // ============================================================================
 // Updated Listing Methods with LinkNumber Support
 // ============================================================================
 
 // SaveListingWithLinkNumber saves a listing to the database with link number
-func (db *DB) SaveListingWithLinkNumber(requestID int, linkNumber int, title, url string, price *float64, currency *string, stars *float64, reviewCount *int) error {
+// Returns the listing ID
+func (db *DB) SaveListingWithLinkNumber(requestID int, linkNumber int, title, url string, price *float64, currency *string, stars *float64, reviewCount *int) (int, error) {
 	return db.SaveListingWithStatusAndLinkNumber(requestID, linkNumber, title, url, price, currency, stars, reviewCount, "pending")
 }
 
-// SaveListingWithStatusAndLinkNumber saves a listing to the database with a specific status and link number
-func (db *DB) SaveListingWithStatusAndLinkNumber(requestID int, linkNumber int, title, url string, price *float64, currency *string, stars *float64, reviewCount *int, status string) error {
+// SaveListingWithStatusAndLinkNumber saves a listing to the database with a specific status and link number.
+// Saving a listing the request already has (a retried link) updates that row instead.
+// Returns the listing ID
+func (db *DB) SaveListingWithStatusAndLinkNumber(requestID int, linkNumber int, title, url string, price *float64, currency *string, stars *float64, reviewCount *int, status string) (int, error) {
 	var priceVal sql.NullFloat64
 	var currencyVal sql.NullString
 	var starsVal sql.NullFloat64
@@ -672,11 +1624,17 @@ func (db *DB) SaveListingWithStatusAndLinkNumber(requestID int, linkNumber int,
 		linkNumberVal = sql.NullInt64{Int64: int64(linkNumber), Valid: true}
 	}
 
-	_, err := db.conn.Exec(`
+	var listingID int
+	err := db.conn.QueryRow(`
 		INSERT INTO listings (request_id, link_number, title, url, price, currency, stars, review_count, status)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, requestID, linkNumberVal, title, url, priceVal, currencyVal, starsVal, reviewCountVal, status)
-	return err
+		ON CONFLICT (request_id, url) DO UPDATE SET
+			link_number = COALESCE(EXCLUDED.link_number, listings.link_number), title = EXCLUDED.title,
+			price = EXCLUDED.price, currency = EXCLUDED.currency, stars = EXCLUDED.stars,
+			review_count = EXCLUDED.review_count, status = EXCLUDED.status
+		RETURNING id
+	`, requestID, linkNumberVal, title, url, priceVal, currencyVal, starsVal, reviewCountVal, status).Scan(&listingID)
+	return listingID, err
 }
 
 // SaveEnrichedListingWithLinkNumber saves a listing with all detail page fields and link number to the database
@@ -743,6 +1701,12 @@ func (db *DB) SaveEnrichedListingWithLinkNumber(requestID int, linkNumber int, t
 		INSERT INTO listings (request_id, link_number, title, url, price, currency, stars, review_count, 
 			is_superhost, is_guest_favorite, bedrooms, bathrooms, beds, description, house_rules, newest_review_date, status)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, 'saved')
+		ON CONFLICT (request_id, url) DO UPDATE SET
+			title = EXCLUDED.title, price = EXCLUDED.price, currency = EXCLUDED.currency, stars = EXCLUDED.stars,
+			review_count = EXCLUDED.review_count, is_superhost = EXCLUDED.is_superhost, is_guest_favorite = EXCLUDED.is_guest_favorite,
+			bedrooms = EXCLUDED.bedrooms, bathrooms = EXCLUDED.bathrooms, beds = EXCLUDED.beds, description = EXCLUDED.description,
+			house_rules = EXCLUDED.house_rules, newest_review_date = EXCLUDED.newest_review_date, link_number = COALESCE(EXCLUDED.link_number, listings.link_number),
+			status = EXCLUDED.status
 		RETURNING id
 	`, requestID, linkNumberVal, title, url, priceVal, currencyVal, starsVal, reviewCountVal,
 		isSuperhostVal, isGuestFavoriteVal, bedroomsVal, bathroomsVal, bedsVal,
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"bnb-fetcher/models"
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL, skipping the test when it is not set
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	t.Setenv("DATABASE_URL", connStr)
+
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	// initSchema sets the search path per connection, so stay on the one it used
+	database.conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestSaveListingUpsertsByRequestAndURL(t *testing.T) {
+	database := openTestDB(t)
+
+	req, err := database.CreateRequest(-1, 0, "https://www.airbnb.com/s/homes", false)
+	if err != nil {
+		t.Fatalf("CreateRequest() error = %v", err)
+	}
+	t.Cleanup(func() { database.conn.Exec(`DELETE FROM requests WHERE id = $1`, req.ID) })
+
+	const url = "https://www.airbnb.com/rooms/1"
+	firstPrice, secondPrice := 100.0, 80.0
+	firstID, err := database.SaveListingWithLinkNumber(req.ID, 1, "Old title", url, &firstPrice, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("first SaveListingWithLinkNumber() error = %v", err)
+	}
+	secondID, err := database.SaveListingWithLinkNumber(req.ID, 2, "New title", url, &secondPrice, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("second SaveListingWithLinkNumber() error = %v", err)
+	}
+	if secondID != firstID {
+		t.Errorf("second save returned ID %d, want the first row's ID %d", secondID, firstID)
+	}
+
+	var count int
+	if err := database.conn.QueryRow(`SELECT COUNT(*) FROM listings WHERE request_id = $1`, req.ID).Scan(&count); err != nil {
+		t.Fatalf("count query error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("request has %d listing rows, want 1", count)
+	}
+
+	var title string
+	var price float64
+	var linkNumber int
+	err = database.conn.QueryRow(`SELECT title, price, link_number FROM listings WHERE id = $1`, firstID).Scan(&title, &price, &linkNumber)
+	if err != nil {
+		t.Fatalf("select query error = %v", err)
+	}
+	if title != "New title" || price != secondPrice || linkNumber != 2 {
+		t.Errorf("row = (%q, %v, link %d), want (%q, %v, link 2)", title, price, linkNumber, "New title", secondPrice)
+	}
+
+	if id, err := database.GetListingIDByURL(req.ID, url); err != nil || id != firstID {
+		t.Errorf("GetListingIDByURL() = (%d, %v), want %d", id, err, firstID)
+	}
+}
+
+func TestSaveReviewsReplacesPreviousReviews(t *testing.T) {
+	database := openTestDB(t)
+
+	req, err := database.CreateRequest(-1, 0, "https://www.airbnb.com/s/homes", false)
+	if err != nil {
+		t.Fatalf("CreateRequest() error = %v", err)
+	}
+	t.Cleanup(func() { database.conn.Exec(`DELETE FROM requests WHERE id = $1`, req.ID) })
+
+	listingID, err := database.SaveListingWithLinkNumber(req.ID, 1, "Loft", "https://www.airbnb.com/rooms/1", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("SaveListingWithLinkNumber() error = %v", err)
+	}
+
+	reviews := []models.Review{
+		{Date: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), Score: 5, FullText: "Great stay", ReviewerName: "Maria", ReviewerLocation: "Lisbon, Portugal"},
+		{Date: time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC), Score: 4, FullText: "Noisy street"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := database.SaveReviews(listingID, reviews); err != nil {
+			t.Fatalf("SaveReviews() call %d error = %v", i+1, err)
+		}
+	}
+
+	var count int
+	if err := database.conn.QueryRow(`SELECT COUNT(*) FROM listing_reviews WHERE listing_id = $1`, listingID).Scan(&count); err != nil {
+		t.Fatalf("count query error = %v", err)
+	}
+	if count != len(reviews) {
+		t.Errorf("listing has %d reviews after saving the same set twice, want %d", count, len(reviews))
+	}
+
+	var name, location sql.NullString
+	if err := database.conn.QueryRow(`SELECT reviewer_name, reviewer_location FROM listing_reviews WHERE listing_id = $1 ORDER BY date LIMIT 1`, listingID).Scan(&name, &location); err != nil {
+		t.Fatalf("reviewer query error = %v", err)
+	}
+	if name.String != "Maria" || location.String != "Lisbon, Portugal" {
+		t.Errorf("saved reviewer = %v from %v, want Maria from Lisbon, Portugal", name, location)
+	}
+}
+
+func TestRunListingsSurviveUntilDeleted(t *testing.T) {
+	database := openTestDB(t)
+
+	req, err := database.CreateRequest(-1, 0, "https://www.airbnb.com/s/homes", false)
+	if err != nil {
+		t.Fatalf("CreateRequest() error = %v", err)
+	}
+	t.Cleanup(func() { database.conn.Exec(`DELETE FROM requests WHERE id = $1`, req.ID) })
+
+	// Recorded by two runs of the scheduler, e.g. before and after a pause
+	first := []models.Listing{{URL: "https://www.airbnb.com/rooms/1", Title: "Loft", Price: 90, Currency: "€"}}
+	second := []models.Listing{{URL: "https://www.airbnb.com/rooms/2", Title: "Flat", Price: 70, Currency: "€"}, first[0]}
+	for _, listings := range [][]models.Listing{first, second} {
+		if err := database.AddRunListings(req.ID, listings); err != nil {
+			t.Fatalf("AddRunListings() error = %v", err)
+		}
+	}
+
+	got, err := database.GetRunListings(req.ID)
+	if err != nil {
+		t.Fatalf("GetRunListings() error = %v", err)
+	}
+	want := append(append([]models.Listing{}, first...), second...)
+	if len(got) != len(want) {
+		t.Fatalf("GetRunListings() returned %d listings, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].URL != want[i].URL || got[i].Price != want[i].Price || got[i].Title != want[i].Title {
+			t.Errorf("listing %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if err := database.DeleteRunListings(req.ID); err != nil {
+		t.Fatalf("DeleteRunListings() error = %v", err)
+	}
+	if got, err := database.GetRunListings(req.ID); err != nil || len(got) != 0 {
+		t.Errorf("GetRunListings() after delete = (%d listings, %v), want none", len(got), err)
+	}
+}
+
+func TestClearSharedSpreadsheetSheets(t *testing.T) {
+	database := openTestDB(t)
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		req, err := database.CreateRequest(-1, 0, "https://www.airbnb.com/s/homes", false)
+		if err != nil {
+			t.Fatalf("CreateRequest() error = %v", err)
+		}
+		ids = append(ids, req.ID)
+		t.Cleanup(func() { database.conn.Exec(`DELETE FROM requests WHERE id = $1`, req.ID) })
+	}
+	// Two requests on a deleted tab, one of them in its own spreadsheet with the same title
+	database.UpdateRequestSheetName(ids[0], "Old_20240101_120000", 11)
+	database.UpdateRequestSheetName(ids[1], "Old_20240101_120000", 11)
+	database.UpdateRequestSpreadsheet(ids[1], "own-spreadsheet")
+	database.UpdateRequestSheetName(ids[2], "Kept_20240101_120000", 12)
+
+	if err := database.ClearSharedSpreadsheetSheets([]string{"Old_20240101_120000"}); err != nil {
+		t.Fatalf("ClearSharedSpreadsheetSheets() error = %v", err)
+	}
+
+	for i, wantCleared := range []bool{true, false, false} {
+		req, err := database.GetRequestByID(ids[i])
+		if err != nil {
+			t.Fatalf("GetRequestByID() error = %v", err)
+		}
+		if cleared := !req.SheetName.Valid && !req.SheetGID.Valid; cleared != wantCleared {
+			t.Errorf("request %d: sheet cleared = %v, want %v", i, cleared, wantCleared)
+		}
+	}
+}
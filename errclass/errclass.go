@@ -0,0 +1,79 @@
+// Package errclass sorts the errors a request can fail with into a few classes, each with
+// an explanation users can act on. The raw errors only go to the logs: wrapped Go error
+// chains mean nothing to someone chatting with the bot.
+package errclass
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+
+	"bnb-fetcher/fetcher"
+
+	"github.com/lib/pq"
+	"google.golang.org/api/googleapi"
+)
+
+// Class is a category of errors users get the same explanation for
+type Class int
+
+const (
+	Unknown       Class = iota
+	Blocked             // a captcha or rate limit page instead of results
+	Timeout             // a page did not load (in time)
+	BrowserLaunch       // the headless browser could not be started
+	SheetsAccess        // the bot may not write to the spreadsheet
+	Sheets              // any other Google Sheets API error
+	Database            // a PostgreSQL or connection error
+)
+
+// Of returns the class of err, looking through wrapped errors, or Unknown
+func Of(err error) Class {
+	var apiErr *googleapi.Error
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		return Unknown
+	case errors.Is(err, fetcher.ErrBotChallenge), errors.Is(err, fetcher.ErrRateLimited):
+		return Blocked
+	case errors.Is(err, fetcher.ErrBrowserLaunch):
+		return BrowserLaunch
+	case errors.Is(err, fetcher.ErrNavigation), errors.Is(err, fetcher.ErrNoPages), errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.As(err, &apiErr):
+		switch apiErr.Code {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return SheetsAccess
+		}
+		return Sheets
+	case errors.As(err, &pqErr), errors.Is(err, sql.ErrConnDone), errors.Is(err, driver.ErrBadConn):
+		return Database
+	}
+	return Unknown
+}
+
+// Message returns what went wrong in the user's terms, with a suggested action
+func (c Class) Message() string {
+	switch c {
+	case Blocked:
+		return "Airbnb blocked the bot with a captcha or rate limit. Wait an hour or so before trying again."
+	case Timeout:
+		return "Airbnb pages didn't load in time. This is usually temporary; try again in a few minutes."
+	case BrowserLaunch:
+		return "The browser on the server failed to start. Please tell the bot admin, the server needs a look."
+	case SheetsAccess:
+		return "The bot can't write to the Google Sheet. The admin needs to share it with the bot's service account as Editor."
+	case Sheets:
+		return "Google Sheets rejected the update. Try again in a few minutes."
+	case Database:
+		return "The bot's database had a problem. Try again in a few minutes; tell the admin if it keeps happening."
+	}
+	return "Something went wrong on the bot's side. Try again; tell the admin if it keeps happening."
+}
+
+// Message returns the user-facing explanation of err's class
+func Message(err error) string {
+	return Of(err).Message()
+}
@@ -0,0 +1,39 @@
+package errclass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"bnb-fetcher/fetcher"
+
+	"github.com/lib/pq"
+	"google.golang.org/api/googleapi"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{"bot challenge", fmt.Errorf("fetch failed: %w", fetcher.ErrBotChallenge), Blocked},
+		{"rate limited", fmt.Errorf("fetch failed: %w", fetcher.ErrRateLimited), Blocked},
+		{"navigation", fmt.Errorf("fetch failed: %w: %w", fetcher.ErrNavigation, context.DeadlineExceeded), Timeout},
+		{"deadline", context.DeadlineExceeded, Timeout},
+		{"browser launch", fmt.Errorf("%w: exec: chromium not found", fetcher.ErrBrowserLaunch), BrowserLaunch},
+		{"sheet not shared", fmt.Errorf("failed to create sheet: %w", &googleapi.Error{Code: 403}), SheetsAccess},
+		{"sheets quota", fmt.Errorf("failed to create sheet: %w", &googleapi.Error{Code: 429}), Sheets},
+		{"database", fmt.Errorf("failed to count requests: %w", &pq.Error{Code: "57P01"}), Database},
+		{"anything else", errors.New("failed to parse HTML"), Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Of(tt.err); got != tt.want {
+				t.Errorf("Of(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package fetcher
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -11,13 +12,14 @@ import (
 
 // CollyFetcher implements the Fetcher interface using colly
 type CollyFetcher struct {
-	collector *colly.Collector
+	collector    *colly.Collector
+	pageProgress PageProgress // told about each fetched page, may be nil
 }
 
 // NewCollyFetcher creates a new CollyFetcher instance
 func NewCollyFetcher() *CollyFetcher {
 	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		colly.UserAgent(identity.UserAgent),
 	)
 
 	// Set rate limiting - 3-5 seconds between requests
@@ -27,24 +29,38 @@ func NewCollyFetcher() *CollyFetcher {
 		Delay:       4 * time.Second, // 4 seconds average (between 3-5)
 	})
 
-	// Set error handler
-	c.OnError(func(r *colly.Response, err error) {
-		log.Printf("Error fetching %s: %v\n", r.Request.URL, err)
-	})
-
 	return &CollyFetcher{
 		collector: c,
 	}
 }
 
+// SetPageProgress implements the ProgressReporter interface
+func (cf *CollyFetcher) SetPageProgress(progress PageProgress) {
+	cf.pageProgress = progress
+}
+
 // Fetch implements the Fetcher interface
 func (cf *CollyFetcher) Fetch(url string, maxPages int) ([]string, error) {
 	var htmlPages []string
 	pageCount := 0
 	visited := make(map[string]bool)
 
+	// Callbacks capture this call's state, so register them on a fresh clone; the clone
+	// shares the rate limits and cookies of the base collector
+	collector := cf.collector.Clone()
+
+	collector.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept-Language", identity.AcceptLanguage)
+		navigations.Wait(context.Background())
+	})
+
+	// Set error handler
+	collector.OnError(func(r *colly.Response, err error) {
+		log.Printf("Error fetching %s: %v\n", r.Request.URL, err)
+	})
+
 	// Set up callback to collect HTML from response
-	cf.collector.OnResponse(func(r *colly.Response) {
+	collector.OnResponse(func(r *colly.Response) {
 		urlStr := r.Request.URL.String()
 		htmlContent := string(r.Body)
 
@@ -65,16 +81,14 @@ func (cf *CollyFetcher) Fetch(url string, maxPages int) ([]string, error) {
 		htmlPages = append(htmlPages, htmlContent)
 		pageCount++
 		log.Printf("Fetched page %d/%d: %s\n", pageCount, maxPages, urlStr)
+		if cf.pageProgress != nil {
+			cf.pageProgress(pageCount, maxPages)
+		}
 	})
 
-	// Visit the initial URL
-	if err := cf.collector.Visit(url); err != nil {
-		return nil, fmt.Errorf("failed to visit URL: %w", err)
-	}
-
 	// Handle pagination - look for page links inside the pagination nav
 	// Visit all pagination links, but duplicates will be filtered by visited map
-	cf.collector.OnHTML("nav[aria-label='Search results pagination'] a", func(e *colly.HTMLElement) {
+	collector.OnHTML("nav[aria-label='Search results pagination'] a", func(e *colly.HTMLElement) {
 		if pageCount >= maxPages {
 			return
 		}
@@ -96,16 +110,22 @@ func (cf *CollyFetcher) Fetch(url string, maxPages int) ([]string, error) {
 
 		// Only visit if we haven't reached max pages
 		if pageCount < maxPages {
-			cf.collector.Visit(nextURL)
+			collector.Visit(nextURL)
 		}
 	})
 
+	// Visit the initial URL; pagination callbacks must be registered before it
+	if err := collector.Visit(url); err != nil {
+		return nil, fmt.Errorf("%w: failed to visit URL: %w", ErrNavigation, err)
+	}
+
 	// Wait for all requests to complete
-	cf.collector.Wait()
+	collector.Wait()
 
 	if len(htmlPages) == 0 {
 		log.Println("Warning: No HTML pages collected. Bnb may be using JavaScript rendering.")
 		log.Println("Consider upgrading to a headless browser implementation.")
+		return nil, ErrNoPages
 	}
 
 	log.Printf("Fetching completed. Total pages fetched: %d (requested: %d)\n", len(htmlPages), maxPages)
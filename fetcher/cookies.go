@@ -0,0 +1,141 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// CookieJar persists browser cookies across browser launches and restarts of the bot, so
+// the site sees a returning visitor with accepted cookies instead of a fresh profile,
+// which gets challenged less. It is opt-in, from environment variables:
+//
+//	COOKIE_JAR      file the cookies are kept in; unset disables persistence
+//	COOKIE_DOMAINS  comma separated domains whose cookies are kept (default airbnb.com);
+//	                subdomains are included
+type CookieJar struct {
+	Path    string
+	Domains []string
+}
+
+// defaultCookieDomains are the domains whose cookies are kept when COOKIE_DOMAINS is unset
+const defaultCookieDomains = "airbnb.com"
+
+// cookieJar is shared by all browsers, set with Configure at startup; nil when disabled
+var cookieJar *CookieJar
+
+// cookieJarFromEnv reads COOKIE_JAR and COOKIE_DOMAINS, returning nil when COOKIE_JAR is unset
+func cookieJarFromEnv() *CookieJar {
+	path := os.Getenv("COOKIE_JAR")
+	if path == "" {
+		return nil
+	}
+	domains := os.Getenv("COOKIE_DOMAINS")
+	if domains == "" {
+		domains = defaultCookieDomains
+	}
+	jar := &CookieJar{Path: path, Domains: parseCookieDomains(domains)}
+	if len(jar.Domains) == 0 {
+		log.Printf("Warning: Invalid COOKIE_DOMAINS=%q, using %s\n", domains, defaultCookieDomains)
+		jar.Domains = parseCookieDomains(defaultCookieDomains)
+	}
+	log.Printf("Using COOKIE_JAR=%s for %s\n", path, strings.Join(jar.Domains, ", "))
+	return jar
+}
+
+// parseCookieDomains parses a comma separated domain list, ignoring leading dots
+func parseCookieDomains(text string) []string {
+	var domains []string
+	for _, domain := range strings.Split(text, ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// keeps reports whether a cookie of the domain is persisted
+func (j *CookieJar) keeps(domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	for _, d := range j.Domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the cookies of the jar's domains that haven't expired. Session cookies
+// (without expiry) are kept: the browser they belong to is only restarted, not closed
+// by a user.
+func (j *CookieJar) filter(cookies []*proto.NetworkCookieParam, now time.Time) []*proto.NetworkCookieParam {
+	kept := make([]*proto.NetworkCookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		if !j.keeps(c.Domain) {
+			continue
+		}
+		if c.Expires <= 0 {
+			c.Expires = 0 // GetCookies reports session cookies with -1, which SetCookies takes as expired
+		} else if c.Expires.Time().Before(now) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// Restore loads the saved cookies into the browser. A missing file is not an error.
+func (j *CookieJar) Restore(browser *rod.Browser) error {
+	data, err := os.ReadFile(j.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cookie jar: %w", err)
+	}
+	var cookies []*proto.NetworkCookieParam
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return fmt.Errorf("failed to parse cookie jar %s: %w", j.Path, err)
+	}
+	cookies = j.filter(cookies, time.Now())
+	if len(cookies) == 0 {
+		return nil
+	}
+	if err := browser.SetCookies(cookies); err != nil {
+		return fmt.Errorf("failed to set cookies: %w", err)
+	}
+	log.Printf("Restored %d cookies from %s\n", len(cookies), j.Path)
+	return nil
+}
+
+// Save writes the browser's cookies of the jar's domains to the file, replacing the
+// previous ones. The file is written next to the old one and renamed, so a crash can't
+// leave it half written.
+func (j *CookieJar) Save(browser *rod.Browser) error {
+	cookies, err := browser.GetCookies()
+	if err != nil {
+		return fmt.Errorf("failed to get cookies: %w", err)
+	}
+	kept := j.filter(proto.CookiesToParams(cookies), time.Now())
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cookies: %w", err)
+	}
+	tmpPath := j.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write cookie jar: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.Path); err != nil {
+		return fmt.Errorf("failed to replace cookie jar: %w", err)
+	}
+	log.Printf("Saved %d cookies to %s\n", len(kept), j.Path)
+	return nil
+}
@@ -0,0 +1,38 @@
+package fetcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestCookieJarFilter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	future := proto.TimeSinceEpoch(now.Add(24 * time.Hour).Unix())
+	past := proto.TimeSinceEpoch(now.Add(-time.Hour).Unix())
+
+	jar := &CookieJar{Domains: parseCookieDomains(" .Airbnb.com, airbnb.co.uk ,")}
+	cookies := []*proto.NetworkCookieParam{
+		{Name: "bev", Domain: ".airbnb.com", Expires: future},
+		{Name: "session", Domain: "www.airbnb.com", Expires: -1},
+		{Name: "uk", Domain: "airbnb.co.uk", Expires: future},
+		{Name: "expired", Domain: ".airbnb.com", Expires: past},
+		{Name: "tracker", Domain: ".doubleclick.net", Expires: future},
+		{Name: "lookalike", Domain: "notairbnb.com", Expires: future},
+	}
+
+	kept := jar.filter(cookies, now)
+	want := []string{"bev", "session", "uk"}
+	if len(kept) != len(want) {
+		t.Fatalf("filter() kept %d cookies, want %d", len(kept), len(want))
+	}
+	for i, c := range kept {
+		if c.Name != want[i] {
+			t.Errorf("kept[%d] = %q, want %q", i, c.Name, want[i])
+		}
+	}
+	if kept[1].Expires != 0 {
+		t.Errorf("session cookie Expires = %v, want 0 (unset)", kept[1].Expires)
+	}
+}
@@ -1,16 +1,50 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/go-rod/rod"
 )
 
+// DetailBrowser selects where detail pages are opened, from the DETAIL_BROWSER
+// environment variable:
+//
+//	shared     tabs of the search page browser (default)
+//	incognito  an incognito context of the search page browser: separate cookies
+//	           and cache, same browser process
+//	separate   a browser process of its own, so a crash or hang while enriching
+//	           doesn't take the search pages down with it (more memory)
+type DetailBrowser string
+
+const (
+	DetailBrowserShared    DetailBrowser = "shared"
+	DetailBrowserIncognito DetailBrowser = "incognito"
+	DetailBrowserSeparate  DetailBrowser = "separate"
+)
+
+// DetailBrowserFromEnv reads DETAIL_BROWSER, falling back to shared when it is unset or unknown
+func DetailBrowserFromEnv() DetailBrowser {
+	switch value := DetailBrowser(os.Getenv("DETAIL_BROWSER")); value {
+	case "":
+		return DetailBrowserShared
+	case DetailBrowserShared, DetailBrowserIncognito, DetailBrowserSeparate:
+		log.Printf("Using DETAIL_BROWSER=%s\n", value)
+		return value
+	default:
+		log.Printf("Warning: Unknown DETAIL_BROWSER=%q (expected shared, incognito or separate), using shared\n", value)
+		return DetailBrowserShared
+	}
+}
+
 // DetailFetcher fetches detail pages for individual listings
 type DetailFetcher struct {
 	browser *rod.Browser
+	close   func() error // releases a browser or context owned by the fetcher, nil if shared
 }
 
 // NewDetailFetcher creates a new DetailFetcher using an existing browser
@@ -20,15 +54,58 @@ func NewDetailFetcher(browser *rod.Browser) *DetailFetcher {
 	}
 }
 
-// FetchDetailPage fetches the HTML content of a single listing detail page
-func (df *DetailFetcher) FetchDetailPage(url string) (string, error) {
+// NewDetailFetcherFor creates a DetailFetcher that opens pages as selected by mode,
+// next to the search pages of rodFetcher. Close it when done.
+func NewDetailFetcherFor(mode DetailBrowser, rodFetcher *RodFetcher) (*DetailFetcher, error) {
+	switch mode {
+	case DetailBrowserIncognito:
+		incognito, err := rodFetcher.GetBrowser().Incognito()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create incognito context: %w", err)
+		}
+		// Closing an incognito browser disposes its context only
+		return &DetailFetcher{browser: incognito, close: incognito.Close}, nil
+	case DetailBrowserSeparate:
+		separate, err := NewRodFetcher()
+		if err != nil {
+			return nil, fmt.Errorf("failed to launch detail page browser: %w", err)
+		}
+		return &DetailFetcher{browser: separate.GetBrowser(), close: separate.Close}, nil
+	default:
+		return NewDetailFetcher(rodFetcher.GetBrowser()), nil
+	}
+}
+
+// Close releases the browser or incognito context created for the fetcher. A shared
+// browser is left open for its owner to close.
+func (df *DetailFetcher) Close() error {
+	if df.close == nil {
+		return nil
+	}
+	return df.close()
+}
+
+// FetchDetailPage fetches the HTML content of a single listing detail page. Loading is
+// abandoned when ctx is cancelled or after DETAIL_TIMEOUT, so a hung page can't stall
+// the caller.
+func (df *DetailFetcher) FetchDetailPage(ctx context.Context, url string) (string, error) {
+	// Waiting for a turn doesn't count towards DETAIL_TIMEOUT
+	if err := navigations.Wait(ctx); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNavigation, err)
+	}
+	if timings.DetailTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timings.DetailTimeout)
+		defer cancel()
+	}
+
 	// Create a new page (use MustPage with panic recovery)
 	var page *rod.Page
 	var pageErr error
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-				pageErr = fmt.Errorf("panic while creating page: %v", r)
+				pageErr = fmt.Errorf("%w: panic while creating page: %v", ErrNoPages, r)
 				log.Printf("Panic while creating page: %v\n", r)
 			}
 		}()
@@ -38,34 +115,74 @@ func (df *DetailFetcher) FetchDetailPage(url string) (string, error) {
 		return "", pageErr
 	}
 	if page == nil {
-		return "", fmt.Errorf("failed to create page")
+		return "", fmt.Errorf("%w: failed to create page", ErrNoPages)
 	}
+	// Close through the original page: the one bound to ctx can't send commands once ctx is done
 	defer page.Close()
+	applyIdentity(page)
+
+	html, err := loadDetailPage(ctx, page.Context(ctx), url)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = fmt.Errorf("%w: %w", err, ctxErr)
+		}
+		return "", err
+	}
+	if !hasListingLinks(html) {
+		if err := checkBlocked(html); err != nil {
+			return "", err
+		}
+	}
+
+	return html, nil
+}
 
+// loadDetailPage navigates page to url and returns its HTML once rendered. Every step
+// fails as soon as ctx, which page is bound to, is done.
+func loadDetailPage(ctx context.Context, page *rod.Page, url string) (string, error) {
 	// Navigate to the URL
 	if err := page.Navigate(url); err != nil {
-		return "", fmt.Errorf("failed to navigate: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrNavigation, err)
 	}
 
 	// Wait for page to load
-	page.WaitLoad()
+	if err := page.WaitLoad(); err != nil && ctx.Err() != nil {
+		return "", fmt.Errorf("%w: page did not load: %w", ErrNavigation, err)
+	}
 
 	// Wait for initial JS execution before WaitStable
-	time.Sleep(2 * time.Second)
+	if err := sleepContext(ctx, timings.DetailLoadWait); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNavigation, err)
+	}
 
 	// Wait for page to stabilize (this is more efficient than fixed sleeps)
-	// Reduced timeout from 10s to 5s and stability check from 500ms to 300ms
-	if err := page.Timeout(5 * time.Second).WaitStable(300 * time.Millisecond); err != nil {
+	if err := page.Timeout(timings.DetailStableTimeout).WaitStable(timings.DetailStableWindow); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%w: %w", ErrNavigation, err)
+		}
 		log.Printf("Warning: Detail page did not stabilize within timeout, continuing anyway: %v\n", err)
 		// If WaitStable fails, give a minimal fallback wait
-		time.Sleep(500 * time.Millisecond)
+		if err := sleepContext(ctx, 500*time.Millisecond); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrNavigation, err)
+		}
 	}
 
 	// Get HTML content
 	html, err := page.HTML()
 	if err != nil {
-		return "", fmt.Errorf("failed to get HTML: %w", err)
+		return "", fmt.Errorf("%w: failed to get HTML: %w", ErrNavigation, err)
 	}
-
 	return html, nil
 }
+
+// sleepContext waits for d, returning ctx's error early if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package fetcher
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors returned by Fetch and FetchDetailPage, wrapped with details. Callers decide
+// how to react with errors.Is.
+var (
+	// ErrNoPages means no page could be fetched at all, e.g. the browser tab could not be created
+	ErrNoPages = errors.New("no pages fetched")
+	// ErrNavigation means loading a page or reading its HTML failed; usually transient
+	ErrNavigation = errors.New("navigation failed")
+	// ErrNoListings means the search page loaded normally but has no results; retrying won't help
+	ErrNoListings = errors.New("no listings on page")
+	// ErrBotChallenge means a captcha or block page was served instead of the content
+	ErrBotChallenge = errors.New("bot challenge page")
+	// ErrRateLimited means the site asked to slow down (HTTP 429 page)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrBrowserLaunch means the browser could not be started or connected to
+	ErrBrowserLaunch = errors.New("failed to launch browser")
+)
+
+// botChallengeMarkers appear (lowercased) on captcha and block pages
+var botChallengeMarkers = []string{
+	"px-captcha",
+	"verify you are a human",
+	"verify you are human",
+	"access to this page has been denied",
+	"challenge-platform",
+	"unusual traffic",
+}
+
+// rateLimitMarkers appear (lowercased) on rate limit pages
+var rateLimitMarkers = []string{
+	"429 too many requests",
+	"too many requests",
+}
+
+// hasListingLinks reports whether the HTML links to any listing, which a block page never does
+func hasListingLinks(html string) bool {
+	return strings.Contains(html, "/rooms/")
+}
+
+// checkBlocked returns ErrBotChallenge or ErrRateLimited if the HTML is a block page,
+// nil otherwise. Only call it for pages without listing links: normal pages may contain
+// the markers in scripts.
+func checkBlocked(html string) error {
+	lower := strings.ToLower(html)
+	for _, marker := range botChallengeMarkers {
+		if strings.Contains(lower, marker) {
+			return ErrBotChallenge
+		}
+	}
+	for _, marker := range rateLimitMarkers {
+		if strings.Contains(lower, marker) {
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
+
+// classifySearchPage returns nil for a search page with results, ErrBotChallenge or
+// ErrRateLimited for a block page and ErrNoListings for an empty result page
+func classifySearchPage(html string) error {
+	if hasListingLinks(html) {
+		return nil
+	}
+	if err := checkBlocked(html); err != nil {
+		return err
+	}
+	return ErrNoListings
+}
@@ -0,0 +1,29 @@
+package fetcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifySearchPage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want error
+	}{
+		{"results", `<a href="/rooms/123">Loft</a>`, nil},
+		{"results mentioning captcha in a script", `<script>loadRecaptcha("px-captcha")</script><a href="/rooms/1">x</a>`, nil},
+		{"perimeterx captcha", `<div id="px-captcha"></div><p>Press &amp; Hold to confirm you are a human</p>`, ErrBotChallenge},
+		{"access denied", `<h1>Access to this page has been denied.</h1>`, ErrBotChallenge},
+		{"rate limited", `<title>429 Too Many Requests</title>`, ErrRateLimited},
+		{"empty results", `<h1>No exact matches</h1><p>Try changing or removing some of your filters.</p>`, ErrNoListings},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifySearchPage(tt.html); !errors.Is(got, tt.want) || (tt.want == nil && got != nil) {
+				t.Errorf("classifySearchPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
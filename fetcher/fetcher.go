@@ -7,6 +7,54 @@ type Fetcher interface {
 	Fetch(url string, maxPages int) ([]string, error)
 }
 
+// PageStreamer is implemented by fetchers that hand each page to the caller as soon as it
+// is fetched, so the HTML of all pages never has to be held at once
+type PageStreamer interface {
+	// FetchStream fetches up to maxPages pages, calling onPage with each page's 1-based
+	// number and HTML in order. Time spent in onPage counts toward the wait before the
+	// next page. If onPage returns an error, fetching stops and the error is returned.
+	FetchStream(url string, maxPages int, onPage func(pageNum int, html string) error) error
+}
+
+// PageProgress is told the 1-based number of each search page as soon as it is fetched,
+// out of at most maxPages. Fetchers that collect all pages before returning them call it
+// long before the pages reach the caller.
+type PageProgress func(pageNum, maxPages int)
+
+// ProgressReporter is implemented by fetchers that report each page as they fetch it
+type ProgressReporter interface {
+	// SetPageProgress sets the function told about fetched pages; nil stops reporting.
+	// It must not be changed while a fetch is running.
+	SetPageProgress(progress PageProgress)
+}
 
+// SetPageProgress makes f report fetched pages to progress, and reports whether f
+// supports it
+func SetPageProgress(f Fetcher, progress PageProgress) bool {
+	reporter, ok := f.(ProgressReporter)
+	if ok {
+		reporter.SetPageProgress(progress)
+	}
+	return ok
+}
 
+// FetchEach fetches up to maxPages pages with f and calls onPage for each of them,
+// streaming them if f is a PageStreamer. If onPage returns an error, the remaining
+// pages are skipped and the error is returned.
+func FetchEach(f Fetcher, url string, maxPages int, onPage func(pageNum int, html string) error) error {
+	if streamer, ok := f.(PageStreamer); ok {
+		return streamer.FetchStream(url, maxPages, onPage)
+	}
 
+	htmlPages, err := f.Fetch(url, maxPages)
+	if err != nil {
+		return err
+	}
+	for i := range htmlPages {
+		if err := onPage(i+1, htmlPages[i]); err != nil {
+			return err
+		}
+		htmlPages[i] = "" // release HTML
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sliceFetcher returns fixed pages from Fetch
+type sliceFetcher struct {
+	pages []string
+	err   error
+}
+
+func (f sliceFetcher) Fetch(url string, maxPages int) ([]string, error) {
+	return f.pages, f.err
+}
+
+// streamingFetcher hands fixed pages to FetchStream and fails if Fetch is used
+type streamingFetcher struct {
+	sliceFetcher
+	t *testing.T
+}
+
+func (f streamingFetcher) Fetch(url string, maxPages int) ([]string, error) {
+	f.t.Error("Fetch() called on a PageStreamer")
+	return nil, nil
+}
+
+func (f streamingFetcher) FetchStream(url string, maxPages int, onPage func(pageNum int, html string) error) error {
+	for i, html := range f.pages {
+		if err := onPage(i+1, html); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func TestFetchEach(t *testing.T) {
+	pages := []string{"<p>1</p>", "<p>2</p>"}
+	// FetchEach releases the HTML it was given, so each fetcher gets its own copy
+	fetchers := map[string]Fetcher{
+		"fetcher":  sliceFetcher{pages: append([]string(nil), pages...)},
+		"streamer": streamingFetcher{sliceFetcher: sliceFetcher{pages: append([]string(nil), pages...)}, t: t},
+	}
+
+	for name, f := range fetchers {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			err := FetchEach(f, "https://www.airbnb.com/s/homes", 5, func(pageNum int, html string) error {
+				if pageNum != len(got)+1 {
+					t.Errorf("page number = %d, want %d", pageNum, len(got)+1)
+				}
+				got = append(got, html)
+				return nil
+			})
+			if err != nil || len(got) != len(pages) {
+				t.Fatalf("FetchEach() handled %d pages (error %v), want %d", len(got), err, len(pages))
+			}
+			for i := range pages {
+				if got[i] != pages[i] {
+					t.Errorf("page %d = %q, want %q", i+1, got[i], pages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchEachError(t *testing.T) {
+	f := sliceFetcher{err: ErrBotChallenge}
+	err := FetchEach(f, "https://www.airbnb.com/s/homes", 5, func(int, string) error {
+		t.Error("onPage called after a failed fetch")
+		return nil
+	})
+	if !errors.Is(err, ErrBotChallenge) {
+		t.Errorf("FetchEach() error = %v, want %v", err, ErrBotChallenge)
+	}
+}
+
+func TestFetchEachStopsOnCallbackError(t *testing.T) {
+	errStop := errors.New("enough pages")
+	fetchers := map[string]Fetcher{
+		"fetcher":  sliceFetcher{pages: []string{"1", "2", "3"}},
+		"streamer": streamingFetcher{sliceFetcher: sliceFetcher{pages: []string{"1", "2", "3"}}, t: t},
+	}
+
+	for name, f := range fetchers {
+		t.Run(name, func(t *testing.T) {
+			handled := 0
+			err := FetchEach(f, "https://www.airbnb.com/s/homes", 5, func(pageNum int, html string) error {
+				handled++
+				if pageNum == 2 {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, errStop) || handled != 2 {
+				t.Errorf("FetchEach() handled %d pages with error %v, want 2 pages and %v", handled, err, errStop)
+			}
+		})
+	}
+}
+
+func TestFallbackFetcher(t *testing.T) {
+	usable := func(html string) bool { return strings.Contains(html, "/rooms/") }
+	rodPages := []string{`<a href="/rooms/2">rod</a>`}
+
+	tests := []struct {
+		name    string
+		primary Fetcher
+		want    string // first page handed to onPage
+	}{
+		{"primary pages with listings", sliceFetcher{pages: []string{"<p>empty</p>", `<a href="/rooms/1">colly</a>`}}, "<p>empty</p>"},
+		{"primary pages without listings", sliceFetcher{pages: []string{"<p>rendered by JavaScript</p>"}}, rodPages[0]},
+		{"primary error", sliceFetcher{err: ErrNoPages}, rodPages[0]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallback := streamingFetcher{sliceFetcher: sliceFetcher{pages: append([]string(nil), rodPages...)}, t: t}
+			f := NewFallbackFetcher(tt.primary, fallback, usable)
+
+			var got []string
+			err := FetchEach(f, "https://www.airbnb.com/s/homes", 5, func(pageNum int, html string) error {
+				got = append(got, html)
+				return nil
+			})
+			if err != nil || len(got) == 0 {
+				t.Fatalf("FetchEach() handled %d pages (error %v)", len(got), err)
+			}
+			if got[0] != tt.want {
+				t.Errorf("first page = %q, want %q", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPageProgress(t *testing.T) {
+	progress := func(pageNum, maxPages int) {}
+
+	if SetPageProgress(sliceFetcher{}, progress) {
+		t.Error("SetPageProgress() = true for a fetcher that can't report pages")
+	}
+
+	colly, rod := NewCollyFetcher(), &RodFetcher{}
+	fallback := NewFallbackFetcher(colly, rod, func(string) bool { return true })
+	if !SetPageProgress(fallback, progress) {
+		t.Fatal("SetPageProgress() = false for a FallbackFetcher")
+	}
+	if colly.pageProgress == nil || rod.pageProgress == nil {
+		t.Error("FallbackFetcher didn't pass the progress function to both fetchers")
+	}
+
+	SetPageProgress(fallback, nil)
+	if colly.pageProgress != nil || rod.pageProgress != nil {
+		t.Error("SetPageProgress(nil) didn't stop the reporting")
+	}
+}
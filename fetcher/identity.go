@@ -0,0 +1,57 @@
+package fetcher
+
+import (
+	"log"
+	"os"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// The browser identity sent with every page. Headless Chrome's own user agent contains
+// "HeadlessChrome", which is easy to fingerprint, and without a language the site may
+// serve a localized layout that the English-keyword selectors miss. Override with the
+// USER_AGENT and ACCEPT_LANGUAGE environment variables. With DEVICE=mobile the default
+// user agent is DefaultMobileUserAgent.
+const (
+	DefaultUserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	DefaultAcceptLanguage = "en-US,en;q=0.9"
+)
+
+// identity is shared by all fetchers, set with Configure at startup
+var identity = defaultIdentity(false)
+
+// defaultIdentity returns the user agent override of a desktop or mobile browser
+func defaultIdentity(mobile bool) *proto.NetworkSetUserAgentOverride {
+	override := &proto.NetworkSetUserAgentOverride{
+		UserAgent:      DefaultUserAgent,
+		AcceptLanguage: DefaultAcceptLanguage,
+	}
+	if mobile {
+		override.UserAgent = DefaultMobileUserAgent
+	}
+	return override
+}
+
+// loadIdentity builds the user agent override from the environment, falling back to the
+// defaults of a desktop or mobile browser
+func loadIdentity(mobile bool) *proto.NetworkSetUserAgentOverride {
+	override := defaultIdentity(mobile)
+	if value := os.Getenv("USER_AGENT"); value != "" {
+		log.Printf("Using USER_AGENT=%s\n", value)
+		override.UserAgent = value
+	}
+	if value := os.Getenv("ACCEPT_LANGUAGE"); value != "" {
+		log.Printf("Using ACCEPT_LANGUAGE=%s\n", value)
+		override.AcceptLanguage = value
+	}
+	return override
+}
+
+// applyIdentity sets the user agent and Accept-Language header (and navigator.language)
+// of a new page. Failing to set them is logged, the page still works with the defaults.
+func applyIdentity(page *rod.Page) {
+	if err := page.SetUserAgent(identity); err != nil {
+		log.Printf("Warning: Failed to set user agent: %v\n", err)
+	}
+}
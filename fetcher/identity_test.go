@@ -0,0 +1,22 @@
+package fetcher
+
+import "testing"
+
+func TestLoadIdentity(t *testing.T) {
+	t.Setenv("USER_AGENT", "")
+	t.Setenv("ACCEPT_LANGUAGE", "")
+	override := loadIdentity(false)
+	if override.UserAgent != DefaultUserAgent || override.AcceptLanguage != DefaultAcceptLanguage {
+		t.Errorf("loadIdentity() = (%q, %q), want the defaults", override.UserAgent, override.AcceptLanguage)
+	}
+	if override := loadIdentity(true); override.UserAgent != DefaultMobileUserAgent {
+		t.Errorf("loadIdentity(mobile) user agent = %q, want %q", override.UserAgent, DefaultMobileUserAgent)
+	}
+
+	t.Setenv("USER_AGENT", "TestAgent/1.0")
+	t.Setenv("ACCEPT_LANGUAGE", "th-TH")
+	override = loadIdentity(true)
+	if override.UserAgent != "TestAgent/1.0" || override.AcceptLanguage != "th-TH" {
+		t.Errorf("loadIdentity() = (%q, %q), want the environment values", override.UserAgent, override.AcceptLanguage)
+	}
+}
@@ -0,0 +1,49 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out events shared by several goroutines: each Wait returns at least
+// interval after the previous one did, whichever goroutine made it. It is a token bucket
+// holding a single token, so there are no bursts after idle periods either.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // earliest time the next Wait may return
+}
+
+// NewLimiter creates a Limiter that lets one event through per interval. An interval of
+// 0 disables limiting.
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval}
+}
+
+// Wait blocks until the caller's turn comes or ctx is done. Turns are handed out in call
+// order; a turn given up because ctx ended is not reused by later callers.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	turn := l.next
+	if turn.Before(now) {
+		turn = now
+	}
+	l.next = turn.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait := turn.Sub(now); wait > 0 {
+		return sleepContext(ctx, wait)
+	}
+	return ctx.Err()
+}
+
+// navigations spaces out page loads across all browsers and fetchers, whoever they run
+// for, by NAVIGATION_INTERVAL. Every navigation to the site acquires a turn first.
+// Configure replaces it when the interval changes.
+var navigations = NewLimiter(DefaultTimings().NavigationInterval)
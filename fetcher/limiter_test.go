@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterSpacesWaits(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	l := NewLimiter(interval)
+
+	start := time.Now()
+	var mu sync.Mutex
+	var times []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(context.Background()); err != nil {
+				t.Errorf("Wait() error = %v", err)
+			}
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// The i-th wait to return can't return before i intervals have passed. Times are taken
+	// after Wait returns, so a goroutine scheduled late only makes them later.
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for i, at := range times {
+		if elapsed := at.Sub(start); elapsed < time.Duration(i)*interval {
+			t.Errorf("wait %d returned after %s, want at least %s", i, elapsed, time.Duration(i)*interval)
+		}
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := NewLimiter(time.Hour)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() error = %v, want no wait", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := NewLimiter(0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("disabled limiter waited %s", elapsed)
+	}
+}
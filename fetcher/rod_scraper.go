@@ -1,6 +1,8 @@
 package fetcher
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"net/url"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/go-rod/rod"
 	rodlauncher "github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
 )
 
 // extractURLPath extracts the path from a URL, removing the domain
@@ -37,10 +40,22 @@ type RodFetcher struct {
 	browser     *rod.Browser
 	launcher    *rodlauncher.Launcher
 	userDataDir string // Temporary directory to clean up on close
+
+	pageProgress PageProgress // told about each fetched search page, may be nil
 }
 
 // NewRodFetcher creates a new RodFetcher instance
 func NewRodFetcher() (*RodFetcher, error) {
+	rf := &RodFetcher{}
+	if err := rf.launch(); err != nil {
+		rf.Close() // release whatever the failed launch left behind
+		return nil, err
+	}
+	return rf, nil
+}
+
+// launch starts a browser with a fresh profile and connects to it
+func (rf *RodFetcher) launch() error {
 	// Create a unique temporary directory for this browser instance
 	// This avoids profile locking issues when multiple instances run or when 
 	// previous instances didn't close properly
@@ -90,6 +105,9 @@ func NewRodFetcher() (*RodFetcher, error) {
 		Set("disable-renderer-backgrounding").
 		Set("disable-ipc-flooding-protection").
 		Set("disable-features", "TranslateUI,BlinkGenPropertyTrees")
+	if browserProxy.Server != "" {
+		rodLauncher = rodLauncher.Set("proxy-server", browserProxy.Server)
+	}
 
 	// Try to find Chrome in common locations (Windows)
 	chromePaths := []string{
@@ -129,31 +147,40 @@ func NewRodFetcher() (*RodFetcher, error) {
 		}
 	}
 
+	// Keep the launcher and profile so Close cleans them up even if connecting fails
+	rf.launcher = rodLauncher
+	rf.userDataDir = userDataDir
+
 	browserURL, err := rodLauncher.Launch()
 	if err != nil {
-		return nil, fmt.Errorf("failed to launch browser: %w\n\nNote: On Linux, you may need to install Chromium dependencies:\n  apt-get update && apt-get install -y chromium chromium-sandbox || yum install -y chromium", err)
+		return fmt.Errorf("%w: %w\n\nNote: On Linux, you may need to install Chromium dependencies:\n  apt-get update && apt-get install -y chromium chromium-sandbox || yum install -y chromium", ErrBrowserLaunch, err)
 	}
 
-	browser := rod.New().ControlURL(browserURL)
+	browser := rod.New().ControlURL(browserURL).DefaultDevice(viewport.device())
 	if err := browser.Connect(); err != nil {
-		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+		return fmt.Errorf("%w: failed to connect: %w", ErrBrowserLaunch, err)
 	}
-
-	return &RodFetcher{
-		browser:     browser,
-		launcher:    rodLauncher,
-		userDataDir: userDataDir,
-	}, nil
+	browserProxy.handleProxyAuth(browser)
+	rf.browser = browser
+	if cookieJar != nil {
+		if err := cookieJar.Restore(browser); err != nil {
+			log.Printf("Warning: Starting without saved cookies: %v\n", err)
+		}
+	}
+	return nil
 }
 
-// Close closes the browser and cleans up temporary files
+// Close closes the browser and cleans up temporary files. Closing twice is a no-op.
 func (rf *RodFetcher) Close() error {
 	var err error
 	if rf.browser != nil {
+		rf.SaveCookies()
 		err = rf.browser.Close()
+		rf.browser = nil
 	}
 	if rf.launcher != nil {
 		rf.launcher.Kill()
+		rf.launcher = nil
 	}
 	// Clean up temporary user data directory
 	if rf.userDataDir != "" {
@@ -162,10 +189,45 @@ func (rf *RodFetcher) Close() error {
 		} else {
 			log.Printf("Cleaned up temporary browser profile: %s\n", rf.userDataDir)
 		}
+		rf.userDataDir = ""
 	}
 	return err
 }
 
+// Restart closes the browser and launches a new one with a fresh profile, releasing
+// the memory the old browser process held. The fetcher can be used again afterwards,
+// unless an error is returned.
+func (rf *RodFetcher) Restart() error {
+	if err := rf.Close(); err != nil {
+		log.Printf("Warning: Failed to close browser before restart: %v\n", err)
+	}
+	if err := rf.launch(); err != nil {
+		rf.Close() // release whatever the failed launch left behind
+		return err
+	}
+	return nil
+}
+
+// Alive reports whether the browser still responds, e.g. after a crash of its process
+func (rf *RodFetcher) Alive() bool {
+	if rf.browser == nil {
+		return false
+	}
+	_, err := rf.browser.Pages()
+	return err == nil
+}
+
+// SaveCookies writes the browser's cookies to the cookie jar, if COOKIE_JAR enables it.
+// Failures are only logged: the next launch just starts with fewer cookies.
+func (rf *RodFetcher) SaveCookies() {
+	if cookieJar == nil || rf.browser == nil {
+		return
+	}
+	if err := cookieJar.Save(rf.browser); err != nil {
+		log.Printf("Warning: Failed to save cookies: %v\n", err)
+	}
+}
+
 // GetBrowser returns the underlying browser instance
 func (rf *RodFetcher) GetBrowser() *rod.Browser {
 	return rf.browser
@@ -174,10 +236,12 @@ func (rf *RodFetcher) GetBrowser() *rod.Browser {
 // findNextPageLink finds the next page link within the pagination navigation.
 // It scopes the search to nav[aria-label='Search results pagination'] to avoid
 // clicking on carousel/calendar controls. Returns the href URL, the element, and any error.
-// Selectors tried in order:
+// A next button without an href is returned with an error and an empty URL; the caller
+// may click it. Selectors tried in order:
 //   - a[rel='next'] within the nav
 //   - a[aria-label='Next'] or a[aria-label='next'] within the nav
 //   - button[data-testid='pagination-right-button'] within the nav
+//   - any visible link or button whose aria-label contains "next"
 func (rf *RodFetcher) findNextPageLink(page *rod.Page) (string, *rod.Element, error) {
 	// First, try to find the pagination nav
 	nav, err := page.Timeout(3 * time.Second).Element("nav[aria-label='Search results pagination']")
@@ -206,10 +270,8 @@ func (rf *RodFetcher) findNextPageLink(page *rod.Page) (string, *rod.Element, er
 	// Strategy 3: Look for button with pagination data-testid within the nav
 	nextButton, err := nav.Timeout(2 * time.Second).Element("button[data-testid='pagination-right-button']")
 	if err == nil {
-		// For buttons, we need to check if they have an href or if we need to click
-		// Try to find a parent link or check if button triggers navigation
-		// For now, return error to use click fallback - but this should be rare
-		return "", nextButton, fmt.Errorf("found button but no href - button-based pagination not yet supported")
+		// Buttons paginate by script, so there is no URL to navigate to
+		return "", nextButton, fmt.Errorf("found next button without href")
 	}
 
 	// Strategy 4: Look for any link/button with "next" in aria-label within nav
@@ -226,6 +288,7 @@ func (rf *RodFetcher) findNextPageLink(page *rod.Page) (string, *rod.Element, er
 					if href != nil && *href != "" {
 						return *href, elem, nil
 					}
+					return "", elem, fmt.Errorf("found next control without href")
 				}
 			}
 		}
@@ -262,9 +325,93 @@ func (rf *RodFetcher) extractItemsOffset(urlStr string) int {
 	return offset
 }
 
+// defaultPageSize is the number of listings per search results page when the URL
+// does not specify items_per_grid
+const defaultPageSize = 18
+
+// nextItemsOffsetURL builds the URL of the next results page by advancing items_offset
+// by the page size (items_per_grid, or 18). page_cursor is dropped since it encodes the
+// current page and would override the new offset. Returns false if the URL has no
+// items_offset to advance.
+func nextItemsOffsetURL(urlStr string) (string, bool) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", false
+	}
+
+	q := parsedURL.Query()
+	offset, err := strconv.Atoi(q.Get("items_offset"))
+	if err != nil || offset < 0 {
+		return "", false
+	}
+
+	pageSize := defaultPageSize
+	if perGrid, err := strconv.Atoi(q.Get("items_per_grid")); err == nil && perGrid > 0 {
+		pageSize = perGrid
+	}
+
+	q.Set("items_offset", strconv.Itoa(offset+pageSize))
+	q.Del("page_cursor")
+	parsedURL.RawQuery = q.Encode()
+	return parsedURL.String(), true
+}
+
+// pageTracker follows pagination through a search and reports when it stops advancing.
+// Pages are compared by content hash against every page fetched so far: date-less
+// ("I'm flexible") searches have no items_offset to compare, and a pager that cycles
+// back to an earlier page would otherwise be followed until maxPages.
+type pageTracker struct {
+	offset int                       // items_offset of the last page, -1 if the URL has none
+	seen   map[[sha256.Size]byte]int // page content hash -> page number
+}
+
+// newPageTracker starts tracking at the first page
+func newPageTracker(offset int, html string) *pageTracker {
+	t := &pageTracker{offset: offset, seen: make(map[[sha256.Size]byte]int)}
+	t.seen[sha256.Sum256([]byte(html))] = 1
+	return t
+}
+
+// advance records the page reached by following the pager. It returns why pagination
+// should stop instead, or "" if the page is new.
+func (t *pageTracker) advance(offset int, html string, usedOffsetFallback bool) string {
+	// A synthesized URL past the last page gets redirected back, so require real progress
+	if usedOffsetFallback && offset <= t.offset {
+		return fmt.Sprintf("items_offset fallback did not advance (was %d, now %d)", t.offset, offset)
+	}
+	if offset >= 0 && offset <= t.offset {
+		log.Printf("Warning: items_offset did not increase (was %d, now %d), comparing page content\n", t.offset, offset)
+	}
+
+	hash := sha256.Sum256([]byte(html))
+	if page, ok := t.seen[hash]; ok {
+		return fmt.Sprintf("content is identical to page %d", page)
+	}
+	t.seen[hash] = len(t.seen) + 1
+	t.offset = offset
+	return ""
+}
+
+// SetPageProgress implements the ProgressReporter interface
+func (rf *RodFetcher) SetPageProgress(progress PageProgress) {
+	rf.pageProgress = progress
+}
+
 // Fetch implements the Fetcher interface
 func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 	var htmlPages []string
+	if err := rf.FetchStream(url, maxPages, func(_ int, html string) error {
+		htmlPages = append(htmlPages, html)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return htmlPages, nil
+}
+
+// FetchStream implements the PageStreamer interface. Only a hash of each page is kept for
+// duplicate detection; the HTML itself is handed to onPage and not retained.
+func (rf *RodFetcher) FetchStream(url string, maxPages int, onPage func(pageNum int, html string) error) error {
 	pageCount := 0
 
 	log.Printf("Starting fetch with maxPages: %d\n", maxPages)
@@ -275,41 +422,54 @@ func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-				pageErr = fmt.Errorf("panic while creating page: %v", r)
+				pageErr = fmt.Errorf("%w: panic while creating page: %v", ErrNoPages, r)
 				log.Printf("Panic while creating page: %v\n", r)
 			}
 		}()
 		page = rf.browser.MustPage()
 	}()
 	if pageErr != nil {
-		return nil, pageErr
+		return pageErr
 	}
 	if page == nil {
-		return nil, fmt.Errorf("failed to create page")
+		return fmt.Errorf("%w: failed to create page", ErrNoPages)
 	}
 	defer page.Close()
+	applyIdentity(page)
 
 	// Navigate to the URL
+	navigations.Wait(context.Background())
 	if err := page.Navigate(url); err != nil {
-		return nil, fmt.Errorf("failed to navigate: %w", err)
+		return fmt.Errorf("%w: %w", ErrNavigation, err)
 	}
 
 	// Wait for page to load and listings to appear
 	page.WaitLoad()
-	time.Sleep(3 * time.Second) // Give JavaScript time to render
+	time.Sleep(timings.PageLoadWait) // Give JavaScript time to render
 
 	// Try to wait for listing elements to appear (with timeout and error handling)
-	if err := page.Timeout(10 * time.Second).WaitStable(500 * time.Millisecond); err != nil {
+	if err := page.Timeout(timings.StableTimeout).WaitStable(timings.StableWindow); err != nil {
 		log.Printf("Warning: Page did not stabilize within timeout, continuing anyway: %v\n", err)
 	}
 
 	// Get HTML content
 	html, err := page.HTML()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get HTML: %w", err)
+		return fmt.Errorf("%w: failed to get HTML: %w", ErrNavigation, err)
+	}
+
+	// Don't paginate through a block page or an empty result page
+	if err := classifySearchPage(html); err != nil {
+		return err
 	}
-	htmlPages = append(htmlPages, html)
 	pageCount++
+	pageReceived := time.Now()
+	if rf.pageProgress != nil {
+		rf.pageProgress(pageCount, maxPages)
+	}
+	if err := onPage(pageCount, html); err != nil {
+		return err
+	}
 
 	// Get current URL and extract items_offset for validation
 	currentURLResult, err := page.Eval(`() => window.location.href`)
@@ -319,14 +479,14 @@ func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 	}
 	log.Printf("Fetched page %d/%d (URL: %s)\n", pageCount, maxPages, extractURLPath(currentURLStr))
 
-	// Extract items_offset from current URL for validation
-	currentOffset := rf.extractItemsOffset(currentURLStr)
-	log.Printf("Current items_offset: %d\n", currentOffset)
+	// Track offsets and page contents to notice when pagination stops advancing
+	tracker := newPageTracker(rf.extractItemsOffset(currentURLStr), html)
 
 	// Handle pagination
 	for pageCount < maxPages {
-		// Add delay between page requests (bigger window to reduce blocking)
-		time.Sleep(7 * time.Second)
+		// Add delay between page requests (bigger window to reduce blocking); the time the
+		// caller spent on the previous page counts toward it
+		time.Sleep(timings.PageInterval - time.Since(pageReceived))
 
 		// Get current URL before navigation attempt
 		beforeURLResult, err := page.Eval(`() => window.location.href`)
@@ -338,9 +498,22 @@ func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 
 		// Find next page link within pagination nav
 		nextURL, nextElement, err := rf.findNextPageLink(page)
+		usedOffsetFallback, clickNext := false, false
 		if err != nil || nextURL == "" {
-			log.Printf("No more pages found after page %d: %v\n", pageCount, err)
-			break
+			// Fallback: the pager may be hidden or not rendered yet, so build the URL from items_offset
+			if fallbackURL, ok := nextItemsOffsetURL(beforeURLStr); ok {
+				log.Printf("No next page link found (%v), falling back to items_offset URL\n", err)
+				nextURL, nextElement = fallbackURL, nil
+				usedOffsetFallback = true
+			} else if nextElement != nil {
+				// Last resort: a next button that paginates by script; the page tracker
+				// stops if clicking it doesn't load a new page
+				log.Printf("No next page link found (%v), clicking the next button\n", err)
+				clickNext = true
+			} else {
+				log.Printf("No more pages found after page %d: %v\n", pageCount, err)
+				break
+			}
 		}
 
 		// Log what we found
@@ -355,30 +528,38 @@ func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 			log.Printf("Found next page element - Tag: %s, aria-label: %v, href: %v\n",
 				tagName, ariaLabel, href)
 		}
-		log.Printf("Next page URL: %s\n", extractURLPath(nextURL))
+		navigations.Wait(context.Background())
+		if clickNext {
+			if err := nextElement.Click(proto.InputMouseButtonLeft, 1); err != nil {
+				log.Printf("Failed to click the next button: %v\n", err)
+				break
+			}
+		} else {
+			log.Printf("Next page URL: %s\n", extractURLPath(nextURL))
 
-		// Normalize URL (handle relative URLs)
-		if strings.HasPrefix(nextURL, "/") {
-			nextURL = "https://www.airbnb.com" + nextURL
-		}
+			// Normalize URL (handle relative URLs)
+			if strings.HasPrefix(nextURL, "/") {
+				nextURL = "https://www.airbnb.com" + nextURL
+			}
 
-		// Navigate to next page
-		if err := page.Navigate(nextURL); err != nil {
-			log.Printf("Failed to navigate to next page: %v\n", err)
-			break
+			// Navigate to next page
+			if err := page.Navigate(nextURL); err != nil {
+				log.Printf("Failed to navigate to next page: %v\n", err)
+				break
+			}
 		}
 
 		// Wait for page to load
 		page.WaitLoad()
-		time.Sleep(5 * time.Second) // Give JavaScript time to render
+		time.Sleep(timings.NextPageLoadWait) // Give JavaScript time to render
 
 		// Wait for page to stabilize
-		if err := page.Timeout(15 * time.Second).WaitStable(500 * time.Millisecond); err != nil {
+		if err := page.Timeout(timings.NextStableTimeout).WaitStable(timings.StableWindow); err != nil {
 			log.Printf("Warning: Page did not stabilize after navigation, continuing anyway: %v\n", err)
 		}
 
 		// Additional wait to ensure listings are rendered
-		time.Sleep(3 * time.Second)
+		time.Sleep(timings.PageSettleWait)
 
 		// Get URL after navigation to validate progress
 		afterURLResult, err := page.Eval(`() => window.location.href`)
@@ -388,64 +569,41 @@ func (rf *RodFetcher) Fetch(url string, maxPages int) ([]string, error) {
 		}
 		log.Printf("After navigation - Current URL: %s\n", extractURLPath(afterURLStr))
 
-		// Validate that we actually moved to a new page by checking items_offset
-		newOffset := rf.extractItemsOffset(afterURLStr)
-		log.Printf("New items_offset: %d (previous: %d)\n", newOffset, currentOffset)
-
-		if newOffset <= currentOffset && newOffset >= 0 {
-			log.Printf("Warning: items_offset did not increase (was %d, now %d). Page may not have advanced.\n",
-				currentOffset, newOffset)
-			// Check HTML content as fallback validation
-			html, err := page.HTML()
-			if err != nil {
-				log.Printf("Failed to get HTML for validation: %v\n", err)
-				break
-			}
-			// Compare with last page - if HTML is identical, it's a duplicate
-			if len(htmlPages) > 0 && html == htmlPages[len(htmlPages)-1] {
-				log.Printf("HTML is identical to previous page, stopping pagination\n")
-				break
-			}
-		}
-
-		// Update current offset for next iteration
-		currentOffset = newOffset
-
 		// Get HTML content
+		newOffset := rf.extractItemsOffset(afterURLStr)
 		html, err := page.HTML()
 		if err != nil {
 			log.Printf("Failed to get HTML for page %d: %v\n", pageCount+1, err)
 			break
 		}
 
-		// Check if we got the same content (compare HTML to detect duplicates)
-		isDuplicate := false
-		if len(htmlPages) > 0 {
-			// Compare with last page - if HTML is identical, it's a duplicate
-			if html == htmlPages[len(htmlPages)-1] {
-				log.Printf("Warning: Page %d HTML is identical to previous page (offset: %d), skipping duplicate\n",
-					pageCount+1, newOffset)
-				isDuplicate = true
+		// Blocked mid-pagination: keep the pages fetched so far
+		if !hasListingLinks(html) {
+			if err := checkBlocked(html); err != nil {
+				log.Printf("Stopping pagination at page %d: %v\n", pageCount+1, err)
+				break
 			}
 		}
 
-		if !isDuplicate {
-			htmlPages = append(htmlPages, html)
-			pageCount++
-			log.Printf("Fetched page %d/%d (HTML size: %d bytes, offset: %d)\n",
-				pageCount, maxPages, len(html), newOffset)
-		} else {
-			// If we got a duplicate, stop pagination
-			log.Printf("Stopping pagination due to duplicate content\n")
+		// Validate that we actually moved to a new page
+		if stop := tracker.advance(newOffset, html, usedOffsetFallback); stop != "" {
+			log.Printf("Stopping pagination at page %d: %s\n", pageCount+1, stop)
 			break
 		}
-	}
-
-	log.Printf("Fetching completed. Total pages fetched: %d (requested: %d)\n", len(htmlPages), maxPages)
 
-	if len(htmlPages) == 0 {
-		log.Println("Warning: No HTML pages collected.")
+		pageCount++
+		pageReceived = time.Now()
+		if rf.pageProgress != nil {
+			rf.pageProgress(pageCount, maxPages)
+		}
+		if err := onPage(pageCount, html); err != nil {
+			return err
+		}
+		log.Printf("Fetched page %d/%d (HTML size: %d bytes, offset: %d)\n",
+			pageCount, maxPages, len(html), newOffset)
 	}
 
-	return htmlPages, nil
+	log.Printf("Fetching completed. Total pages fetched: %d (requested: %d)\n", pageCount, maxPages)
+
+	return nil
 }
@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNextItemsOffsetURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantOK     bool
+		wantOffset string
+	}{
+		{
+			name:       "default page size",
+			input:      "https://www.airbnb.com/s/Bangkok/homes?items_offset=18&currency=USD",
+			wantOK:     true,
+			wantOffset: "36",
+		},
+		{
+			name:       "items_per_grid sets page size",
+			input:      "https://www.airbnb.com/s/Bangkok/homes?items_offset=0&items_per_grid=20",
+			wantOK:     true,
+			wantOffset: "20",
+		},
+		{
+			name:       "page_cursor is dropped",
+			input:      "https://www.airbnb.com/s/Bangkok/homes?items_offset=36&page_cursor=abc",
+			wantOK:     true,
+			wantOffset: "54",
+		},
+		{
+			name:   "no items_offset",
+			input:  "https://www.airbnb.com/s/Bangkok/homes?currency=USD",
+			wantOK: false,
+		},
+		{
+			name:   "invalid items_offset",
+			input:  "https://www.airbnb.com/s/Bangkok/homes?items_offset=abc",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextItemsOffsetURL(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("nextItemsOffsetURL(%q) ok = %v, want %v", tt.input, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			parsed, err := url.Parse(got)
+			if err != nil {
+				t.Fatalf("invalid URL %q: %v", got, err)
+			}
+			q := parsed.Query()
+			if q.Get("items_offset") != tt.wantOffset {
+				t.Errorf("items_offset = %q, want %q", q.Get("items_offset"), tt.wantOffset)
+			}
+			if q.Has("page_cursor") {
+				t.Errorf("page_cursor should be removed, got %q", got)
+			}
+			if parsed.Path != "/s/Bangkok/homes" {
+				t.Errorf("path changed: %q", parsed.Path)
+			}
+		})
+	}
+}
+
+func TestPageTrackerWithoutOffset(t *testing.T) {
+	// Date-less searches have no items_offset (-1), so only page content tells pages apart
+	pageA := `<a href="/rooms/1">A</a>`
+	pageB := `<a href="/rooms/2">B</a>`
+	pageC := `<a href="/rooms/3">C</a>`
+
+	tests := []struct {
+		name     string
+		pages    []string // pages reached by following the pager, after pageA
+		wantKept int      // pages accepted before pagination stops
+	}{
+		{"advances", []string{pageB, pageC}, 2},
+		{"same page again", []string{pageA, pageB}, 0},
+		{"cycles back to an earlier page", []string{pageB, pageA, pageC}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newPageTracker(-1, pageA)
+			kept := 0
+			for _, html := range tt.pages {
+				if stop := tracker.advance(-1, html, false); stop != "" {
+					break
+				}
+				kept++
+			}
+			if kept != tt.wantKept {
+				t.Errorf("kept %d pages, want %d", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestPageTrackerOffsetFallback(t *testing.T) {
+	tracker := newPageTracker(18, `<a href="/rooms/1">A</a>`)
+
+	// The synthesized next URL was redirected back to the same offset
+	if stop := tracker.advance(18, `<a href="/rooms/2">B</a>`, true); stop == "" {
+		t.Error("advance() accepted a fallback page that did not move past the previous offset")
+	}
+	if stop := tracker.advance(36, `<a href="/rooms/2">B</a>`, true); stop != "" {
+		t.Errorf("advance() stopped on an advancing fallback page: %s", stop)
+	}
+}
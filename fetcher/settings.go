@@ -0,0 +1,43 @@
+package fetcher
+
+import (
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Settings are the fetcher options taken from the environment: page waits, the emulated
+// screen, the browser identity, cookie persistence and the proxy. They are shared by
+// every fetcher and browser; until Configure is called the built-in defaults apply.
+type Settings struct {
+	Timings   Timings
+	Viewport  Viewport
+	Identity  *proto.NetworkSetUserAgentOverride // nil for the default of the viewport's device
+	CookieJar *CookieJar                         // nil disables cookie persistence
+	Proxy     Proxy                              // zero for a direct connection
+}
+
+// SettingsFromEnv reads the fetcher settings from the environment variables documented
+// on Timings, Viewport, DefaultUserAgent, CookieJar and Proxy, logging the ones that are set
+func SettingsFromEnv() Settings {
+	v := viewportFromEnv()
+	return Settings{
+		Timings:   loadTimings(),
+		Viewport:  v,
+		Identity:  loadIdentity(v.Mobile),
+		CookieJar: cookieJarFromEnv(),
+		Proxy:     proxyFromEnv(),
+	}
+}
+
+// Configure replaces the fetcher settings. Call it once at startup, before the first
+// fetcher is created: fetchers read the settings without locking.
+func Configure(s Settings) {
+	timings = s.Timings
+	viewport = s.Viewport
+	identity = s.Identity
+	if identity == nil {
+		identity = defaultIdentity(viewport.Mobile)
+	}
+	cookieJar = s.CookieJar
+	browserProxy = s.Proxy
+	navigations = NewLimiter(s.Timings.NavigationInterval)
+}
@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"log"
+	"os"
+)
+
+// Strategy selects how search pages are fetched, from the FETCHER environment variable:
+//
+//	rod    headless browser (default); handles JavaScript-rendered pages
+//	colly  plain HTTP requests; much faster and lighter, but only works when the
+//	       search HTML is server-rendered
+//	auto   colly first, falling back to rod when colly's pages have no listings
+//
+// Detail pages always need the browser, so it is launched with every strategy.
+type Strategy string
+
+const (
+	StrategyRod   Strategy = "rod"
+	StrategyColly Strategy = "colly"
+	StrategyAuto  Strategy = "auto"
+)
+
+// StrategyFromEnv reads the strategy from FETCHER, falling back to rod when it is unset or unknown
+func StrategyFromEnv() Strategy {
+	switch value := Strategy(os.Getenv("FETCHER")); value {
+	case "":
+		return StrategyRod
+	case StrategyRod, StrategyColly, StrategyAuto:
+		log.Printf("Using FETCHER=%s\n", value)
+		return value
+	default:
+		log.Printf("Warning: Unknown FETCHER=%q (expected rod, colly or auto), using rod\n", value)
+		return StrategyRod
+	}
+}
+
+// NewSearchFetcher returns the search page fetcher for the strategy. rodFetcher is used
+// for rod and as the auto fallback; hasListings reports whether a page parses to at
+// least one listing and decides when auto falls back.
+func NewSearchFetcher(strategy Strategy, rodFetcher *RodFetcher, hasListings func(html string) bool) Fetcher {
+	switch strategy {
+	case StrategyColly:
+		return NewCollyFetcher()
+	case StrategyAuto:
+		return NewFallbackFetcher(NewCollyFetcher(), rodFetcher, hasListings)
+	default:
+		return rodFetcher
+	}
+}
+
+// FallbackFetcher fetches with a primary fetcher and retries the URL with a fallback
+// fetcher when the primary fails or none of its pages is usable
+type FallbackFetcher struct {
+	primary  Fetcher
+	fallback Fetcher
+	usable   func(html string) bool
+}
+
+// NewFallbackFetcher creates a FallbackFetcher; usable reports whether a primary page is good enough
+func NewFallbackFetcher(primary, fallback Fetcher, usable func(html string) bool) *FallbackFetcher {
+	return &FallbackFetcher{
+		primary:  primary,
+		fallback: fallback,
+		usable:   usable,
+	}
+}
+
+// Fetch implements the Fetcher interface
+func (ff *FallbackFetcher) Fetch(url string, maxPages int) ([]string, error) {
+	var htmlPages []string
+	err := ff.FetchStream(url, maxPages, func(pageNum int, html string) error {
+		htmlPages = append(htmlPages, html)
+		return nil
+	})
+	return htmlPages, err
+}
+
+// SetPageProgress implements the ProgressReporter interface. Pages are reported by
+// whichever fetcher fetches them, so a fallback starts counting again from page 1.
+func (ff *FallbackFetcher) SetPageProgress(progress PageProgress) {
+	SetPageProgress(ff.primary, progress)
+	SetPageProgress(ff.fallback, progress)
+}
+
+// FetchStream implements the PageStreamer interface. The primary pages are only handed
+// to onPage once one of them is known to be usable, so onPage never sees pages from both
+// fetchers.
+func (ff *FallbackFetcher) FetchStream(url string, maxPages int, onPage func(pageNum int, html string) error) error {
+	htmlPages, err := ff.primary.Fetch(url, maxPages)
+	switch {
+	case err != nil:
+		log.Printf("Primary fetcher failed, falling back: %v\n", err)
+	case !ff.anyUsable(htmlPages):
+		log.Printf("Primary fetcher returned %d pages without listings, falling back\n", len(htmlPages))
+	default:
+		for i := range htmlPages {
+			if err := onPage(i+1, htmlPages[i]); err != nil {
+				return err
+			}
+			htmlPages[i] = "" // release HTML
+		}
+		return nil
+	}
+	return FetchEach(ff.fallback, url, maxPages, onPage)
+}
+
+// anyUsable reports whether at least one of the pages is usable
+func (ff *FallbackFetcher) anyUsable(htmlPages []string) bool {
+	for _, html := range htmlPages {
+		if ff.usable(html) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Timings holds the waits used while loading pages. The defaults are tuned for a fast
+// connection; on slow hosts pages may not be rendered yet and extraction yields zeros,
+// so every value can be overridden with an environment variable holding a Go duration
+// (e.g. PAGE_LOAD_WAIT=6s):
+//
+//	PAGE_LOAD_WAIT         3s     render wait after the first search page loads
+//	NEXT_PAGE_LOAD_WAIT    5s     render wait after navigating to the next search page
+//	PAGE_SETTLE_WAIT       3s     extra wait after a next search page stabilizes
+//	PAGE_INTERVAL          7s     delay between search page requests
+//	STABLE_TIMEOUT         10s    WaitStable timeout for the first search page
+//	NEXT_STABLE_TIMEOUT    15s    WaitStable timeout for subsequent search pages
+//	STABLE_WINDOW          500ms  DOM quiet period WaitStable waits for on search pages
+//	DETAIL_LOAD_WAIT       2s     render wait after a detail page loads
+//	DETAIL_STABLE_TIMEOUT  5s     WaitStable timeout for detail pages
+//	DETAIL_STABLE_WINDOW   300ms  DOM quiet period WaitStable waits for on detail pages
+//	DETAIL_TIMEOUT         30s    hard limit for loading one detail page (0 disables it)
+//	NAVIGATION_INTERVAL    1s     minimum spacing between any two page loads, across all
+//	                              browsers and requests (0 disables it)
+type Timings struct {
+	PageLoadWait        time.Duration
+	NextPageLoadWait    time.Duration
+	PageSettleWait      time.Duration
+	PageInterval        time.Duration
+	StableTimeout       time.Duration
+	NextStableTimeout   time.Duration
+	StableWindow        time.Duration
+	DetailLoadWait      time.Duration
+	DetailStableTimeout time.Duration
+	DetailStableWindow  time.Duration
+	DetailTimeout       time.Duration
+	NavigationInterval  time.Duration
+}
+
+// DefaultTimings returns the built-in waits
+func DefaultTimings() Timings {
+	return Timings{
+		PageLoadWait:        3 * time.Second,
+		NextPageLoadWait:    5 * time.Second,
+		PageSettleWait:      3 * time.Second,
+		PageInterval:        7 * time.Second,
+		StableTimeout:       10 * time.Second,
+		NextStableTimeout:   15 * time.Second,
+		StableWindow:        500 * time.Millisecond,
+		DetailLoadWait:      2 * time.Second,
+		DetailStableTimeout: 5 * time.Second,
+		DetailStableWindow:  300 * time.Millisecond,
+		DetailTimeout:       30 * time.Second,
+		NavigationInterval:  time.Second,
+	}
+}
+
+// timings are shared by all fetchers, set with Configure at startup
+var timings = DefaultTimings()
+
+// loadTimings applies environment overrides to the default timings
+func loadTimings() Timings {
+	t := DefaultTimings()
+	t.PageLoadWait = durationFromEnv("PAGE_LOAD_WAIT", t.PageLoadWait)
+	t.NextPageLoadWait = durationFromEnv("NEXT_PAGE_LOAD_WAIT", t.NextPageLoadWait)
+	t.PageSettleWait = durationFromEnv("PAGE_SETTLE_WAIT", t.PageSettleWait)
+	t.PageInterval = durationFromEnv("PAGE_INTERVAL", t.PageInterval)
+	t.StableTimeout = durationFromEnv("STABLE_TIMEOUT", t.StableTimeout)
+	t.NextStableTimeout = durationFromEnv("NEXT_STABLE_TIMEOUT", t.NextStableTimeout)
+	t.StableWindow = durationFromEnv("STABLE_WINDOW", t.StableWindow)
+	t.DetailLoadWait = durationFromEnv("DETAIL_LOAD_WAIT", t.DetailLoadWait)
+	t.DetailStableTimeout = durationFromEnv("DETAIL_STABLE_TIMEOUT", t.DetailStableTimeout)
+	t.DetailStableWindow = durationFromEnv("DETAIL_STABLE_WINDOW", t.DetailStableWindow)
+	t.DetailTimeout = durationFromEnv("DETAIL_TIMEOUT", t.DetailTimeout)
+	t.NavigationInterval = durationFromEnv("NAVIGATION_INTERVAL", t.NavigationInterval)
+	return t
+}
+
+// durationFromEnv parses a duration from the named environment variable,
+// falling back to def when it is unset or invalid
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Warning: Invalid %s=%q (expected a duration like 3s), using default %s\n", key, value, def)
+		return def
+	}
+	log.Printf("Using %s=%s\n", key, d)
+	return d
+}
@@ -0,0 +1,108 @@
+package fetcher
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod/lib/devices"
+)
+
+// Viewport is the screen every page is rendered for. The site serves a different layout
+// to phones, which may be lighter and more stable to parse than the desktop one, so it is
+// configurable with environment variables:
+//
+//	DEVICE    desktop (default) or mobile; mobile emulates a touch phone with a mobile
+//	          user agent unless USER_AGENT is set
+//	VIEWPORT  WIDTHxHEIGHT in CSS pixels (default 1280x800 on desktop, 390x844 on mobile)
+type Viewport struct {
+	Width  int
+	Height int
+	Mobile bool
+}
+
+// Default viewports per device
+var (
+	DefaultDesktopViewport = Viewport{Width: 1280, Height: 800}
+	DefaultMobileViewport  = Viewport{Width: 390, Height: 844, Mobile: true}
+)
+
+// DefaultMobileUserAgent is sent instead of DefaultUserAgent when DEVICE=mobile
+const DefaultMobileUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+
+// viewport is shared by all browsers, set with Configure at startup
+var viewport = DefaultDesktopViewport
+
+// viewportFromEnv reads DEVICE and VIEWPORT, falling back to the desktop defaults when
+// they are unset or invalid
+func viewportFromEnv() Viewport {
+	v := DefaultDesktopViewport
+	switch value := strings.ToLower(os.Getenv("DEVICE")); value {
+	case "", "desktop":
+	case "mobile":
+		log.Printf("Using DEVICE=%s\n", value)
+		v = DefaultMobileViewport
+	default:
+		log.Printf("Warning: Unknown DEVICE=%q (expected desktop or mobile), using desktop\n", value)
+	}
+
+	if value := os.Getenv("VIEWPORT"); value != "" {
+		width, height, err := ParseViewportSize(value)
+		if err != nil {
+			log.Printf("Warning: Invalid VIEWPORT=%q (%v), using %dx%d\n", value, err, v.Width, v.Height)
+		} else {
+			log.Printf("Using VIEWPORT=%dx%d\n", width, height)
+			v.Width, v.Height = width, height
+		}
+	}
+	return v
+}
+
+// ParseViewportSize parses a WIDTHxHEIGHT size such as "1280x800"
+func ParseViewportSize(text string) (int, int, error) {
+	widthStr, heightStr, ok := strings.Cut(strings.ToLower(strings.TrimSpace(text)), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected WIDTHxHEIGHT")
+	}
+	width, err := strconv.Atoi(widthStr)
+	if err != nil || width < 200 || width > 10000 {
+		return 0, 0, fmt.Errorf("width must be between 200 and 10000 pixels")
+	}
+	height, err := strconv.Atoi(heightStr)
+	if err != nil || height < 200 || height > 10000 {
+		return 0, 0, fmt.Errorf("height must be between 200 and 10000 pixels")
+	}
+	return width, height, nil
+}
+
+// device returns the rod device emulated by every new page. The user agent is replaced
+// by applyIdentity afterwards; it is set here too so both agree.
+func (v Viewport) device() devices.Device {
+	if !v.Mobile {
+		// Landscape, like rod's default laptop device
+		return devices.Device{
+			Title:          "Desktop",
+			Capabilities:   []string{},
+			UserAgent:      identity.UserAgent,
+			AcceptLanguage: identity.AcceptLanguage,
+			Screen: devices.Screen{
+				DevicePixelRatio: 1,
+				Horizontal:       devices.ScreenSize{Width: v.Width, Height: v.Height},
+				Vertical:         devices.ScreenSize{Width: v.Height, Height: v.Width},
+			},
+		}.Landscape()
+	}
+	return devices.Device{
+		Title:          "Mobile",
+		Capabilities:   []string{"touch", "mobile"},
+		UserAgent:      identity.UserAgent,
+		AcceptLanguage: identity.AcceptLanguage,
+		Screen: devices.Screen{
+			DevicePixelRatio: 2,
+			Horizontal:       devices.ScreenSize{Width: v.Height, Height: v.Width},
+			Vertical:         devices.ScreenSize{Width: v.Width, Height: v.Height},
+		},
+	}
+}
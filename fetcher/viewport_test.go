@@ -0,0 +1,36 @@
+package fetcher
+
+import "testing"
+
+func TestParseViewportSize(t *testing.T) {
+	tests := []struct {
+		input         string
+		width, height int
+		wantErr       bool
+	}{
+		{"1280x800", 1280, 800, false},
+		{" 390X844 ", 390, 844, false},
+		{"1280", 0, 0, true},
+		{"1280x", 0, 0, true},
+		{"100x800", 0, 0, true},
+		{"wide x tall", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		width, height, err := ParseViewportSize(tt.input)
+		if width != tt.width || height != tt.height || (err != nil) != tt.wantErr {
+			t.Errorf("ParseViewportSize(%q) = (%d, %d, %v), want (%d, %d, error %v)", tt.input, width, height, err, tt.width, tt.height, tt.wantErr)
+		}
+	}
+}
+
+func TestViewportDevice(t *testing.T) {
+	desktop := DefaultDesktopViewport.device().MetricsEmulation()
+	if desktop.Width != 1280 || desktop.Height != 800 || desktop.Mobile {
+		t.Errorf("desktop metrics = %dx%d mobile=%v, want 1280x800 desktop", desktop.Width, desktop.Height, desktop.Mobile)
+	}
+	mobile := DefaultMobileViewport.device().MetricsEmulation()
+	if mobile.Width != 390 || mobile.Height != 844 || !mobile.Mobile {
+		t.Errorf("mobile metrics = %dx%d mobile=%v, want 390x844 mobile", mobile.Width, mobile.Height, mobile.Mobile)
+	}
+}
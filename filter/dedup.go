@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"fmt"
+
+	"bnb-fetcher/searchurl"
+)
+
+// Dedup scopes decide when a listing found again counts as a duplicate and is dropped.
+//
+// With DedupRequest each listing appears once per request, in the first link that found
+// it; this keeps the sheet free of repeats but leaves a price band's section incomplete
+// when a listing near a band boundary shows up in two bands. DedupLink only drops repeats
+// within one link (Bnb repeats listings across result pages), so every band is complete
+// at the cost of listings appearing, and being enriched, more than once. DedupNone keeps
+// every parsed listing.
+const (
+	DedupRequest = "request"
+	DedupLink    = "link"
+	DedupNone    = "none"
+)
+
+// DedupScopeOption describes one dedup scope
+type DedupScopeOption struct {
+	Key   string // value stored in user config
+	Label string // shown in the Telegram menu
+}
+
+// DedupScopeOptions lists the supported dedup scopes in menu order
+var DedupScopeOptions = []DedupScopeOption{
+	{Key: DedupRequest, Label: "Per request (each listing once)"},
+	{Key: DedupLink, Label: "Per link (each link complete)"},
+	{Key: DedupNone, Label: "None (keep repeats)"},
+}
+
+// LookupDedupScope returns the dedup scope option with the given key
+func LookupDedupScope(key string) (DedupScopeOption, bool) {
+	for _, option := range DedupScopeOptions {
+		if option.Key == key {
+			return option, true
+		}
+	}
+	return DedupScopeOption{}, false
+}
+
+// Deduper remembers which listings were already seen in a request
+type Deduper struct {
+	scope    string
+	seen     map[string]int  // dedup key -> link number that first found the listing
+	excluded map[string]bool // dedup keys of listings reported before this request, see Exclude
+}
+
+// NewDeduper creates a Deduper for the given scope. Unknown scopes fall back to DedupRequest.
+func NewDeduper(scope string) *Deduper {
+	if _, ok := LookupDedupScope(scope); !ok {
+		scope = DedupRequest
+	}
+	return &Deduper{scope: scope, seen: make(map[string]int), excluded: make(map[string]bool)}
+}
+
+// Exclude marks the listing at url as reported by an earlier request: Add returns it as
+// a duplicate (first found by link 0) whatever the scope. Listings are matched by room
+// ID, since the query of a listing link differs between searches.
+func (d *Deduper) Exclude(url string) {
+	d.excluded[searchurl.ListingKey(url)] = true
+}
+
+// Add records that link linkNumber found the listing at url. It returns false if the
+// listing is a duplicate within the scope, together with the link that first found it.
+// The URL itself is left alone: callers keep it for display and linking.
+func (d *Deduper) Add(url string, linkNumber int) (isNew bool, firstLink int) {
+	key := searchurl.ListingKey(url)
+	if d.excluded[key] {
+		return false, 0
+	}
+	if d.scope == DedupNone {
+		return true, linkNumber
+	}
+
+	if d.scope == DedupLink {
+		key = fmt.Sprintf("%d|%s", linkNumber, key)
+	}
+	if first, seen := d.seen[key]; seen {
+		return false, first
+	}
+	d.seen[key] = linkNumber
+	return true, linkNumber
+}
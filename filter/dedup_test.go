@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestDeduperScopes(t *testing.T) {
+	// The same listing found twice on link 1 (repeated across result pages) and again on
+	// link 2 (a neighbouring price band); the URLs differ only in query parameter order.
+	type found struct {
+		url        string
+		linkNumber int
+	}
+	finds := []found{
+		{"https://www.airbnb.com/rooms/1?adults=2&check_in=2026-01-01", 1},
+		{"https://www.airbnb.com/rooms/1?check_in=2026-01-01&adults=2", 1},
+		{"https://www.airbnb.com/rooms/1?adults=2&check_in=2026-01-01", 2},
+		{"https://www.airbnb.com/rooms/2", 2},
+	}
+
+	tests := []struct {
+		scope string
+		want  []bool // isNew for each find
+	}{
+		{DedupRequest, []bool{true, false, false, true}},
+		{DedupLink, []bool{true, false, true, true}},
+		{DedupNone, []bool{true, true, true, true}},
+		{"unknown", []bool{true, false, false, true}}, // falls back to per request
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			deduper := NewDeduper(tt.scope)
+			for i, f := range finds {
+				if isNew, _ := deduper.Add(f.url, f.linkNumber); isNew != tt.want[i] {
+					t.Errorf("find %d (link %d): isNew = %v, want %v", i, f.linkNumber, isNew, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeduperMatchesRoomID(t *testing.T) {
+	deduper := NewDeduper(DedupRequest)
+	deduper.Add("https://www.airbnb.com/rooms/1?check_in=2026-01-01&source_impression_id=p3_1", 1)
+
+	// Same room from another link: other dates, search IDs and domain
+	if isNew, firstLink := deduper.Add("https://www.airbnb.de/rooms/1/?check_in=2026-01-08&search_id=abc", 2); isNew || firstLink != 1 {
+		t.Errorf("Add() of the same room = (%v, %d), want (false, 1)", isNew, firstLink)
+	}
+	if isNew, _ := deduper.Add("https://www.airbnb.com/rooms/10?check_in=2026-01-01", 2); !isNew {
+		t.Error("Add() of another room returned a duplicate")
+	}
+}
+
+func TestDeduperReportsFirstLink(t *testing.T) {
+	deduper := NewDeduper(DedupRequest)
+	deduper.Add("https://www.airbnb.com/rooms/1", 3)
+
+	isNew, firstLink := deduper.Add("https://www.airbnb.com/rooms/1", 5)
+	if isNew || firstLink != 3 {
+		t.Errorf("Add() = (%v, %d), want (false, 3)", isNew, firstLink)
+	}
+}
+
+func TestDeduperExclude(t *testing.T) {
+	for _, scope := range []string{DedupRequest, DedupLink, DedupNone} {
+		deduper := NewDeduper(scope)
+		deduper.Exclude("https://www.airbnb.com/rooms/1?adults=2&check_in=2026-01-01")
+
+		if isNew, firstLink := deduper.Add("https://www.airbnb.com/rooms/1?check_in=2026-01-01&adults=2", 1); isNew || firstLink != 0 {
+			t.Errorf("%s: Add() of an excluded listing = (%v, %d), want (false, 0)", scope, isNew, firstLink)
+		}
+		if isNew, _ := deduper.Add("https://www.airbnb.com/rooms/2", 1); !isNew {
+			t.Errorf("%s: Add() of a new listing returned a duplicate", scope)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package filter
 
 import (
+	"strings"
+
 	"bnb-fetcher/config"
 	"bnb-fetcher/models"
 )
@@ -47,6 +49,63 @@ func (f *Filter) matchesFilters(listing models.Listing) bool {
 
 	// Star rating filter removed per user request
 
+	// Check discount badge
+	if f.cfg.Filters.DiscountedOnly && listing.DiscountPercent <= 0 {
+		return false
+	}
+
+	// Check location - only filter if the location was extracted from the card
+	if !f.matchesLocation(listing) {
+		return false
+	}
+
+	return true
+}
+
+// matchesLocation reports whether the listing's location contains the configured text.
+// Listings without a location pass.
+func (f *Filter) matchesLocation(listing models.Listing) bool {
+	if f.cfg.Filters.LocationContains == "" || listing.Location == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(listing.Location), strings.ToLower(f.cfg.Filters.LocationContains))
+}
+
+// ApplyDetailFilters applies the filters that need detail page data. Listings that were
+// not enriched are kept, since their detail values are unknown (same policy as a missing
+// price). Returns the kept listings and the ones filtered out.
+func (f *Filter) ApplyDetailFilters(listings []models.Listing) (kept, dropped []models.Listing) {
+	for _, listing := range listings {
+		if !listing.Enriched || f.matchesDetailFilters(listing) {
+			kept = append(kept, listing)
+		} else {
+			dropped = append(dropped, listing)
+		}
+	}
+	return kept, dropped
+}
+
+// matchesDetailFilters checks an enriched listing against the detail page filters
+func (f *Filter) matchesDetailFilters(listing models.Listing) bool {
+	if f.cfg.Filters.SuperhostOnly && !listing.IsSuperhost {
+		return false
+	}
+	if f.cfg.Filters.GuestFavoriteOnly && !listing.IsGuestFavorite {
+		return false
+	}
+	// MinNights is 0 for "No minimum" or when the page doesn't say, which passes
+	if f.cfg.Filters.MaxMinimumNights > 0 && listing.MinNights > f.cfg.Filters.MaxMinimumNights {
+		return false
+	}
+	// The per-guest price is 0 when the USD price or guest count is unknown, which passes
+	if perGuest := listing.PricePerGuestUSD(); f.cfg.Filters.MaxPricePerGuest > 0 && perGuest > f.cfg.Filters.MaxPricePerGuest {
+		return false
+	}
+	// Cards without a location may have got one from the detail page
+	if !f.matchesLocation(listing) {
+		return false
+	}
+
 	return true
 }
 
@@ -0,0 +1,180 @@
+package filter
+
+import (
+	"testing"
+
+	"bnb-fetcher/config"
+	"bnb-fetcher/models"
+)
+
+func TestApplyDetailFilters(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "both", Enriched: true, IsSuperhost: true, IsGuestFavorite: true},
+		{Title: "superhost", Enriched: true, IsSuperhost: true},
+		{Title: "favorite", Enriched: true, IsGuestFavorite: true},
+		{Title: "neither", Enriched: true},
+		{Title: "not enriched"},
+	}
+
+	tests := []struct {
+		name              string
+		superhostOnly     bool
+		guestFavoriteOnly bool
+		want              []string
+	}{
+		{"no detail filters", false, false, []string{"both", "superhost", "favorite", "neither", "not enriched"}},
+		{"superhost only", true, false, []string{"both", "superhost", "not enriched"}},
+		{"guest favorite only", false, true, []string{"both", "favorite", "not enriched"}},
+		{"both required", true, true, []string{"both", "not enriched"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.FilterConfig{}
+			cfg.Filters.SuperhostOnly = tt.superhostOnly
+			cfg.Filters.GuestFavoriteOnly = tt.guestFavoriteOnly
+
+			kept, dropped := NewFilter(cfg).ApplyDetailFilters(listings)
+			if len(kept)+len(dropped) != len(listings) {
+				t.Fatalf("kept %d + dropped %d != %d listings", len(kept), len(dropped), len(listings))
+			}
+			if len(kept) != len(tt.want) {
+				t.Fatalf("kept %d listings, want %d", len(kept), len(tt.want))
+			}
+			for i, listing := range kept {
+				if listing.Title != tt.want[i] {
+					t.Errorf("kept[%d] = %q, want %q", i, listing.Title, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFiltersLocation(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "old town", Location: "Old Town, Chiang Mai"},
+		{Title: "nimman", Location: "Nimman, Chiang Mai"},
+		{Title: "no location"},
+	}
+
+	tests := []struct {
+		name             string
+		locationContains string
+		want             []string
+	}{
+		{"no location filter", "", []string{"old town", "nimman", "no location"}},
+		{"case-insensitive match", "old town", []string{"old town", "no location"}},
+		{"matches several", "Chiang Mai", []string{"old town", "nimman", "no location"}},
+		{"no match keeps empty location", "Bangkok", []string{"no location"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.FilterConfig{}
+			cfg.Filters.MaxPrice = 2000
+			cfg.Filters.LocationContains = tt.locationContains
+
+			kept := NewFilter(cfg).ApplyFilters(listings)
+			if len(kept) != len(tt.want) {
+				t.Fatalf("kept %d listings, want %d", len(kept), len(tt.want))
+			}
+			for i, listing := range kept {
+				if listing.Title != tt.want[i] {
+					t.Errorf("kept[%d] = %q, want %q", i, listing.Title, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDetailFiltersMinimumNights(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "short stay", Enriched: true, MinNights: 2},
+		{Title: "week minimum", Enriched: true, MinNights: 7},
+		{Title: "no minimum", Enriched: true},
+		{Title: "not enriched"},
+	}
+
+	cfg := &config.FilterConfig{}
+	cfg.Filters.MaxMinimumNights = 3
+
+	kept, dropped := NewFilter(cfg).ApplyDetailFilters(listings)
+	want := []string{"short stay", "no minimum", "not enriched"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d listings, want %d", len(kept), len(want))
+	}
+	for i, listing := range kept {
+		if listing.Title != want[i] {
+			t.Errorf("kept[%d] = %q, want %q", i, listing.Title, want[i])
+		}
+	}
+	if len(dropped) != 1 || dropped[0].Title != "week minimum" {
+		t.Errorf("dropped = %v, want only \"week minimum\"", dropped)
+	}
+}
+
+func TestApplyFiltersDiscountedOnly(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "discounted", DiscountPercent: 20},
+		{Title: "full price"},
+	}
+
+	cfg := &config.FilterConfig{}
+	cfg.Filters.MaxPrice = 2000
+	if got := NewFilter(cfg).ApplyFilters(listings); len(got) != 2 {
+		t.Errorf("without DiscountedOnly kept %d listings, want 2", len(got))
+	}
+
+	cfg.Filters.DiscountedOnly = true
+	got := NewFilter(cfg).ApplyFilters(listings)
+	if len(got) != 1 || got[0].Title != "discounted" {
+		t.Errorf("with DiscountedOnly kept %+v, want only the discounted listing", got)
+	}
+}
+
+func TestApplyDetailFiltersMaxPricePerGuest(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "group house", Enriched: true, PriceUSD: 240, MaxGuests: 8},  // $30 per guest
+		{Title: "couple studio", Enriched: true, PriceUSD: 90, MaxGuests: 2}, // $45 per guest
+		{Title: "at the cap", Enriched: true, PriceUSD: 160, MaxGuests: 4},   // $40 per guest
+		{Title: "no guest count", Enriched: true, PriceUSD: 300},             // unknown, passes
+		{Title: "no USD price", Enriched: true, Price: 5000, MaxGuests: 2},   // unknown, passes
+		{Title: "not enriched", PriceUSD: 500, MaxGuests: 1},                 // not enriched, passes
+	}
+
+	cfg := &config.FilterConfig{}
+	cfg.Filters.MaxPricePerGuest = 40
+
+	kept, dropped := NewFilter(cfg).ApplyDetailFilters(listings)
+	want := []string{"group house", "at the cap", "no guest count", "no USD price", "not enriched"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d listings, want %d", len(kept), len(want))
+	}
+	for i, listing := range kept {
+		if listing.Title != want[i] {
+			t.Errorf("kept[%d] = %q, want %q", i, listing.Title, want[i])
+		}
+	}
+	if len(dropped) != 1 || dropped[0].Title != "couple studio" {
+		t.Errorf("dropped = %v, want only \"couple studio\"", dropped)
+	}
+}
+
+func TestApplyDetailFiltersLocation(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "detail location matches", Enriched: true, Location: "Sukhumvit, Bangkok"},
+		{Title: "detail location differs", Enriched: true, Location: "Silom, Bangkok"},
+		{Title: "still unknown", Enriched: true},
+	}
+
+	cfg := &config.FilterConfig{}
+	cfg.Filters.LocationContains = "sukhumvit"
+
+	kept, dropped := NewFilter(cfg).ApplyDetailFilters(listings)
+	if len(kept) != 2 || kept[0].Title != "detail location matches" || kept[1].Title != "still unknown" {
+		t.Errorf("kept = %v, want the matching and the unknown location", kept)
+	}
+	if len(dropped) != 1 || dropped[0].Title != "detail location differs" {
+		t.Errorf("dropped = %v, want only \"detail location differs\"", dropped)
+	}
+}
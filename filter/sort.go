@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"sort"
+
+	"bnb-fetcher/models"
+)
+
+// SortOption describes one way of ordering listings
+type SortOption struct {
+	Key        string // value stored in user config
+	Label      string // shown in the Telegram menu
+	Column     string // sheet header column to sort by ("" keeps scrape order)
+	Descending bool
+}
+
+// SortNone keeps listings in scrape order (by link, then page)
+const SortNone = "none"
+
+// SortOptions lists the supported sort orders in menu order
+var SortOptions = []SortOption{
+	{Key: SortNone, Label: "Scrape order"},
+	{Key: "price_asc", Label: "Price (cheapest first)", Column: "Price"},
+	{Key: "rating_desc", Label: "Rating (highest first)", Column: "Rating", Descending: true},
+	{Key: "reviews_desc", Label: "Reviews (most first)", Column: "Review Count", Descending: true},
+	{Key: "activity_desc", Label: "Activity (most active first)", Column: "Activity Score", Descending: true},
+	{Key: "price_per_guest_asc", Label: "Price per guest (cheapest first)", Column: "Price per Guest (USD)"},
+	{Key: "price_per_bedroom_asc", Label: "Price per bedroom (cheapest first)", Column: "Price per Bedroom (USD)"},
+}
+
+// LookupSortOption returns the sort option with the given key
+func LookupSortOption(key string) (SortOption, bool) {
+	for _, option := range SortOptions {
+		if option.Key == key {
+			return option, true
+		}
+	}
+	return SortOption{}, false
+}
+
+// SortListings orders listings in place according to the sort option key.
+// Listings with an unknown (zero) value for the sort field go last; ties keep scrape order.
+func SortListings(listings []models.Listing, key string) {
+	var value func(models.Listing) float64
+	switch key {
+	case "price_asc":
+		value = func(l models.Listing) float64 { return l.Price }
+	case "rating_desc":
+		value = func(l models.Listing) float64 { return l.Stars }
+	case "reviews_desc":
+		value = func(l models.Listing) float64 { return float64(l.ReviewCount) }
+	case "activity_desc":
+		value = func(l models.Listing) float64 { return l.ActivityScore }
+	case "price_per_guest_asc":
+		value = func(l models.Listing) float64 { return l.PricePerGuestUSD() }
+	case "price_per_bedroom_asc":
+		value = func(l models.Listing) float64 { return l.PricePerBedroomUSD() }
+	default:
+		return
+	}
+	option, _ := LookupSortOption(key)
+
+	sort.SliceStable(listings, func(i, j int) bool {
+		a, b := value(listings[i]), value(listings[j])
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		if option.Descending {
+			return a > b
+		}
+		return a < b
+	})
+}
@@ -0,0 +1,44 @@
+package filter
+
+import (
+	"testing"
+
+	"bnb-fetcher/models"
+)
+
+func TestSortListingsPerBedroom(t *testing.T) {
+	listings := []models.Listing{
+		{Title: "studio", PriceUSD: 80},                             // no bedrooms: unknown value, goes last
+		{Title: "3br", PriceUSD: 240, Bedrooms: 3},                  // $80 per bedroom
+		{Title: "1br", PriceUSD: 100, Bedrooms: 1},                  // $100 per bedroom
+		{Title: "2br", Price: 4000, PriceUSD: 120, Bedrooms: 2},     // $60 per bedroom, whatever the local price
+		{Title: "no rate", Price: 50, Currency: "XYZ", Bedrooms: 2}, // no USD price: unknown value, goes last
+	}
+
+	SortListings(listings, "price_per_bedroom_asc")
+
+	want := []string{"2br", "3br", "1br", "studio", "no rate"}
+	for i, title := range want {
+		if listings[i].Title != title {
+			t.Errorf("position %d = %q, want %q", i, listings[i].Title, title)
+		}
+	}
+}
+
+func TestPricePerBedroomUSD(t *testing.T) {
+	tests := []struct {
+		listing models.Listing
+		want    float64
+	}{
+		{models.Listing{PriceUSD: 300, Bedrooms: 3}, 100},
+		{models.Listing{PriceUSD: 150, Bedrooms: 1.5}, 100},
+		{models.Listing{PriceUSD: 90}, 0},
+		{models.Listing{Price: 300, Bedrooms: 2}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.listing.PricePerBedroomUSD(); got != tt.want {
+			t.Errorf("PricePerBedroomUSD(price %v, bedrooms %v) = %v, want %v", tt.listing.PriceUSD, tt.listing.Bedrooms, got, tt.want)
+		}
+	}
+}
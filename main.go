@@ -1,8 +1,12 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html"
 	"log"
 	"net/url"
 	"os"
@@ -10,15 +14,19 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"bnb-fetcher/config"
+	"bnb-fetcher/currency"
 	"bnb-fetcher/db"
 	"bnb-fetcher/fetcher"
 	"bnb-fetcher/filter"
 	"bnb-fetcher/models"
 	"bnb-fetcher/parser"
 	"bnb-fetcher/pricerange"
+	"bnb-fetcher/sanitize"
 	"bnb-fetcher/scheduler"
+	"bnb-fetcher/searchurl"
 	"bnb-fetcher/sheets"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -33,6 +41,10 @@ func main() {
 	credentialsPath := flag.String("credentials", "", "Path to Google service account credentials JSON file (or use GOOGLE_SHEETS_CREDENTIALS env var)")
 	flag.Parse()
 
+	// Refresh environment variables (Windows-specific), then read the settings from them
+	refreshEnvVars()
+	loadSettings()
+
 	// If URL is provided, run in CLI mode
 	if *url != "" {
 		runCLIMode(*url, *configPath, *maxPages, *spreadsheetURL, *credentialsPath)
@@ -43,6 +55,23 @@ func main() {
 	runTelegramBot(*configPath, *maxPages, *spreadsheetURL, *credentialsPath)
 }
 
+// sheetSettings are the sheet options read by loadSettings, for every sheets.Writer
+var sheetSettings sheets.Settings
+
+// loadSettings reads the package-wide settings from environment variables and hands
+// them over, once at startup before anything is fetched, parsed or written. Until then
+// those packages use their built-in defaults. Options of a single object are still read
+// where it is made: NewScheduler and db.NewDB read theirs when called, as do the USD
+// rate converter and the fetch strategy used by CLI mode.
+func loadSettings() {
+	fetcher.Configure(fetcher.SettingsFromEnv())
+	currency.Configure(currency.SettingsFromEnv())
+	searchurl.Configure(searchurl.SettingsFromEnv())
+	sanitize.Configure(sanitize.SettingsFromEnv())
+	scheduler.Configure(scheduler.SettingsFromEnv())
+	sheetSettings = sheets.SettingsFromEnv()
+}
+
 // runCLIMode runs the fetcher in CLI mode
 func runCLIMode(urlStr, configPath string, maxPages int, spreadsheetURL, credentialsPath string) {
 	// Add currency=USD to URL
@@ -78,19 +107,27 @@ func runCLIMode(urlStr, configPath string, maxPages int, spreadsheetURL, credent
 		return
 	}
 
-	writer, err := sheets.NewWriter(spreadsheetID, credentialsPath)
+	writer, err := sheets.NewWriter(spreadsheetID, credentialsPath, sheetSettings)
 	if err != nil {
 		log.Printf("Warning: Failed to initialize Google Sheets writer: %v\n", err)
 		return
 	}
 
+	if err := writer.VerifyAccess(); err != nil {
+		log.Printf("Warning: %v\n", err)
+		return
+	}
+
 	// Format filter information for CLI mode
 	filterInfo := fmt.Sprintf("Min Reviews: %d, Min Price: %.2f, Max Price: %.2f, Min Stars: %.2f",
 		cfg.Filters.MinReviews, cfg.Filters.MinPrice, cfg.Filters.MaxPrice, cfg.Filters.MinStars)
 
+	// Add a comparable USD price next to the raw price
+	currency.NewConverterFromEnv().ApplyUSDPrices(filteredListings)
+
 	// Create a temporary sheet name for CLI mode
 	sheetName := fmt.Sprintf("CLI_%s", time.Now().Format("20060102_150405"))
-	
+
 	// Use CreateSheetAndWriteListings to insert at the beginning
 	// CLI mode doesn't have unfiltered listings, so pass empty slice
 	_, _, err = writer.CreateSheetAndWriteListings(sheetName, filteredListings, []models.Listing{}, urlStr, filterInfo)
@@ -101,6 +138,9 @@ func runCLIMode(urlStr, configPath string, maxPages int, spreadsheetURL, credent
 	}
 }
 
+// adminUserID receives service notifications and may run maintenance commands
+const adminUserID int64 = 420478432
+
 // Allowed user IDs
 var allowedUserIDs = map[int64]bool{
 	420478432: true,
@@ -110,8 +150,32 @@ var allowedUserIDs = map[int64]bool{
 // pendingConfigInput tracks which config type a user is currently entering a value for
 var pendingConfigInput = make(map[int64]string)
 
+// pendingSearch is a price-split search waiting for the user to confirm it
+type pendingSearch struct {
+	text  string // the submitted URLs
+	quick bool
+}
+
+// pendingSearches tracks the search each user was asked to confirm
+var pendingSearches = make(map[int64]pendingSearch)
+
+// defaultTimePerPage estimates how long a search page takes to fetch and enrich before
+// any request has finished
+const defaultTimePerPage = 30 * time.Second
+
+// mainKeyboard returns the persistent keyboard shown under the chat
+func mainKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	keyboard := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⚙️ Config"),
+		),
+	)
+	keyboard.ResizeKeyboard = true
+	return keyboard
+}
+
 // handleCallbackQuery handles callback queries from inline keyboard buttons
-func handleCallbackQuery(bot *tgbotapi.BotAPI, database *db.DB, callback *tgbotapi.CallbackQuery) {
+func handleCallbackQuery(bot *tgbotapi.BotAPI, database *db.DB, writer *sheets.Writer, spreadsheetURL string, callback *tgbotapi.CallbackQuery) {
 	userID := callback.From.ID
 	chatID := callback.Message.Chat.ID
 	data := callback.Data
@@ -137,6 +201,40 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, database *db.DB, callback *tgbota
 		// Store which config type this user is entering
 		pendingConfigInput[userID] = configType
 		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Please enter the new value for %s (as a number):", configType)))
+	} else if data == "search|confirm" || data == "search|cancel" {
+		pending, ok := pendingSearches[userID]
+		delete(pendingSearches, userID)
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, "This search was already started or cancelled."))
+			return
+		}
+		if data == "search|cancel" {
+			bot.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, callback.Message.Text+"\n\n❌ Cancelled."))
+			return
+		}
+		bot.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, callback.Message.Text+"\n\n✅ Confirmed."))
+		submitSearchRequest(bot, database, chatID, userID, pending.text, pending.quick, true, mainKeyboard())
+	} else if strings.HasPrefix(data, "sheets|") {
+		// Format: sheets|page
+		page, err := strconv.Atoi(strings.TrimPrefix(data, "sheets|"))
+		if err == nil {
+			handleSheetsCommand(bot, database, spreadsheetURL, chatID, userID, page, callback.Message.MessageID)
+		}
+	} else if data == "cleartab|cancel" {
+		bot.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, callback.Message.Text+"\n\n❌ Kept the tab."))
+	} else if strings.HasPrefix(data, "cleartab|") {
+		// Format: cleartab|requestID|runs, runs being the saved search runs the user agreed to lose
+		parts := strings.SplitN(data, "|", 3)
+		if len(parts) == 3 {
+			runs, err := strconv.Atoi(parts[2])
+			if err == nil {
+				bot.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, callback.Message.Text+"\n\n✅ Confirmed."))
+				handleClearTabCommand(bot, database, writer, chatID, userID, parts[1], runs)
+			}
+		}
+	} else if strings.HasPrefix(data, "preset|") {
+		// Format: preset|name
+		handleLoadPresetCommand(bot, database, chatID, userID, strings.TrimPrefix(data, "preset|"))
 	} else if strings.HasPrefix(data, "resume|") {
 		requestIDStr := strings.TrimPrefix(data, "resume|")
 		requestID, err := strconv.Atoi(requestIDStr)
@@ -161,20 +259,76 @@ func showConfigMenu(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID
 		return
 	}
 
-	configText := fmt.Sprintf(
+	configText := formatConfigText(userConfig)
+
+	keyboard := configMenuKeyboard(userConfig)
+
+	msg := tgbotapi.NewMessage(chatID, configText)
+	msg.ReplyMarkup = keyboard
+	bot.Send(msg)
+}
+
+// formatConfigText renders the current configuration shown above the config menu
+func formatConfigText(userConfig *db.UserConfig) string {
+	return fmt.Sprintf(
 		"⚙️ Current Configuration:\n\n"+
 			"📄 Max Pages: %d\n"+
+			"📚 Max Total Pages: %s\n"+
+			"✂️ Max Listings: %s\n"+
 			"⭐ Min Reviews: %d\n"+
 			"💰 Min Price: %.2f\n"+
 			"💰 Max Price: %.2f\n"+
-			"⭐ Min Stars: %.2f\n\n"+
+			"⭐ Min Stars: %.2f\n"+
+			"🏅 Superhost Only: %s\n"+
+			"💖 Guest Favorite Only: %s\n"+
+			"🏷 Discounted Only: %s\n"+
+			"🌙 Max Minimum Nights: %s\n"+
+			"↕️ Sort By: %s\n"+
+			"🔁 Dedup: %s\n"+
+			"📅 Similar Dates: %s\n"+
+			"🔍 Auto-Widen: %s\n"+
+			"📏 Price Step: $%d (preview with /step)\n"+
+			"📍 Location Contains: %s (set with /location)\n"+
+			"👥 Max Price per Guest: %s (set with /perguest)\n"+
+			"🧩 Detail Fields: %s (set with /fields)\n"+
+			"🗓 Saved Searches: %s\n"+
+			"🗂 Spreadsheets: %s\n"+
+			"📨 Forward To: %s (set with /forward)\n\n"+
 			"Click buttons below to change values:",
-		userConfig.MaxPages, userConfig.MinReviews, userConfig.MinPrice, userConfig.MaxPrice, userConfig.MinStars)
+		userConfig.MaxPages, formatPageBudget(userConfig.MaxTotalPages), formatListingCap(userConfig.MaxListings), userConfig.MinReviews, userConfig.MinPrice,
+		userConfig.MaxPrice, userConfig.MinStars, onOff(userConfig.SuperhostOnly),
+		onOff(userConfig.GuestFavoriteOnly), onOff(userConfig.DiscountedOnly), formatMinimumNightsLimit(userConfig.MaxMinimumNights), sortLabel(userConfig.SortBy), dedupScopeLabel(userConfig.DedupScope), onOff(userConfig.IncludeSimilarDates), formatAutoWiden(userConfig.AutoWidenMin), userConfig.PriceStep, formatLocationFilter(userConfig.LocationContains), formatPerGuestLimit(userConfig.MaxPricePerGuest), formatDetailFields(userConfig.EnrichFields), savedSearchSheetLabel(userConfig.AppendSavedSearches), spreadsheetModeLabel(userConfig.SpreadsheetPerRequest), formatForwardTarget(userConfig.ForwardTo))
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+// savedSearchSheetLabel describes where the runs of saved searches are written
+func savedSearchSheetLabel(appendRuns bool) string {
+	if appendRuns {
+		return "one tab per search, runs appended"
+	}
+	return "new tab per run"
+}
+
+// spreadsheetModeLabel describes where requests are written
+func spreadsheetModeLabel(perRequest bool) string {
+	if perRequest {
+		return "new spreadsheet per request"
+	}
+	return "new tab per request in the shared spreadsheet"
+}
+
+// configMenuKeyboard returns the inline keyboard listing all config values.
+// On/off settings toggle directly from this menu.
+func configMenuKeyboard(userConfig *db.UserConfig) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📄 Max Pages", "config|max_pages"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📚 Max Total Pages", "config|max_total_pages"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✂️ Max Listings", "config|max_listings"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Reviews", "config|min_reviews"),
 		),
@@ -187,11 +341,106 @@ func showConfigMenu(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Stars", "config|min_stars"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏅 Superhost Only: "+onOff(userConfig.SuperhostOnly),
+				fmt.Sprintf("set|superhost_only|%t", !userConfig.SuperhostOnly)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💖 Guest Favorite Only: "+onOff(userConfig.GuestFavoriteOnly),
+				fmt.Sprintf("set|guest_favorite_only|%t", !userConfig.GuestFavoriteOnly)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏷 Discounted Only: "+onOff(userConfig.DiscountedOnly),
+				fmt.Sprintf("set|discounted_only|%t", !userConfig.DiscountedOnly)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌙 Max Minimum Nights", "config|max_minimum_nights"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↕️ Sort By", "config|sort_by"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Dedup", "config|dedup_scope"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📅 Similar Dates: "+onOff(userConfig.IncludeSimilarDates),
+				fmt.Sprintf("set|include_similar_dates|%t", !userConfig.IncludeSimilarDates)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔍 Auto-Widen", "config|auto_widen_min"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📏 Price Step", "config|price_step"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗓 Append Saved Search Runs: "+onOff(userConfig.AppendSavedSearches),
+				fmt.Sprintf("set|append_saved_searches|%t", !userConfig.AppendSavedSearches)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Spreadsheet per Request: "+onOff(userConfig.SpreadsheetPerRequest),
+				fmt.Sprintf("set|spreadsheet_per_request|%t", !userConfig.SpreadsheetPerRequest)),
+		),
 	)
+}
 
-	msg := tgbotapi.NewMessage(chatID, configText)
-	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+// formatPageBudget formats the per-request page budget, where 0 means the default
+// budget (MAX_TOTAL_PAGES) or unlimited when there is none
+func formatPageBudget(maxTotalPages int) string {
+	if maxTotalPages > 0 {
+		return strconv.Itoa(maxTotalPages)
+	}
+	if budget := scheduler.DefaultPageBudget(); budget > 0 {
+		return fmt.Sprintf("default (%d)", budget)
+	}
+	return "unlimited"
+}
+
+// formatListingCap formats the per-request listing cap, where 0 means no cap
+func formatListingCap(maxListings int) string {
+	if maxListings <= 0 {
+		return "no cap"
+	}
+	return strconv.Itoa(maxListings)
+}
+
+// formatMinimumNightsLimit formats the longest accepted minimum stay, where 0 means no limit
+func formatMinimumNightsLimit(maxMinimumNights int) string {
+	if maxMinimumNights <= 0 {
+		return "no limit"
+	}
+	return strconv.Itoa(maxMinimumNights)
+}
+
+// formatAutoWiden formats the auto-widen listing floor, where 0 means off
+func formatAutoWiden(autoWidenMin int) string {
+	if autoWidenMin <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("below %d listings", autoWidenMin)
+}
+
+// onOff formats a boolean setting for the config menu
+func onOff(enabled bool) string {
+	if enabled {
+		return "On"
+	}
+	return "Off"
+}
+
+// sortLabel returns the menu label of a sort option key
+func sortLabel(sortBy string) string {
+	if option, ok := filter.LookupSortOption(sortBy); ok {
+		return option.Label
+	}
+	return sortBy
+}
+
+// dedupScopeLabel returns the menu label of a dedup scope key
+func dedupScopeLabel(dedupScope string) string {
+	if option, ok := filter.LookupDedupScope(dedupScope); ok {
+		return option.Label
+	}
+	return dedupScope
 }
 
 // handleConfigCallback shows options for changing a specific config value
@@ -226,6 +475,111 @@ func handleConfigCallback(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, u
 				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
 			),
 		)
+	case "max_total_pages":
+		defaultBudgetLabel := "Unlimited"
+		if budget := scheduler.DefaultPageBudget(); budget > 0 {
+			defaultBudgetLabel = fmt.Sprintf("Default (%d)", budget)
+		}
+		text = fmt.Sprintf("📚 Max Total Pages\n\nCurrent: %s\n\nPage budget shared by all links of a request (0 = %s). Select new value or enter custom:",
+			formatPageBudget(userConfig.MaxTotalPages), formatPageBudget(0))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(defaultBudgetLabel, "set|max_total_pages|0"),
+				tgbotapi.NewInlineKeyboardButtonData("20", "set|max_total_pages|20"),
+				tgbotapi.NewInlineKeyboardButtonData("30", "set|max_total_pages|30"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("50", "set|max_total_pages|50"),
+				tgbotapi.NewInlineKeyboardButtonData("100", "set|max_total_pages|100"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Custom Value", "input|max_total_pages"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+			),
+		)
+	case "max_listings":
+		text = fmt.Sprintf("✂️ Max Listings\n\nCurrent: %s\n\nOnly this many matching listings are enriched per request, picked by Sort By (0 = no cap). Select new value or enter custom:",
+			formatListingCap(userConfig.MaxListings))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("No cap", "set|max_listings|0"),
+				tgbotapi.NewInlineKeyboardButtonData("20", "set|max_listings|20"),
+				tgbotapi.NewInlineKeyboardButtonData("50", "set|max_listings|50"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("100", "set|max_listings|100"),
+				tgbotapi.NewInlineKeyboardButtonData("200", "set|max_listings|200"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Custom Value", "input|max_listings"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+			),
+		)
+	case "max_minimum_nights":
+		text = fmt.Sprintf("🌙 Max Minimum Nights\n\nCurrent: %s\n\nListings that require a longer stay are dropped after their detail page is read (0 = no limit). Select new value or enter custom:",
+			formatMinimumNightsLimit(userConfig.MaxMinimumNights))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("No limit", "set|max_minimum_nights|0"),
+				tgbotapi.NewInlineKeyboardButtonData("2", "set|max_minimum_nights|2"),
+				tgbotapi.NewInlineKeyboardButtonData("3", "set|max_minimum_nights|3"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("5", "set|max_minimum_nights|5"),
+				tgbotapi.NewInlineKeyboardButtonData("7", "set|max_minimum_nights|7"),
+				tgbotapi.NewInlineKeyboardButtonData("14", "set|max_minimum_nights|14"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Custom Value", "input|max_minimum_nights"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+			),
+		)
+	case "auto_widen_min":
+		text = fmt.Sprintf("🔍 Auto-Widen\n\nCurrent: %s\n\nWhen fewer listings than this are kept, the search runs once more without its maximum price and the new listings are added (0 = off). Select new value or enter custom:",
+			formatAutoWiden(userConfig.AutoWidenMin))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Off", "set|auto_widen_min|0"),
+				tgbotapi.NewInlineKeyboardButtonData("5", "set|auto_widen_min|5"),
+				tgbotapi.NewInlineKeyboardButtonData("10", "set|auto_widen_min|10"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("20", "set|auto_widen_min|20"),
+				tgbotapi.NewInlineKeyboardButtonData("50", "set|auto_widen_min|50"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Custom Value", "input|auto_widen_min"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+			),
+		)
+	case "price_step":
+		text = fmt.Sprintf("📏 Price Step\n\nCurrent: $%d\n\nSearch URLs with a maximum price are split into one link per price band of this width. "+
+			"Smaller steps find more listings in dense areas but create more links. Send /step <amount> <url> to see how many links a search would make. Select new value or enter custom:",
+			userConfig.PriceStep)
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("$25", "set|price_step|25"),
+				tgbotapi.NewInlineKeyboardButtonData("$50", "set|price_step|50"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("$100", "set|price_step|100"),
+				tgbotapi.NewInlineKeyboardButtonData("$200", "set|price_step|200"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Custom Value", "input|price_step"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+			),
+		)
 	case "min_reviews":
 		currentValue := userConfig.MinReviews
 		text = fmt.Sprintf("⭐ Min Reviews\n\nCurrent: %d\n\nSelect new value or enter custom:", currentValue)
@@ -306,6 +660,33 @@ func handleConfigCallback(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, u
 				tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
 			),
 		)
+	case "sort_by":
+		text = fmt.Sprintf("↕️ Sort By\n\nCurrent: %s\n\nSelect how listings are ordered in the sheet:", sortLabel(userConfig.SortBy))
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, option := range filter.SortOptions {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(option.Label, "set|sort_by|"+option.Key),
+			))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+		))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	case "dedup_scope":
+		text = fmt.Sprintf("🔁 Dedup\n\nCurrent: %s\n\n"+
+			"Per request keeps each listing once, in the first link that found it. "+
+			"Per link keeps every price range complete, so listings near a range boundary may appear (and be enriched) twice.",
+			dedupScopeLabel(userConfig.DedupScope))
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, option := range filter.DedupScopeOptions {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(option.Label, "set|dedup_scope|"+option.Key),
+			))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back", "config|back"),
+		))
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	case "back":
 		showConfigMenu(bot, database, chatID, userID)
 		return
@@ -319,6 +700,37 @@ func handleConfigCallback(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, u
 	bot.Send(editMsg)
 }
 
+// maxPagesLimit caps the pages fetched per link; more only repeats Airbnb's last page
+const maxPagesLimit = 50
+
+// checkPriceRange returns an error for a negative price or a min price above the max price
+func checkPriceRange(minPrice, maxPrice float64) error {
+	if minPrice < 0 || maxPrice < 0 {
+		return errors.New("prices must not be negative")
+	}
+	if minPrice > maxPrice {
+		return fmt.Errorf("min price %.2f is above max price %.2f", minPrice, maxPrice)
+	}
+	return nil
+}
+
+// checkConfigPriceRange checks the price range the user's config would have with a new
+// min or max price
+func checkConfigPriceRange(database *db.DB, userID int64, minPrice, maxPrice *float64) error {
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	newMin, newMax := userConfig.MinPrice, userConfig.MaxPrice
+	if minPrice != nil {
+		newMin = *minPrice
+	}
+	if maxPrice != nil {
+		newMax = *maxPrice
+	}
+	return checkPriceRange(newMin, newMax)
+}
+
 // handleSetConfigValue updates a config value and shows confirmation
 func handleSetConfigValue(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, configType string, valueStr string, messageID int) {
 	var err error
@@ -331,135 +743,1531 @@ func handleSetConfigValue(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, u
 			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
 			return
 		}
-		err = database.UpdateUserConfig(userID, &value, nil, nil, nil, nil)
-		updateText = fmt.Sprintf("✅ Max Pages updated to %d", value)
-	case "min_reviews":
-		var value int
-		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+		if value < 1 || value > maxPagesLimit {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Max Pages must be between 1 and %d.", maxPagesLimit)))
+			return
+		}
+		err = database.UpdateUserConfig(userID, &value, nil, nil, nil, nil)
+		updateText = fmt.Sprintf("✅ Max Pages updated to %d", value)
+	case "max_total_pages":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value < 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigMaxTotalPages(userID, value)
+		updateText = fmt.Sprintf("✅ Max Total Pages updated to %s", formatPageBudget(value))
+	case "max_listings":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value < 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigMaxListings(userID, value)
+		updateText = fmt.Sprintf("✅ Max Listings updated to %s", formatListingCap(value))
+	case "max_minimum_nights":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value < 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigMaxMinimumNights(userID, value)
+		updateText = fmt.Sprintf("✅ Max Minimum Nights updated to %s", formatMinimumNightsLimit(value))
+	case "price_step":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value <= 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigPriceStep(userID, value)
+		updateText = fmt.Sprintf("✅ Price Step updated to $%d", value)
+	case "auto_widen_min":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value < 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigAutoWidenMin(userID, value)
+		updateText = fmt.Sprintf("✅ Auto-Widen updated to %s", formatAutoWiden(value))
+	case "min_reviews":
+		var value int
+		if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || value < 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfig(userID, nil, &value, nil, nil, nil)
+		updateText = fmt.Sprintf("✅ Min Reviews updated to %d", value)
+	case "min_price":
+		var value float64
+		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		if rangeErr := checkConfigPriceRange(database, userID, &value, nil); rangeErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Min Price not changed: %v", rangeErr)))
+			return
+		}
+		err = database.UpdateUserConfig(userID, nil, nil, &value, nil, nil)
+		updateText = fmt.Sprintf("✅ Min Price updated to %.2f", value)
+	case "max_price":
+		var value float64
+		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		if rangeErr := checkConfigPriceRange(database, userID, nil, &value); rangeErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Max Price not changed: %v", rangeErr)))
+			return
+		}
+		err = database.UpdateUserConfig(userID, nil, nil, nil, &value, nil)
+		updateText = fmt.Sprintf("✅ Max Price updated to %.2f", value)
+	case "min_stars":
+		var value float64
+		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		if value < 0 || value > 5 {
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Min Stars must be between 0 and 5."))
+			return
+		}
+		err = database.UpdateUserConfig(userID, nil, nil, nil, nil, &value)
+		updateText = fmt.Sprintf("✅ Min Stars updated to %.2f", value)
+	case "superhost_only":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigSuperhostOnly(userID, value)
+		updateText = fmt.Sprintf("✅ Superhost Only turned %s", onOff(value))
+	case "guest_favorite_only":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigGuestFavoriteOnly(userID, value)
+		updateText = fmt.Sprintf("✅ Guest Favorite Only turned %s", onOff(value))
+	case "discounted_only":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigDiscountedOnly(userID, value)
+		updateText = fmt.Sprintf("✅ Discounted Only turned %s", onOff(value))
+	case "append_saved_searches":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigAppendSavedSearches(userID, value)
+		updateText = fmt.Sprintf("✅ Append Saved Search Runs turned %s", onOff(value))
+	case "spreadsheet_per_request":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigSpreadsheetPerRequest(userID, value)
+		updateText = fmt.Sprintf("✅ Spreadsheet per Request turned %s", onOff(value))
+		if value {
+			updateText += fmt.Sprintf("\n\nNew spreadsheets belong to the bot's Google account and %s. "+
+				"Use File > Make a copy in Google Sheets to keep your own.", sheetSettings.NewSpreadsheetAccess())
+		}
+	case "include_similar_dates":
+		value, parseErr := strconv.ParseBool(valueStr)
+		if parseErr != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigIncludeSimilarDates(userID, value)
+		updateText = fmt.Sprintf("✅ Similar Dates turned %s", onOff(value))
+	case "sort_by":
+		if _, ok := filter.LookupSortOption(valueStr); !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigSortBy(userID, valueStr)
+		updateText = fmt.Sprintf("✅ Sort By updated to %s", sortLabel(valueStr))
+	case "dedup_scope":
+		if _, ok := filter.LookupDedupScope(valueStr); !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+			return
+		}
+		err = database.UpdateUserConfigDedupScope(userID, valueStr)
+		updateText = fmt.Sprintf("✅ Dedup updated to %s", dedupScopeLabel(valueStr))
+	default:
+		bot.Send(tgbotapi.NewMessage(chatID, "Unknown config type"))
+		return
+	}
+
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating config: %v", err)))
+		return
+	}
+
+	// Show updated config
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, updateText))
+		return
+	}
+
+	configText := updateText + "\n\n" + formatConfigText(userConfig)
+
+	keyboard := configMenuKeyboard(userConfig)
+
+	// If messageID is 0, send a new message instead of editing
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, configText)
+		msg.ReplyMarkup = keyboard
+		bot.Send(msg)
+	} else {
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, configText)
+		editMsg.ReplyMarkup = &keyboard
+		bot.Send(editMsg)
+	}
+}
+
+// isNumeric checks if a string is numeric
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// handleCustomConfigInput handles when user enters a custom numeric value
+func handleCustomConfigInput(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, valueStr string) {
+	// Show menu to select which config to update
+	text := fmt.Sprintf("You entered: %s\n\nWhich config value would you like to update?", valueStr)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📄 Max Pages", fmt.Sprintf("set|max_pages|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📚 Max Total Pages", fmt.Sprintf("set|max_total_pages|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✂️ Max Listings", fmt.Sprintf("set|max_listings|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Reviews", fmt.Sprintf("set|min_reviews|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💰 Min Price", fmt.Sprintf("set|min_price|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💰 Max Price", fmt.Sprintf("set|max_price|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Stars", fmt.Sprintf("set|min_stars|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌙 Max Minimum Nights", fmt.Sprintf("set|max_minimum_nights|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔍 Auto-Widen", fmt.Sprintf("set|auto_widen_min|%s", valueStr)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📏 Price Step", fmt.Sprintf("set|price_step|%s", valueStr)),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	bot.Send(msg)
+}
+
+// handlePauseCommand pauses or resumes request processing for everyone (admin only)
+func handlePauseCommand(bot *tgbotapi.BotAPI, sched *scheduler.Scheduler, chatID int64, userID int64, paused bool) {
+	if userID != adminUserID {
+		bot.Send(tgbotapi.NewMessage(chatID, "This command is only available to the admin."))
+		return
+	}
+	if sched.Paused() == paused {
+		if paused {
+			bot.Send(tgbotapi.NewMessage(chatID, "⏸ Processing is already paused. Use /resume to start it again."))
+		} else {
+			bot.Send(tgbotapi.NewMessage(chatID, "▶️ Processing is already running."))
+		}
+		return
+	}
+
+	if err := sched.SetPaused(paused); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v", err)))
+		return
+	}
+	if paused {
+		log.Println("Request processing paused by the admin")
+		bot.Send(tgbotapi.NewMessage(chatID, "⏸ Processing paused. A request already running finishes; new requests are queued "+
+			"and processed after /resume. The state is kept across restarts."))
+	} else {
+		log.Println("Request processing resumed by the admin")
+		bot.Send(tgbotapi.NewMessage(chatID, "▶️ Processing resumed. Queued requests are picked up now."))
+	}
+}
+
+// handleCleanupCommand deletes request sheets older than the given number of days (admin only)
+func handleCleanupCommand(bot *tgbotapi.BotAPI, database *db.DB, writer *sheets.Writer, chatID int64, userID int64, args string) {
+	if userID != adminUserID {
+		bot.Send(tgbotapi.NewMessage(chatID, "This command is only available to the admin."))
+		return
+	}
+
+	days, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || days < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /cleanup <days>\nDeletes request sheets older than the given number of days (at least 1)."))
+		return
+	}
+
+	// Keep tabs of requests that are still running or can be resumed
+	keep := make(map[string]bool)
+	names, err := database.GetUnfinishedRequestSheetNames()
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to load active requests: %v", err)))
+		return
+	}
+	for _, name := range names {
+		keep[name] = true
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	deleted, err := writer.DeleteSheetsOlderThan(cutoff, keep)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Cleanup failed: %v", err)))
+		return
+	}
+
+	// Forget the deleted tabs, as /cleartab does, so retries write to a new sheet
+	if err := database.ClearSharedSpreadsheetSheets(deleted); err != nil {
+		log.Printf("Error clearing sheets deleted by /cleanup from their requests: %v\n", err)
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🧹 Deleted %d sheet(s) older than %d day(s).", len(deleted), days)))
+}
+
+// formatLocationFilter describes the location filter for the config menu
+func formatLocationFilter(locationContains string) string {
+	if locationContains == "" {
+		return "Any"
+	}
+	return fmt.Sprintf("%q", locationContains)
+}
+
+// formatPerGuestLimit describes the per-guest price cap, where 0 means no limit
+func formatPerGuestLimit(maxPricePerGuest float64) string {
+	if maxPricePerGuest <= 0 {
+		return "No limit"
+	}
+	return fmt.Sprintf("$%.2f", maxPricePerGuest)
+}
+
+// formatForwardTarget describes who success messages are also sent to, where 0 means nobody
+func formatForwardTarget(forwardTo int64) string {
+	if forwardTo == 0 {
+		return "Nobody"
+	}
+	return fmt.Sprintf("user %d", forwardTo)
+}
+
+// formatDetailFields describes the selected detail field groups for the config menu
+func formatDetailFields(enrichFields string) string {
+	if enrichFields == "" {
+		return "All"
+	}
+	return strings.ReplaceAll(enrichFields, ",", ", ")
+}
+
+// parseConfigImport decodes and validates settings produced by db.ExportUserConfig.
+// Unknown fields and out-of-range values are rejected. current supplies the values of
+// fields left out, to check that the price range stays valid.
+func parseConfigImport(text string, current *db.UserConfig) (*db.ConfigExport, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.DisallowUnknownFields()
+	var imported db.ConfigExport
+	if err := decoder.Decode(&imported); err != nil {
+		return nil, fmt.Errorf("malformed config: %w", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("malformed config: unexpected data after the closing brace")
+	}
+	if imported == (db.ConfigExport{}) {
+		return nil, errors.New("config has no settings")
+	}
+
+	nonNegative := map[string]*int{
+		"max_total_pages":    imported.MaxTotalPages,
+		"max_listings":       imported.MaxListings,
+		"min_reviews":        imported.MinReviews,
+		"max_minimum_nights": imported.MaxMinimumNights,
+		"auto_widen_min":     imported.AutoWidenMin,
+	}
+	for name, value := range nonNegative {
+		if value != nil && *value < 0 {
+			return nil, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if imported.MaxPages != nil && (*imported.MaxPages < 1 || *imported.MaxPages > maxPagesLimit) {
+		return nil, fmt.Errorf("max_pages must be between 1 and %d", maxPagesLimit)
+	}
+	if imported.PriceStep != nil && *imported.PriceStep < 1 {
+		return nil, errors.New("price_step must be at least 1")
+	}
+	if imported.MinStars != nil && (*imported.MinStars < 0 || *imported.MinStars > 5) {
+		return nil, errors.New("min_stars must be between 0 and 5")
+	}
+	if imported.MaxPricePerGuest != nil && *imported.MaxPricePerGuest < 0 {
+		return nil, errors.New("max_price_per_guest must not be negative")
+	}
+
+	minPrice, maxPrice := current.MinPrice, current.MaxPrice
+	if imported.MinPrice != nil {
+		minPrice = *imported.MinPrice
+	}
+	if imported.MaxPrice != nil {
+		maxPrice = *imported.MaxPrice
+	}
+	if err := checkPriceRange(minPrice, maxPrice); err != nil {
+		return nil, err
+	}
+
+	if imported.SortBy != nil {
+		if _, ok := filter.LookupSortOption(*imported.SortBy); !ok {
+			return nil, fmt.Errorf("unknown sort_by %q", *imported.SortBy)
+		}
+	}
+	if imported.DedupScope != nil {
+		if _, ok := filter.LookupDedupScope(*imported.DedupScope); !ok {
+			return nil, fmt.Errorf("unknown dedup_scope %q", *imported.DedupScope)
+		}
+	}
+	if imported.LocationContains != nil {
+		location := strings.TrimSpace(*imported.LocationContains)
+		imported.LocationContains = &location
+	}
+	if imported.EnrichFields != nil {
+		fields, err := parser.ParseDetailFields(*imported.EnrichFields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enrich_fields: %w", err)
+		}
+		enrichFields := fields.String()
+		imported.EnrichFields = &enrichFields
+	}
+
+	return &imported, nil
+}
+
+// applyConfigImport saves the imported settings, leaving fields that were left out unchanged
+func applyConfigImport(database *db.DB, userID int64, imported *db.ConfigExport) error {
+	if err := database.UpdateUserConfig(userID, imported.MaxPages, imported.MinReviews,
+		imported.MinPrice, imported.MaxPrice, imported.MinStars); err != nil {
+		return err
+	}
+
+	var updates []func() error
+	if v := imported.MaxTotalPages; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigMaxTotalPages(userID, *v) })
+	}
+	if v := imported.MaxListings; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigMaxListings(userID, *v) })
+	}
+	if v := imported.SuperhostOnly; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigSuperhostOnly(userID, *v) })
+	}
+	if v := imported.GuestFavoriteOnly; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigGuestFavoriteOnly(userID, *v) })
+	}
+	if v := imported.DiscountedOnly; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigDiscountedOnly(userID, *v) })
+	}
+	if v := imported.AppendSavedSearches; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigAppendSavedSearches(userID, *v) })
+	}
+	if v := imported.SpreadsheetPerRequest; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigSpreadsheetPerRequest(userID, *v) })
+	}
+	if v := imported.IncludeSimilarDates; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigIncludeSimilarDates(userID, *v) })
+	}
+	if v := imported.MaxMinimumNights; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigMaxMinimumNights(userID, *v) })
+	}
+	if v := imported.LocationContains; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigLocationContains(userID, *v) })
+	}
+	if v := imported.MaxPricePerGuest; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigMaxPricePerGuest(userID, *v) })
+	}
+	if v := imported.EnrichFields; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigEnrichFields(userID, *v) })
+	}
+	if v := imported.SortBy; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigSortBy(userID, *v) })
+	}
+	if v := imported.DedupScope; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigDedupScope(userID, *v) })
+	}
+	if v := imported.AutoWidenMin; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigAutoWidenMin(userID, *v) })
+	}
+	if v := imported.PriceStep; v != nil {
+		updates = append(updates, func() error { return database.UpdateUserConfigPriceStep(userID, *v) })
+	}
+	for _, update := range updates {
+		if err := update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatStoredConfig lists every stored setting of the user as "column: value" lines,
+// including settings without a menu button, for support and debugging
+func formatStoredConfig(userConfig *db.UserConfig) string {
+	fields := []struct {
+		name  string
+		value interface{}
+	}{
+		{"user_id", userConfig.UserID},
+		{"max_pages", userConfig.MaxPages},
+		{"max_total_pages", userConfig.MaxTotalPages},
+		{"max_listings", userConfig.MaxListings},
+		{"min_reviews", userConfig.MinReviews},
+		{"min_price", userConfig.MinPrice},
+		{"max_price", userConfig.MaxPrice},
+		{"min_stars", userConfig.MinStars},
+		{"superhost_only", userConfig.SuperhostOnly},
+		{"guest_favorite_only", userConfig.GuestFavoriteOnly},
+		{"discounted_only", userConfig.DiscountedOnly},
+		{"sort_by", userConfig.SortBy},
+		{"location_contains", fmt.Sprintf("%q", userConfig.LocationContains)},
+		{"max_minimum_nights", userConfig.MaxMinimumNights},
+		{"dedup_scope", userConfig.DedupScope},
+		{"auto_widen_min", userConfig.AutoWidenMin},
+		{"price_step", userConfig.PriceStep},
+		{"include_similar_dates", userConfig.IncludeSimilarDates},
+		{"max_price_per_guest", userConfig.MaxPricePerGuest},
+		{"enrich_fields", fmt.Sprintf("%q", userConfig.EnrichFields)},
+		{"append_saved_searches", userConfig.AppendSavedSearches},
+		{"spreadsheet_per_request", userConfig.SpreadsheetPerRequest},
+		{"forward_to", userConfig.ForwardTo},
+		{"created_at", userConfig.CreatedAt.Format("2006-01-02 15:04:05")},
+		{"updated_at", userConfig.UpdatedAt.Format("2006-01-02 15:04:05")},
+	}
+
+	var b strings.Builder
+	b.WriteString("🗂 Stored config:\n\n")
+	for _, field := range fields {
+		fmt.Fprintf(&b, "%s: %v\n", field.name, field.value)
+	}
+	return b.String()
+}
+
+// handleMyConfigCommand replies with the user's stored config as a key/value list
+func handleMyConfigCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64) {
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Error loading config: %v", err)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, formatStoredConfig(userConfig)))
+}
+
+// handleStatsCommand replies with aggregates over all of the user's requests
+func handleStatsCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64) {
+	stats, err := database.GetUserStats(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to load your stats: %v", err)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, formatUserStats(stats)))
+}
+
+// formatUserStats renders the /stats reply. Requests that finished without listings are
+// called out: a run of them usually means the site changed and parsing broke.
+func formatUserStats(stats *db.UserStats) string {
+	if stats.Requests == 0 {
+		return "📈 No requests yet. Send me a search URL to get started!"
+	}
+
+	var b strings.Builder
+	b.WriteString("📈 Your stats:\n\n")
+	fmt.Fprintf(&b, "Requests: %d (%d done, %d failed)\n", stats.Requests, stats.DoneRequests, stats.FailedRequests)
+	fmt.Fprintf(&b, "Listings scraped: %d\n", stats.Listings)
+	fmt.Fprintf(&b, "Average per request: %.1f\n", float64(stats.Listings)/float64(stats.Requests))
+	if stats.TopCurrency != "" {
+		fmt.Fprintf(&b, "Most common currency: %s\n", stats.TopCurrency)
+	}
+	if stats.LastRequestAt.Valid {
+		fmt.Fprintf(&b, "Last request: %s\n", stats.LastRequestAt.Time.Format("2006-01-02 15:04"))
+	}
+	if stats.EmptyRequests > 0 {
+		fmt.Fprintf(&b, "\n⚠️ %d finished request(s) kept no listings, the latest on %s.",
+			stats.EmptyRequests, stats.LastEmptyAt.Time.Format("2006-01-02"))
+	}
+	return b.String()
+}
+
+// handleWhoAmICommand replies with the user's Telegram ID, e.g. for the admin to allowlist them
+func handleWhoAmICommand(bot *tgbotapi.BotAPI, chatID int64, from *tgbotapi.User) {
+	text := fmt.Sprintf("🪪 Your Telegram user ID: %d", from.ID)
+	if from.UserName != "" {
+		text += fmt.Sprintf("\nUsername: @%s", from.UserName)
+	}
+	if chatID != from.ID {
+		text += fmt.Sprintf("\nChat ID: %d", chatID)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// handleFeedbackCommand stores a user's report together with their latest request and
+// settings, and forwards it to the admin, so wrong extractions get reported with context
+func handleFeedbackCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, from *tgbotapi.User, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /feedback <text>\nDescribe what looks wrong, e.g. \"prices in request #12 are empty\". Your latest request and settings are attached automatically."))
+		return
+	}
+
+	latest, err := database.GetLatestRequest(from.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to look up latest request for feedback: %v\n", err)
+	}
+	var requestID sql.NullInt64
+	if latest != nil {
+		requestID = sql.NullInt64{Int64: int64(latest.ID), Valid: true}
+	}
+	var config string
+	if userConfig, err := database.GetUserConfig(from.ID); err != nil {
+		log.Printf("Warning: Failed to load config for feedback: %v\n", err)
+	} else {
+		config = db.ExportUserConfig(userConfig)
+	}
+
+	feedbackID, err := database.CreateFeedback(from.ID, requestID, text, config)
+	if err != nil {
+		log.Printf("Error storing feedback: %v\n", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Failed to save your feedback, please try again later."))
+		return
+	}
+
+	adminMsg := tgbotapi.NewMessage(adminUserID, formatFeedback(feedbackID, from, latest, text, config))
+	adminMsg.DisableWebPagePreview = true
+	if _, err := bot.Send(adminMsg); err != nil {
+		log.Printf("Warning: Failed to forward feedback #%d to admin: %v\n", feedbackID, err)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🙏 Thanks! Your feedback #%d was sent to the admin.", feedbackID)))
+}
+
+// formatFeedback renders a /feedback report for the admin
+func formatFeedback(feedbackID int, from *tgbotapi.User, latest *db.Request, text string, config string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📝 Feedback #%d from user %d", feedbackID, from.ID)
+	if from.UserName != "" {
+		fmt.Fprintf(&b, " (@%s)", from.UserName)
+	}
+	b.WriteString("\n\n" + text + "\n\n")
+	if latest != nil {
+		fmt.Fprintf(&b, "Latest request: #%d (%s, %d listings, %s)\n", latest.ID, latest.Status, latest.ListingsCount,
+			latest.CreatedAt.Format("2006-01-02 15:04"))
+	} else {
+		b.WriteString("Latest request: none\n")
+	}
+	if config != "" {
+		b.WriteString("Config: " + config)
+	}
+	return b.String()
+}
+
+// handleExportConfigCommand replies with the user's settings as an /importconfig command
+func handleExportConfigCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64) {
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Error loading config: %v", err)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, "📤 Your current config. Send this line back to restore it:"))
+	bot.Send(tgbotapi.NewMessage(chatID, "/importconfig "+db.ExportUserConfig(userConfig)))
+}
+
+// handleImportConfigCommand applies settings exported with /exportconfig
+func handleImportConfigCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /importconfig <config>\nApplies settings exported with /exportconfig. Settings left out keep their value."))
+		return
+	}
+
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Error loading config: %v", err)))
+		return
+	}
+	imported, err := parseConfigImport(args, userConfig)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Config not imported: %v", err)))
+		return
+	}
+	if err := applyConfigImport(database, userID, imported); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating config: %v", err)))
+		return
+	}
+
+	userConfig, err = database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "✅ Config imported"))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, "✅ Config imported\n\n"+formatConfigText(userConfig))
+	msg.ReplyMarkup = configMenuKeyboard(userConfig)
+	bot.Send(msg)
+}
+
+// maxPresetNameLen is the longest preset name; names also go into callback data, which
+// Telegram limits to 64 bytes
+const maxPresetNameLen = 32
+
+// normalizePresetName lowercases a preset name and checks that it is usable
+func normalizePresetName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", errors.New("preset name is empty")
+	}
+	if len(name) > maxPresetNameLen {
+		return "", fmt.Errorf("preset name is longer than %d characters", maxPresetNameLen)
+	}
+	if strings.ContainsAny(name, "|\n") {
+		return "", errors.New("preset name must not contain | or line breaks")
+	}
+	return name, nil
+}
+
+// handleSavePresetCommand stores the user's current config under a name
+func handleSavePresetCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /savepreset <name>\nSaves your current settings under a name; load them again with /loadpreset or /presets."))
+		return
+	}
+	name, err := normalizePresetName(args)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v", err)))
+		return
+	}
+
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Error loading config: %v", err)))
+		return
+	}
+	if err := database.SaveFilterPreset(userID, name, db.ExportUserConfig(userConfig)); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error saving preset: %v", err)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("💾 Saved your current settings as preset %q.", name)))
+}
+
+// handleLoadPresetCommand applies one of the user's presets to their config
+func handleLoadPresetCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /loadpreset <name>\nApplies a preset saved with /savepreset. /presets lists them."))
+		return
+	}
+	name, err := normalizePresetName(args)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v", err)))
+		return
+	}
+
+	preset, err := database.GetFilterPreset(userID, name)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading preset: %v", err)))
+		return
+	}
+	if preset == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ No preset named %q. /presets lists your presets.", name)))
+		return
+	}
+
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Error loading config: %v", err)))
+		return
+	}
+	imported, err := parseConfigImport(preset.Settings, userConfig)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Preset %q is invalid: %v", name, err)))
+		return
+	}
+	if err := applyConfigImport(database, userID, imported); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating config: %v", err)))
+		return
+	}
+
+	userConfig, err = database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Loaded preset %q", name)))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Loaded preset %q\n\n", name)+formatConfigText(userConfig))
+	msg.ReplyMarkup = configMenuKeyboard(userConfig)
+	bot.Send(msg)
+}
+
+// handlePresetsCommand lists the user's presets with a button to load each
+func handlePresetsCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64) {
+	presets, err := database.GetFilterPresets(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading presets: %v", err)))
+		return
+	}
+	if len(presets) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "You have no presets yet. Save your current settings with /savepreset <name>."))
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, preset := range presets {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📂 "+preset.Name, "preset|"+preset.Name),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("💾 Your presets (%d). Tap one to load it:", len(presets)))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msg)
+}
+
+// sheetsPageSize is how many sheet tabs /sheets lists per page
+const sheetsPageSize = 10
+
+// sheetsPageKeyboard returns the Prev/Next buttons for page (0-based) of pages, or nil
+// if there is only one page
+func sheetsPageKeyboard(page, pages int) *tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", fmt.Sprintf("sheets|%d", page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", fmt.Sprintf("sheets|%d", page+1)))
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &keyboard
+}
+
+// handleSheetsCommand lists the sheet tabs of the user's finished requests, newest first,
+// one page (0-based) at a time. With a messageID the page replaces that message.
+func handleSheetsCommand(bot *tgbotapi.BotAPI, database *db.DB, spreadsheetURL string, chatID int64, userID int64, page int, messageID int) {
+	if page < 0 {
+		page = 0
+	}
+	requests, total, err := database.GetUserSheetTabs(userID, sheetsPageSize, page*sheetsPageSize)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading your sheets: %v", err)))
+		return
+	}
+	if total == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "You have no finished requests with a sheet tab yet."))
+		return
+	}
+	pages := (total + sheetsPageSize - 1) / sheetsPageSize
+	if len(requests) == 0 {
+		// Tabs were removed since the page was shown: show the last page instead
+		handleSheetsCommand(bot, database, spreadsheetURL, chatID, userID, pages-1, messageID)
+		return
+	}
+
+	lines := []string{fmt.Sprintf("📊 Your sheets (%d), page %d/%d:", total, page+1, pages)}
+	for _, req := range requests {
+		lines = append(lines, fmt.Sprintf("#%d <a href=\"%s\">%s</a> · %s · %d listings",
+			req.ID, html.EscapeString(sheets.TabURL(requestSpreadsheetURL(spreadsheetURL, &req), req.SheetGID.Int64)), html.EscapeString(req.SheetName.String),
+			req.CreatedAt.Format("2006-01-02"), req.ListingsCount))
+	}
+	text := strings.Join(lines, "\n")
+	keyboard := sheetsPageKeyboard(page, pages)
+
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		if keyboard != nil {
+			msg.ReplyMarkup = *keyboard
+		}
+		bot.Send(msg)
+		return
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	editMsg.ParseMode = "HTML"
+	editMsg.DisableWebPagePreview = true
+	editMsg.ReplyMarkup = keyboard
+	bot.Send(editMsg)
+}
+
+// handleLastSheetCommand links the sheet tab of the user's most recent request. The gid is
+// looked up again by tab name, so the link also works for requests stored before gids
+// were, and the user is told when the tab is gone.
+func handleLastSheetCommand(bot *tgbotapi.BotAPI, database *db.DB, writer *sheets.Writer, spreadsheetURL string, chatID int64, userID int64) {
+	req, err := database.GetLastSheetRequest(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading your last request: %v", err)))
+		return
+	}
+	if req == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "You have no requests with a sheet tab yet. Send me a search URL to get started!"))
+		return
+	}
+
+	spreadsheetURL = requestSpreadsheetURL(spreadsheetURL, req)
+	sheetID, err := writer.ForSpreadsheet(req.SpreadsheetID.String).GetSheetIDByName(req.SheetName.String)
+	switch {
+	case errors.Is(err, sheets.ErrSheetNotFound):
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ The tab %s of request #%d is no longer in the spreadsheet (deleted or renamed).\n%s",
+			req.SheetName.String, req.ID, spreadsheetURL))
+		msg.DisableWebPagePreview = true
+		bot.Send(msg)
+		return
+	case err != nil:
+		log.Printf("Warning: Failed to look up sheet '%s': %v\n", req.SheetName.String, err)
+	}
+
+	tabURL := spreadsheetURL
+	if err == nil {
+		tabURL = sheets.TabURL(spreadsheetURL, sheetID)
+	} else if req.SheetGID.Valid {
+		// The stored gid is still right unless the tab was recreated
+		tabURL = sheets.TabURL(spreadsheetURL, req.SheetGID.Int64)
+	}
+
+	text := fmt.Sprintf("📊 Request #%d (%s, %d listings, %s):\n<a href=\"%s\">%s</a>",
+		req.ID, req.Status, req.ListingsCount, req.CreatedAt.Format("2006-01-02 15:04"),
+		html.EscapeString(tabURL), html.EscapeString(req.SheetName.String))
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	bot.Send(msg)
+}
+
+// requestSpreadsheetURL returns the URL of the spreadsheet a request wrote to: its own
+// spreadsheet if it has one, the shared spreadsheet otherwise
+func requestSpreadsheetURL(spreadsheetURL string, req *db.Request) string {
+	if req.SpreadsheetID.Valid {
+		return sheets.SpreadsheetURL(req.SpreadsheetID.String)
+	}
+	return spreadsheetURL
+}
+
+// estimateSearchDuration returns how many pages a search of the given number of links
+// fetches at most, and roughly how long that takes
+func estimateSearchDuration(links, maxPages, maxTotalPages int, timePerPage time.Duration) (int, time.Duration) {
+	pages := links * maxPages
+	if maxTotalPages > 0 && pages > maxTotalPages {
+		pages = maxTotalPages
+	}
+	return pages, time.Duration(pages) * timePerPage
+}
+
+// formatEstimate formats an estimated duration in whole minutes ("45 min", "2h 05m")
+func formatEstimate(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes < 1:
+		return "under a minute"
+	case minutes < 60:
+		return fmt.Sprintf("%d min", minutes)
+	default:
+		return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+	}
+}
+
+// askSearchConfirmation tells the user how many links and how much time a price-split
+// search takes and asks whether to queue it
+func askSearchConfirmation(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, search pendingSearch,
+	links int, priceStep int, userConfig *db.UserConfig) {
+	fetchPerPage, totalPerPage, err := database.GetAverageTimePerPage()
+	if err != nil {
+		log.Printf("Warning: Failed to load average time per page: %v\n", err)
+	}
+	timePerPage := totalPerPage
+	if search.quick {
+		timePerPage = fetchPerPage // no detail pages
+	}
+	if timePerPage <= 0 {
+		timePerPage = defaultTimePerPage
+	}
+	pages, estimate := estimateSearchDuration(links, userConfig.MaxPages, scheduler.EffectivePageBudget(userConfig.MaxTotalPages), timePerPage)
+
+	pendingSearches[userID] = search
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"🔢 This search will be split into %d price range links ($%d steps) of up to %d pages each: at most %d pages.\n"+
+			"⏱ Estimated time: ~%s\n\nStart it?",
+		links, priceStep, userConfig.MaxPages, pages, formatEstimate(estimate)))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Start", "search|confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "search|cancel"),
+		),
+	)
+	bot.Send(msg)
+}
+
+// handleStepCommand sets the width of the price bands search URLs are split into. Given
+// search URLs after the amount, it only previews how many links the step would create and
+// asks for confirmation.
+func handleStepCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"📏 Price Step: $%d\nUsage: /step <amount> to change it, /step <amount> <url> to first see how many links a search would be split into.",
+			userConfig.PriceStep)))
+		return
+	}
+
+	step, err := strconv.Atoi(strings.TrimPrefix(fields[0], "$"))
+	if err != nil || step <= 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "The step must be a whole number of dollars above 0, e.g. /step 25"))
+		return
+	}
+
+	urls := fields[1:]
+	if len(urls) == 0 {
+		if err := database.UpdateUserConfigPriceStep(userID, step); err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating config: %v", err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Price Step updated to $%d", step)))
+		return
+	}
+
+	links, currentLinks := 0, 0
+	for _, u := range urls {
+		links += pricerange.CountURLRanges(u, step)
+		currentLinks += pricerange.CountURLRanges(u, userConfig.PriceStep)
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"📏 With $%d steps this search is split into %d link(s) (%d with your current $%d step).",
+		step, links, currentLinks, userConfig.PriceStep))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ Use $%d steps", step), fmt.Sprintf("set|price_step|%d", step)),
+		),
+	)
+	bot.Send(msg)
+}
+
+// defaultSavedSearchTime is when a saved search runs if /subscribe gets no time
+const defaultSavedSearchTime = "08:00"
+
+// maxSavedSearches limits the saved searches of one user
+const maxSavedSearches = 10
+
+// parseSubscribeArgs splits "/subscribe" arguments into the search URL, the daily run
+// time ("HH:MM", default defaultSavedSearchTime) and how often the search runs: "daily"
+// (the default, implied by a time) or "hourly". The options may come before or after the URL.
+func parseSubscribeArgs(args string) (string, string, string, error) {
+	var searchURL string
+	runAt := ""
+	every := db.RunDaily
+	for _, field := range strings.Fields(args) {
+		if strings.HasPrefix(field, "http://") || strings.HasPrefix(field, "https://") {
+			if searchURL != "" {
+				return "", "", "", errors.New("only one URL per saved search")
+			}
+			searchURL = field
+			continue
+		}
+		switch strings.ToLower(field) {
+		case db.RunDaily, db.RunHourly:
+			every = strings.ToLower(field)
+			continue
+		}
+		t, err := time.Parse("15:04", field)
+		if err != nil {
+			return "", "", "", fmt.Errorf("%q is neither a URL, daily/hourly nor a time like 07:30", field)
+		}
+		runAt = t.Format("15:04")
+	}
+	if searchURL == "" {
+		return "", "", "", errors.New("missing search URL")
+	}
+	if every == db.RunHourly {
+		if runAt != "" {
+			return "", "", "", errors.New("hourly searches take no time, they run an hour after each other")
+		}
+		return searchURL, time.Now().Format("15:04"), every, nil
+	}
+	if runAt == "" {
+		runAt = defaultSavedSearchTime
+	}
+	return searchURL, runAt, every, nil
+}
+
+// formatSavedSearchSchedule describes when a saved search runs
+func formatSavedSearchSchedule(search db.SavedSearch) string {
+	if search.Every == db.RunHourly {
+		return "every hour"
+	}
+	return "daily at " + search.RunAt
+}
+
+// handleSubscribeCommand saves a search to re-run every day or every hour, or lists the
+// saved searches when called without arguments. /schedule is an alias.
+func handleSubscribeCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	searches, err := database.GetSavedSearches(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading saved searches: %v", err)))
+		return
+	}
+
+	if strings.TrimSpace(args) == "" {
+		usage := "Usage: /subscribe <url> [HH:MM|hourly] - re-run a search every day (default " + defaultSavedSearchTime +
+			" server time) or every hour and get only the new listings.\n/unsubscribe <id> to stop."
+		if len(searches) == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "You have no saved searches.\n\n"+usage))
+			return
+		}
+		var lines []string
+		lines = append(lines, "⏰ Saved searches:")
+		for _, search := range searches {
+			lines = append(lines, fmt.Sprintf("#%d %s: %s", search.ID, formatSavedSearchSchedule(search), search.URL))
+		}
+		msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n")+"\n\n"+usage)
+		msg.DisableWebPagePreview = true
+		bot.Send(msg)
+		return
+	}
+
+	searchURL, runAt, every, err := parseSubscribeArgs(args)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v\nUsage: /subscribe <url> [HH:MM|hourly]", err)))
+		return
+	}
+	if err := searchurl.Validate(searchURL); err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Not an Airbnb search: %v.\n\n%s", err, searchURLGuidance))
+		msg.DisableWebPagePreview = true
+		bot.Send(msg)
+		return
+	}
+	if len(searches) >= maxSavedSearches {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"❌ You already have %d saved searches. Remove one with /unsubscribe <id> first.", len(searches))))
+		return
+	}
+
+	// Runs keep the settings the search was saved with, like /exportconfig would give them
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+		return
+	}
+
+	// Normalized like a search sent in the chat, so a run is not queued while the same search is pending
+	searchURL = searchurl.Normalize(addCurrencyToURL(searchURL))
+	search, err := database.CreateSavedSearch(userID, searchURL, runAt, every, db.ExportUserConfig(userConfig))
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error saving search: %v", err)))
+		return
+	}
+
+	schedule := fmt.Sprintf("every day at %s (server time)", runAt)
+	firstRun := "today at " + runAt
+	if every == db.RunHourly {
+		schedule = "every hour"
+	}
+	if every == db.RunHourly || time.Now().Format("15:04") >= runAt {
+		firstRun = "now"
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ Saved search #%d runs %s. The first run starts %s and collects the current listings; "+
+			"later runs report only listings that are new. Runs use your current settings, "+
+			"later changes don't apply to them.\nStop with /unsubscribe %d.",
+		search.ID, schedule, firstRun, search.ID)))
+}
+
+// handleUnsubscribeCommand deletes one of the user's saved searches
+func handleUnsubscribeCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	savedSearchID, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil || savedSearchID < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /unsubscribe <id>\nSee your saved searches with /subscribe."))
+		return
+	}
+
+	deleted, err := database.DeleteSavedSearch(userID, savedSearchID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error removing saved search: %v", err)))
+		return
+	}
+	if !deleted {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Saved search #%d not found.", savedSearchID)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Saved search #%d removed. A run already queued still finishes.", savedSearchID)))
+}
+
+// handleLocationCommand sets the user's location filter: only listings whose location
+// contains the text are kept. "/location off" clears it; no argument shows the current value.
+func handleLocationCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		userConfig, err := database.GetUserConfig(userID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"📍 Location Contains: %s\nUsage: /location <text> to keep only listings in that area, /location off to clear.",
+			formatLocationFilter(userConfig.LocationContains))))
+		return
+	}
+	if strings.EqualFold(text, "off") || strings.EqualFold(text, "clear") {
+		text = ""
+	}
+
+	if err := database.UpdateUserConfigLocationContains(userID, text); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating location filter: %v", err)))
+		return
+	}
+
+	if text == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "📍 Location filter cleared. All locations will be kept."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"📍 Location filter set: keeping listings whose location contains %q. Listings without a location are kept too.", text)))
+}
+
+// handlePerGuestCommand sets the user's per-guest price cap in USD: enriched listings
+// whose USD price divided by the guest capacity is higher are dropped. "/perguest off"
+// clears it; no argument shows the current value.
+func handlePerGuestCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		userConfig, err := database.GetUserConfig(userID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"👥 Max Price per Guest: %s\nUsage: /perguest <USD> to drop listings costing more per guest per night, /perguest off to clear.",
+			formatPerGuestLimit(userConfig.MaxPricePerGuest))))
+		return
+	}
+
+	var value float64
+	if !strings.EqualFold(text, "off") && !strings.EqualFold(text, "clear") {
+		parsed, err := strconv.ParseFloat(strings.TrimPrefix(text, "$"), 64)
+		if err != nil || parsed <= 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Send a positive amount in USD, e.g. /perguest 40, or /perguest off."))
+			return
+		}
+		value = parsed
+	}
+
+	if err := database.UpdateUserConfigMaxPricePerGuest(userID, value); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating price per guest limit: %v", err)))
+		return
+	}
+
+	if value == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "👥 Price per guest limit cleared."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"👥 Price per guest limit set: dropping listings above $%.2f per guest per night. "+
+			"Listings without a USD price or guest count are kept.", value)))
+}
+
+// parseForwardTarget parses the Telegram user ID given to /forward, 0 for "off". Only
+// users allowed to use the bot can be targets, so it can't be used to message strangers.
+func parseForwardTarget(text string, userID int64) (int64, error) {
+	if strings.EqualFold(text, "off") || strings.EqualFold(text, "clear") {
+		return 0, nil
+	}
+	target, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a Telegram user ID (they can get it with /whoami)", text)
+	}
+	if target == userID {
+		return 0, errors.New("the results already go to you")
+	}
+	if !allowedUserIDs[target] {
+		return 0, fmt.Errorf("user %d is not allowed to use this bot", target)
+	}
+	return target, nil
+}
+
+// handleForwardCommand sets another user the success messages of the user's requests are
+// also sent to, e.g. "/forward 123456789". "/forward off" clears it; no argument shows
+// the current target.
+func handleForwardCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		userConfig, err := database.GetUserConfig(userID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"📨 Forward To: %s\nUsage: /forward <userID> to also send your results to another user of this bot, /forward off to stop.",
+			formatForwardTarget(userConfig.ForwardTo))))
+		return
+	}
+
+	target, err := parseForwardTarget(text, userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v", err)))
+		return
+	}
+	if err := database.UpdateUserConfigForwardTo(userID, target); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating forward target: %v", err)))
+		return
+	}
+
+	if target == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "📨 Results are no longer forwarded."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"📨 The results of your requests will also be sent to user %d. They need to have started this bot to receive them.", target)))
+}
+
+// handleFieldsCommand sets which detail page field groups are extracted, e.g.
+// "/fields rooms,superhost" to skip the slow review scan. "/fields all" extracts
+// everything again; no argument shows the current selection.
+func handleFieldsCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		userConfig, err := database.GetUserConfig(userID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"🧩 Detail Fields: %s\nUsage: /fields <fields> to extract only some of %s from detail pages "+
+				"(reviews are the slowest), /fields all to extract everything.",
+			formatDetailFields(userConfig.EnrichFields), strings.Join(parser.AllDetailFields, ", "))))
+		return
+	}
+	if strings.EqualFold(text, "all") {
+		text = ""
+	}
+
+	fields, err := parser.ParseDetailFields(strings.ReplaceAll(text, " ", ","))
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v", err)))
+		return
+	}
+	enrichFields := fields.String()
+	if err := database.UpdateUserConfigEnrichFields(userID, enrichFields); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating detail fields: %v", err)))
+		return
+	}
+
+	if enrichFields == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "🧩 All detail fields will be extracted."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"🧩 Detail fields set: %s. Fields your filters or sort order need are extracted too.", formatDetailFields(enrichFields))))
+}
+
+// handleClearTabCommand deletes the sheet tab created for one of the user's requests.
+// Requests that are still running keep their tab. When runs of a saved search appended
+// to the tab, deleting it loses all of them, so the user is asked first; confirmedRuns
+// is the number of runs they agreed to lose (0 when not asked yet).
+func handleClearTabCommand(bot *tgbotapi.BotAPI, database *db.DB, writer *sheets.Writer, chatID int64, userID int64, args string, confirmedRuns int) {
+	requestID, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || requestID < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /cleartab <requestID>\nDeletes the sheet tab of a finished request."))
+		return
+	}
+
+	req, err := database.GetRequestByID(requestID)
+	if err != nil || req.UserID != userID {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Request #%d not found.", requestID)))
+		return
+	}
+	if req.Status != "done" && req.Status != "failed" {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d is still %s; wait for it to finish before deleting its tab.", requestID, req.Status)))
+		return
+	}
+	if req.SpreadsheetID.Valid {
+		// Its tab is the only one in the spreadsheet, and a spreadsheet can't be left without tabs
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d has a spreadsheet of its own, its only tab can't be deleted. Ask the bot admin to delete the spreadsheet if you no longer need it.", requestID)))
+		return
+	}
+	if req.SavedSearchID.Valid {
+		runs, err := database.CountRequestsSharingSheet(requestID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to check which runs share the tab of request #%d: %v", requestID, err)))
+			return
+		}
+		// Asked again when more runs appended since the user confirmed
+		if runs > 1 && runs != confirmedRuns {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+				"⚠️ Request #%d is a run of saved search #%d, and its tab '%s' holds the rows of %d runs. Deleting the tab loses all %d runs, not just this one.",
+				requestID, req.SavedSearchID.Int64, req.SheetName.String, runs, runs))
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 Delete all %d runs", runs), fmt.Sprintf("cleartab|%d|%d", requestID, runs)),
+				tgbotapi.NewInlineKeyboardButtonData("❌ Keep", "cleartab|cancel"),
+			))
+			bot.Send(msg)
 			return
 		}
-		err = database.UpdateUserConfig(userID, nil, &value, nil, nil, nil)
-		updateText = fmt.Sprintf("✅ Min Reviews updated to %d", value)
-	case "min_price":
-		var value float64
-		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+	}
+	sheetID := req.SheetGID.Int64
+	if !req.SheetGID.Valid {
+		// Requests from before gids were stored only know their tab by name
+		if !req.SheetName.Valid || req.SheetName.String == "" {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d has no stored sheet tab to delete.", requestID)))
 			return
 		}
-		err = database.UpdateUserConfig(userID, nil, nil, &value, nil, nil)
-		updateText = fmt.Sprintf("✅ Min Price updated to %.2f", value)
-	case "max_price":
-		var value float64
-		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+		sheetID, err = writer.GetSheetIDByName(req.SheetName.String)
+		if errors.Is(err, sheets.ErrSheetNotFound) {
+			if err := database.ClearRequestSheet(requestID); err != nil {
+				log.Printf("Warning: Failed to clear sheet of request %d: %v\n", requestID, err)
+			}
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("The tab '%s' of request #%d is already gone.", req.SheetName.String, requestID)))
 			return
 		}
-		err = database.UpdateUserConfig(userID, nil, nil, nil, &value, nil)
-		updateText = fmt.Sprintf("✅ Max Price updated to %.2f", value)
-	case "min_stars":
-		var value float64
-		if _, err := fmt.Sscanf(valueStr, "%f", &value); err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid value: %s", valueStr)))
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to find the tab of request #%d: %v", requestID, err)))
 			return
 		}
-		err = database.UpdateUserConfig(userID, nil, nil, nil, nil, &value)
-		updateText = fmt.Sprintf("✅ Min Stars updated to %.2f", value)
-	default:
-		bot.Send(tgbotapi.NewMessage(chatID, "Unknown config type"))
+	}
+
+	if err := writer.DeleteSheet(sheetID); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to delete the tab of request #%d: %v", requestID, err)))
 		return
 	}
+	if err := database.ClearRequestSheet(requestID); err != nil {
+		log.Printf("Warning: Failed to clear sheet of request %d: %v\n", requestID, err)
+	}
 
-	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error updating config: %v", err)))
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 Deleted sheet tab '%s' of request #%d.", req.SheetName.String, requestID)))
+}
+
+// maxTelegramMessageLen is the longest text Telegram accepts in one message
+const maxTelegramMessageLen = 4096
+
+// maxListMessages caps how many messages /list sends for one request
+const maxListMessages = 10
+
+// handleListCommand sends the listings saved for one of the user's requests, split into
+// as many messages as needed
+func handleListCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	requestID, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || requestID < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /list <requestID>\nShows the listings kept by a request."))
 		return
 	}
 
-	// Show updated config
-	userConfig, err := database.GetUserConfig(userID)
+	req, err := database.GetRequestByID(requestID)
+	if err != nil || req.UserID != userID {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Request #%d not found.", requestID)))
+		return
+	}
+
+	listings, err := database.GetRequestListings(requestID)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, updateText))
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to load listings of request #%d: %v", requestID, err)))
+		return
+	}
+	if len(listings) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d (status: %s) has no saved listings.", requestID, req.Status)))
 		return
 	}
 
-	configText := fmt.Sprintf(
-		"%s\n\n⚙️ Current Configuration:\n\n"+
-			"📄 Max Pages: %d\n"+
-			"⭐ Min Reviews: %d\n"+
-			"💰 Min Price: %.2f\n"+
-			"💰 Max Price: %.2f\n"+
-			"⭐ Min Stars: %.2f\n\n"+
-			"Click buttons below to change values:",
-		updateText, userConfig.MaxPages, userConfig.MinReviews, userConfig.MinPrice, userConfig.MaxPrice, userConfig.MinStars)
+	text := fmt.Sprintf("📋 Request #%d (status: %s): %d listing(s)\n\n", requestID, req.Status, len(listings)) +
+		formatListingEntries(listings)
+	parts := splitMessage(text, maxTelegramMessageLen)
+	for i, part := range parts {
+		if i == maxListMessages {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✂️ %d more message(s) not shown; see the request's sheet for all listings.", len(parts)-i)))
+			break
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, part))
+	}
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📄 Max Pages", "config|max_pages"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Reviews", "config|min_reviews"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💰 Min Price", "config|min_price"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💰 Max Price", "config|max_price"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Stars", "config|min_stars"),
-		),
-	)
+// maxFindResults caps how many listings /find returns
+const maxFindResults = 20
 
-	// If messageID is 0, send a new message instead of editing
-	if messageID == 0 {
-		msg := tgbotapi.NewMessage(chatID, configText)
-		msg.ReplyMarkup = keyboard
+// minFindQueryLen is the shortest /find query; shorter ones match almost everything
+const minFindQueryLen = 2
+
+// handleFindCommand searches the titles and descriptions of the listings stored for all
+// of the user's requests
+func handleFindCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	query := strings.TrimSpace(args)
+	if utf8.RuneCountInString(query) < minFindQueryLen {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /find <text>\nSearches the titles and descriptions of all listings you have fetched."))
+		return
+	}
+
+	listings, err := database.SearchListings(userID, query, maxFindResults+1)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to search your listings: %v", err)))
+		return
+	}
+	if len(listings) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("No stored listing mentions %q.", query)))
+		return
+	}
+
+	header := fmt.Sprintf("🔎 %d listing(s) mentioning %q, newest first:\n\n", len(listings), query)
+	if len(listings) > maxFindResults {
+		listings = listings[:maxFindResults]
+		header = fmt.Sprintf("🔎 The %d newest listings mentioning %q (there are more; refine the search to narrow them down):\n\n", maxFindResults, query)
+	}
+	for _, part := range splitMessage(header+formatListingEntries(listings), maxTelegramMessageLen) {
+		msg := tgbotapi.NewMessage(chatID, part)
+		msg.DisableWebPagePreview = true
 		bot.Send(msg)
-	} else {
-		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, configText)
-		editMsg.ReplyMarkup = &keyboard
-		bot.Send(editMsg)
 	}
 }
 
-// isNumeric checks if a string is numeric
-func isNumeric(s string) bool {
-	_, err := strconv.ParseFloat(s, 64)
-	return err == nil
-}
+// handleRetryCommand re-queues the failed links of one of the user's finished requests.
+// The request resumes into its existing sheet, skipping links that already completed.
+func handleRetryCommand(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, args string) {
+	requestID, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || requestID < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Usage: /retry <requestID>\nRe-runs only the failed links of a previous request."))
+		return
+	}
 
-// handleCustomConfigInput handles when user enters a custom numeric value
-func handleCustomConfigInput(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, valueStr string) {
-	// Show menu to select which config to update
-	text := fmt.Sprintf("You entered: %s\n\nWhich config value would you like to update?", valueStr)
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📄 Max Pages", fmt.Sprintf("set|max_pages|%s", valueStr)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Reviews", fmt.Sprintf("set|min_reviews|%s", valueStr)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💰 Min Price", fmt.Sprintf("set|min_price|%s", valueStr)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💰 Max Price", fmt.Sprintf("set|max_price|%s", valueStr)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⭐ Min Stars", fmt.Sprintf("set|min_stars|%s", valueStr)),
-		),
-	)
+	req, err := database.GetRequestByID(requestID)
+	if err != nil || req.UserID != userID {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Request #%d not found.", requestID)))
+		return
+	}
+	if req.Status != "done" && req.Status != "failed" {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d is still %s; wait for it to finish before retrying.", requestID, req.Status)))
+		return
+	}
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	count, err := database.ResetFailedSearchLinks(requestID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to re-queue request #%d: %v", requestID, err)))
+		return
+	}
+	if count == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Request #%d has no failed links to retry.", requestID)))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🔁 Retrying %d failed link(s) of request #%d. Results will be added to its existing sheet.", count, requestID)))
 }
 
 // runTelegramBot runs the fetcher as a Telegram bot
 func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentialsPath string) {
-	// Refresh environment variables (Windows-specific)
-	refreshEnvVars()
-
 	// Get bot token from environment
 	botToken := os.Getenv("AIR_KEY_TG")
 	if botToken == "" {
@@ -475,13 +2283,12 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 	log.Printf("Authorized on account %s\n", bot.Self.UserName)
 
 	// Send startup notification to admin (only once)
-	adminID := int64(420478432)
-	startupMsg := tgbotapi.NewMessage(adminID, "🚀 Service started successfully!")
+	startupMsg := tgbotapi.NewMessage(adminUserID, "🚀 Service started successfully!")
 	_, err = bot.Send(startupMsg)
 	if err != nil {
 		log.Printf("Warning: Failed to send startup notification to admin: %v\n", err)
 	} else {
-		log.Printf("Startup notification sent to admin %d\n", adminID)
+		log.Printf("Startup notification sent to admin %d\n", adminUserID)
 	}
 
 	// Initialize database
@@ -495,7 +2302,7 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 	// Initialize Google Sheets writer
 	spreadsheetID := sheets.ExtractSpreadsheetID(spreadsheetURL)
 	if spreadsheetID == "" {
-		log.Fatalf("Error: Could not extract spreadsheet ID from URL: %s\n", spreadsheetURL)
+		log.Fatalf("Error: Could not extract spreadsheet ID from URL: %s (expected a link like https://docs.google.com/spreadsheets/d/<id>/edit; published /d/e/ links can't be written to)\n", spreadsheetURL)
 	}
 
 	// Check if credentials are available
@@ -507,12 +2314,18 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 		log.Printf("Using GOOGLE_SHEETS_CREDENTIALS from environment variable (length: %d chars)\n", len(credsEnv))
 	}
 
-	writer, err := sheets.NewWriter(spreadsheetID, credentialsPath)
+	writer, err := sheets.NewWriter(spreadsheetID, credentialsPath, sheetSettings)
 	if err != nil {
 		log.Fatalf("Error: Failed to initialize Google Sheets writer: %v\n", err)
 	}
 
-	log.Printf("Google Sheets writer initialized for spreadsheet: %s\n", spreadsheetID)
+	log.Printf("Google Sheets writer initialized for spreadsheet: %s (service account: %s)\n", spreadsheetID, writer.ServiceAccountEmail())
+
+	// Fail fast if the service account can't edit the spreadsheet, rather than on the first request
+	if err := writer.VerifyAccess(); err != nil {
+		log.Fatalf("Error: Google Sheets access check failed: %v\n", err)
+	}
+	log.Println("Google Sheets write access verified")
 
 	// Initialize and start scheduler (browser will be created on-demand)
 	sched := scheduler.NewScheduler(database, bot, writer, spreadsheetURL)
@@ -529,12 +2342,7 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 	updates := bot.GetUpdatesChan(updateConfig)
 
 	// Create persistent keyboard
-	configKeyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("⚙️ Config"),
-		),
-	)
-	configKeyboard.ResizeKeyboard = true
+	configKeyboard := mainKeyboard()
 
 	// Handle updates
 	for update := range updates {
@@ -547,7 +2355,7 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 			}
 
 			if update.CallbackQuery.Message != nil {
-				handleCallbackQuery(bot, database, update.CallbackQuery)
+				handleCallbackQuery(bot, database, writer, spreadsheetURL, update.CallbackQuery)
 			}
 			continue
 		}
@@ -558,6 +2366,12 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 
 		userID := update.Message.From.ID
 
+		// /whoami answers everyone: users not on the allowlist need their ID to be added
+		if update.Message.IsCommand() && update.Message.Command() == "whoami" {
+			handleWhoAmICommand(bot, update.Message.Chat.ID, update.Message.From)
+			continue
+		}
+
 		// Check authorization first - silently ignore unauthorized users
 		if !allowedUserIDs[userID] {
 			// Silently ignore - don't send any messages
@@ -597,7 +2411,7 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 					bot.Send(pinMsg)
 				}
 			case "help":
-				helpText := "Commands:\n/start - Start the bot\n/help - Show this help\n/config - Configure filter settings\n\nJust send me a Bnb search URL to fetch listings! Results will be automatically added to Google Sheets."
+				helpText := "Commands:\n/start - Start the bot\n/help - Show this help\n/config - Configure filter settings\n/exportconfig - Get your settings as text to save or share\n/myconfig - Show every stored setting\n/stats - Show how much you have fetched so far\n/whoami - Show your Telegram user ID\n/feedback <text> - Report wrong or missing data to the admin\n/importconfig <config> - Apply settings from /exportconfig\n/savepreset <name> - Save your settings as a named preset\n/loadpreset <name> - Apply a saved preset\n/presets - List your presets to load one with a tap\n/retry <requestID> - Re-run the failed links of a request\n/cleartab <requestID> - Delete the sheet tab of a finished request\n/list <requestID> - Show the listings kept by a request\n/find <text> - Search the titles and descriptions of all listings you have fetched\n/sheets - Browse the sheet tabs of your finished requests\n/lastsheet - Open the sheet tab of your latest request\n/quick <url> - Fetch search results only, skipping detail pages (much faster)\n/location <text> - Keep only listings whose location contains the text (/location off to clear)\n/perguest <USD> - Drop listings above a price per guest per night (/perguest off to clear)\n/fields <fields> - Choose which detail page fields to extract (/fields all to reset)\n/forward <userID> - Also send your results to another user of this bot (/forward off to stop)\n/step <amount> [url] - Set the price band width searches are split into (with a URL: preview the link count)\n/subscribe <url> [HH:MM|hourly] - Re-run a search daily or hourly and get only new listings (no arguments: list saved searches; /schedule works too)\n/unsubscribe <id> - Stop a saved search (or /unschedule)\n\nJust send me a Bnb search URL to fetch listings! Results will be automatically added to Google Sheets."
 				msg := tgbotapi.NewMessage(update.Message.Chat.ID, helpText)
 				msg.ReplyMarkup = configKeyboard
 				bot.Send(msg)
@@ -614,6 +2428,56 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 					msg.ReplyMarkup = configKeyboard
 					bot.Send(msg)
 				}
+			case "pause":
+				handlePauseCommand(bot, sched, update.Message.Chat.ID, userID, true)
+			case "resume":
+				handlePauseCommand(bot, sched, update.Message.Chat.ID, userID, false)
+			case "cleanup":
+				handleCleanupCommand(bot, database, writer, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "cleartab":
+				handleClearTabCommand(bot, database, writer, update.Message.Chat.ID, userID, update.Message.CommandArguments(), 0)
+			case "myconfig":
+				handleMyConfigCommand(bot, database, update.Message.Chat.ID, userID)
+			case "stats":
+				handleStatsCommand(bot, database, update.Message.Chat.ID, userID)
+			case "feedback":
+				handleFeedbackCommand(bot, database, update.Message.Chat.ID, update.Message.From, update.Message.CommandArguments())
+			case "exportconfig":
+				handleExportConfigCommand(bot, database, update.Message.Chat.ID, userID)
+			case "importconfig":
+				handleImportConfigCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "savepreset":
+				handleSavePresetCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "loadpreset":
+				handleLoadPresetCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "presets":
+				handlePresetsCommand(bot, database, update.Message.Chat.ID, userID)
+			case "step":
+				handleStepCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "sheets":
+				handleSheetsCommand(bot, database, spreadsheetURL, update.Message.Chat.ID, userID, 0, 0)
+			case "lastsheet":
+				handleLastSheetCommand(bot, database, writer, spreadsheetURL, update.Message.Chat.ID, userID)
+			case "list":
+				handleListCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "find":
+				handleFindCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "retry":
+				handleRetryCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "subscribe", "schedule":
+				handleSubscribeCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "unsubscribe", "unschedule":
+				handleUnsubscribeCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "location":
+				handleLocationCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "perguest":
+				handlePerGuestCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "fields":
+				handleFieldsCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "forward":
+				handleForwardCommand(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments())
+			case "quick":
+				submitSearchRequest(bot, database, update.Message.Chat.ID, userID, update.Message.CommandArguments(), true, false, configKeyboard)
 			default:
 				msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Unknown command. Use /help for available commands.")
 				msg.ReplyMarkup = configKeyboard
@@ -655,115 +2519,172 @@ func runTelegramBot(configPath string, maxPages int, spreadsheetURL, credentials
 		}
 
 		// Handle URL messages - support multiple URLs separated by newlines
-		messageText := strings.TrimSpace(update.Message.Text)
-		if messageText == "" {
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Please send me a Bnb search URL (or multiple URLs, one per line).")
-			bot.Send(msg)
-			continue
-		}
-
-		// Split by newlines and validate each URL
-		lines := strings.Split(messageText, "\n")
-		var validURLs []string
-		var invalidLines []string
+		submitSearchRequest(bot, database, update.Message.Chat.ID, userID, update.Message.Text, false, false, configKeyboard)
+	}
+}
 
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
+// searchURLGuidance tells users which URLs the bot can fetch
+const searchURLGuidance = "Search on Airbnb (any country's site), set the dates, guests and filters you want, " +
+	"then copy the address of the results page, e.g. https://www.airbnb.com/s/Lisbon/homes?checkin=2026-06-01&checkout=2026-06-05&adults=2"
+
+// submitSearchRequest validates the search URLs in messageText (one per line), expands
+// them into price range links and queues a request. Quick requests skip detail page enrichment.
+func submitSearchRequest(bot *tgbotapi.BotAPI, database *db.DB, chatID int64, userID int64, messageText string, quick bool,
+	confirmed bool, configKeyboard tgbotapi.ReplyKeyboardMarkup) {
+	messageText = strings.TrimSpace(messageText)
+	if messageText == "" {
+		msg := tgbotapi.NewMessage(chatID, "Please send me a Bnb search URL (or multiple URLs, one per line).")
+		bot.Send(msg)
+		return
+	}
 
-			// Validate URL
-			if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
-				invalidLines = append(invalidLines, line)
-				continue
-			}
+	// Split by newlines and validate each URL
+	lines := strings.Split(messageText, "\n")
+	var validURLs []string
+	var invalidLines []string
+	var rejectedURLs []string // URLs that are not Airbnb searches, with the reason
 
-			// Add currency=USD to URL
-			urlWithCurrency := addCurrencyToURL(line)
-			validURLs = append(validURLs, urlWithCurrency)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
 		}
 
-		// If no valid URLs found
-		if len(validURLs) == 0 {
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Please send valid URLs starting with http:// or https://")
-			bot.Send(msg)
+		// Validate URL
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			invalidLines = append(invalidLines, line)
 			continue
 		}
-
-		// Warn about invalid lines if any
-		if len(invalidLines) > 0 {
-			warnMsg := fmt.Sprintf("⚠️ Skipped %d invalid line(s) that don't look like URLs.", len(invalidLines))
-			bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, warnMsg))
+		if err := searchurl.Validate(line); err != nil {
+			rejectedURLs = append(rejectedURLs, fmt.Sprintf("• %s: %v", sanitize.Text(line, 60), err))
+			continue
 		}
 
-		// Expand URLs into price range sub-URLs ($50 steps)
-		var expandedURLs []string
-		var priceRangeLabels []string // parallel array: label for each expanded URL
-		totalOriginalURLs := len(validURLs)
-		hasPriceRanges := false
+		// Add currency=USD to URL and normalize so resubmissions compare equal
+		urlWithCurrency := searchurl.Normalize(addCurrencyToURL(line))
+		validURLs = append(validURLs, urlWithCurrency)
+	}
 
-		for _, u := range validURLs {
-			rangeURLs, err := pricerange.GeneratePriceRangeURLs(u, pricerange.DefaultStep)
-			if err != nil {
-				log.Printf("Warning: Failed to generate price ranges for URL: %v\n", err)
-				expandedURLs = append(expandedURLs, u)
-				priceRangeLabels = append(priceRangeLabels, "")
-				continue
-			}
-			if len(rangeURLs) > 1 {
-				hasPriceRanges = true
-			}
-			for _, r := range rangeURLs {
-				expandedURLs = append(expandedURLs, r.URL)
-				priceRangeLabels = append(priceRangeLabels, r.Label)
-			}
-		}
+	if len(rejectedURLs) > 0 {
+		msg := tgbotapi.NewMessage(chatID, "⚠️ Skipped URL(s) that are not Airbnb searches:\n"+strings.Join(rejectedURLs, "\n")+
+			"\n\n"+searchURLGuidance)
+		msg.DisableWebPagePreview = true
+		bot.Send(msg)
+	}
 
-		// Send processing message
-		var processingText string
-		if hasPriceRanges {
-			processingText = fmt.Sprintf(
-				"📝 Request received! Splitting into %d price range steps ($%d increments) from %d URL(s).\n"+
-					"Your request has been queued and will be processed shortly.",
-				len(expandedURLs), pricerange.DefaultStep, totalOriginalURLs)
-		} else if len(expandedURLs) == 1 {
-			processingText = "📝 Request received! Your request has been queued and will be processed shortly. You'll receive status updates as the scraping progresses."
-		} else {
-			processingText = fmt.Sprintf("📝 Request received with %d links! Your request has been queued and will be processed shortly. Each link will be processed sequentially.", len(expandedURLs))
-		}
-		processingMsg := tgbotapi.NewMessage(update.Message.Chat.ID, processingText)
-		processingMsg.ReplyMarkup = configKeyboard
-		sentMsg, err := bot.Send(processingMsg)
-		if err != nil {
-			log.Printf("Error sending processing message: %v\n", err)
-			continue
+	// If no valid URLs found
+	if len(validURLs) == 0 {
+		if len(rejectedURLs) == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "Please send valid URLs starting with http:// or https://"))
 		}
+		return
+	}
 
-		// Store first original URL in request (for display)
-		allURLsJoined := strings.Join(validURLs, "\n")
+	// Warn about invalid lines if any
+	if len(invalidLines) > 0 {
+		warnMsg := fmt.Sprintf("⚠️ Skipped %d invalid line(s) that don't look like URLs.", len(invalidLines))
+		bot.Send(tgbotapi.NewMessage(chatID, warnMsg))
+	}
 
-		// Save request to database
-		req, err := database.CreateRequest(userID, sentMsg.MessageID, allURLsJoined)
-		if err != nil {
-			log.Printf("Error creating request: %v\n", err)
-			errorMsg := tgbotapi.NewEditMessageText(update.Message.Chat.ID, sentMsg.MessageID, fmt.Sprintf("❌ Error: Failed to create request: %v", err))
-			bot.Send(errorMsg)
-			continue
-		}
+	// Store all original URLs in request (for display and duplicate detection)
+	allURLsJoined := strings.Join(validURLs, "\n")
+
+	// Don't queue the same search twice while the first one is still pending
+	existingReq, err := database.FindActiveRequestByURL(userID, allURLsJoined)
+	if err != nil {
+		log.Printf("Warning: Failed to check for duplicate request: %v\n", err)
+	} else if existingReq != nil {
+		dupMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"♻️ This search is already queued as request #%d (status: %s). Updates will keep coming in reply to the original message.",
+			existingReq.ID, existingReq.Status))
+		dupMsg.ReplyToMessageID = existingReq.TelegramMessageID
+		dupMsg.ReplyMarkup = configKeyboard
+		bot.Send(dupMsg)
+		return
+	}
+
+	// Expand URLs into price range sub-URLs at the user's step
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Error loading config: %v", err)))
+		return
+	}
+	priceStep := userConfig.PriceStep
+	if priceStep <= 0 {
+		priceStep = pricerange.DefaultStep
+	}
+	var expandedURLs []string
+	var priceRangeLabels []string // parallel array: label for each expanded URL
+	totalOriginalURLs := len(validURLs)
+	hasPriceRanges := false
 
-		// Create search_links entries for each expanded URL
-		_, err = database.CreateSearchLinks(req.ID, expandedURLs)
+	for _, u := range validURLs {
+		rangeURLs, err := pricerange.GeneratePriceRangeURLs(u, priceStep)
 		if err != nil {
-			log.Printf("Error creating search links: %v\n", err)
-			errorMsg := tgbotapi.NewEditMessageText(update.Message.Chat.ID, sentMsg.MessageID, fmt.Sprintf("❌ Error: Failed to create search links: %v", err))
-			bot.Send(errorMsg)
+			log.Printf("Warning: Failed to generate price ranges for URL: %v\n", err)
+			expandedURLs = append(expandedURLs, u)
+			priceRangeLabels = append(priceRangeLabels, "")
 			continue
 		}
+		if len(rangeURLs) > 1 {
+			hasPriceRanges = true
+		}
+		for _, r := range rangeURLs {
+			expandedURLs = append(expandedURLs, r.URL)
+			priceRangeLabels = append(priceRangeLabels, r.Label)
+		}
+	}
+
+	// Splitting can create many links: show how big the run is and ask before queuing it
+	if hasPriceRanges && !confirmed {
+		askSearchConfirmation(bot, database, chatID, userID, pendingSearch{text: messageText, quick: quick},
+			len(expandedURLs), priceStep, userConfig)
+		return
+	}
+
+	// Send processing message
+	var processingText string
+	if hasPriceRanges {
+		processingText = fmt.Sprintf(
+			"📝 Request received! Splitting into %d price range steps ($%d increments) from %d URL(s).\n"+
+				"Your request has been queued and will be processed shortly.",
+			len(expandedURLs), priceStep, totalOriginalURLs)
+	} else if len(expandedURLs) == 1 {
+		processingText = "📝 Request received! Your request has been queued and will be processed shortly. You'll receive status updates as the scraping progresses."
+	} else {
+		processingText = fmt.Sprintf("📝 Request received with %d links! Your request has been queued and will be processed shortly. Each link will be processed sequentially.", len(expandedURLs))
+	}
+	if quick {
+		processingText += "\n⚡ Quick mode: detail pages are skipped, so descriptions, reviews and superhost info stay empty."
+	}
+	processingMsg := tgbotapi.NewMessage(chatID, processingText)
+	processingMsg.ReplyMarkup = configKeyboard
+	sentMsg, err := bot.Send(processingMsg)
+	if err != nil {
+		log.Printf("Error sending processing message: %v\n", err)
+		return
+	}
+
+	// Save request to database
+	req, err := database.CreateRequest(userID, sentMsg.MessageID, allURLsJoined, quick)
+	if err != nil {
+		log.Printf("Error creating request: %v\n", err)
+		errorMsg := tgbotapi.NewEditMessageText(chatID, sentMsg.MessageID, fmt.Sprintf("❌ Error: Failed to create request: %v", err))
+		bot.Send(errorMsg)
+		return
+	}
 
-		log.Printf("Created request ID %d for user %d with %d search links (from %d original URLs, price ranges: %v)\n",
-			req.ID, userID, len(expandedURLs), totalOriginalURLs, hasPriceRanges)
+	// Create search_links entries for each expanded URL
+	_, err = database.CreateSearchLinks(req.ID, expandedURLs)
+	if err != nil {
+		log.Printf("Error creating search links: %v\n", err)
+		errorMsg := tgbotapi.NewEditMessageText(chatID, sentMsg.MessageID, fmt.Sprintf("❌ Error: Failed to create search links: %v", err))
+		bot.Send(errorMsg)
+		return
 	}
+
+	log.Printf("Created request ID %d for user %d with %d search links (from %d original URLs, price ranges: %v)\n",
+		req.ID, userID, len(expandedURLs), totalOriginalURLs, hasPriceRanges)
 }
 
 // refreshEnvVars refreshes environment variables (Windows-specific)
@@ -834,10 +2755,15 @@ func fetchListings(url string, maxPages int, cfg *config.FilterConfig) ([]models
 			log.Printf("Warning: Failed to close browser: %v\n", err)
 		}
 	}()
-	fetcherInstance := fetcher.Fetcher(rodFetcher)
+	parserInstance := parser.NewParser()
+	fetcherInstance := fetcher.NewSearchFetcher(fetcher.StrategyFromEnv(), rodFetcher, parserInstance.HasListings)
 
 	// Fetch pages
 	htmlPages, err := fetcherInstance.Fetch(url, maxPages)
+	if errors.Is(err, fetcher.ErrNoListings) {
+		log.Println("Search returned no listings")
+		return nil, nil, nil
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("fetching failed: %w", err)
 	}
@@ -847,7 +2773,6 @@ func fetchListings(url string, maxPages int, cfg *config.FilterConfig) ([]models
 	}
 
 	// Parse listings
-	parserInstance := parser.NewParser()
 	var allListings []models.Listing
 
 	for i, html := range htmlPages {
@@ -875,52 +2800,9 @@ func fetchListings(url string, maxPages int, cfg *config.FilterConfig) ([]models
 	return filteredListings, allListings, nil
 }
 
-// formatListingsConsole formats listings for console output
+// formatListingsConsole formats listings for console output, with the same entries as Telegram
 func formatListingsConsole(listings []models.Listing) {
-	for i, listing := range listings {
-		fmt.Printf("\n%d. %s\n", i+1, listing.Title)
-
-		// Link
-		if listing.URL != "" {
-			fmt.Printf("   Link: %s\n", listing.URL)
-		}
-
-		// Price
-		if listing.Price > 0 {
-			currency := listing.Currency
-			if currency == "" {
-				currency = "THB" // Default fallback
-			}
-			// Format price with currency symbol
-			switch currency {
-			case "USD", "$":
-				fmt.Printf("   Price: $%.2f\n", listing.Price)
-			case "EUR", "€":
-				fmt.Printf("   Price: €%.2f\n", listing.Price)
-			case "THB", "฿":
-				fmt.Printf("   Price: ฿%.0f\n", listing.Price)
-			case "VND", "₫":
-				fmt.Printf("   Price: ₫%.0f\n", listing.Price)
-			case "GBP", "£":
-				fmt.Printf("   Price: £%.2f\n", listing.Price)
-			default:
-				fmt.Printf("   Price: %s %.2f\n", currency, listing.Price)
-			}
-		} else {
-			fmt.Printf("   Price: Not available\n")
-		}
-
-		// Rating (stars)
-		if listing.Stars > 0 {
-			// Display stars with full precision (no rounding)
-			fmt.Printf("   Rating: %g\n", listing.Stars)
-		}
-
-		// Review count
-		if listing.ReviewCount > 0 {
-			fmt.Printf("   Review count: %d\n", listing.ReviewCount)
-		}
-	}
+	fmt.Print("\n" + formatListingEntries(listings))
 }
 
 // formatListingsTelegram formats listings for Telegram message
@@ -937,8 +2819,16 @@ func formatListingsTelegram(filteredListings, allListings []models.Listing) stri
 
 	sb.WriteString("Filtered Listings:\n")
 	sb.WriteString("==================\n\n")
+	sb.WriteString(formatListingEntries(filteredListings))
+
+	return sb.String()
+}
+
+// formatListingEntries formats one numbered entry per listing for a Telegram message or the console
+func formatListingEntries(listings []models.Listing) string {
+	var sb strings.Builder
 
-	for i, listing := range filteredListings {
+	for i, listing := range listings {
 		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, listing.Title))
 
 		// Link
@@ -948,25 +2838,7 @@ func formatListingsTelegram(filteredListings, allListings []models.Listing) stri
 
 		// Price
 		if listing.Price > 0 {
-			currency := listing.Currency
-			if currency == "" {
-				currency = "THB" // Default fallback
-			}
-			// Format price with currency symbol
-			switch currency {
-			case "USD", "$":
-				sb.WriteString(fmt.Sprintf("   Price: $%.2f\n", listing.Price))
-			case "EUR", "€":
-				sb.WriteString(fmt.Sprintf("   Price: €%.2f\n", listing.Price))
-			case "THB", "฿":
-				sb.WriteString(fmt.Sprintf("   Price: ฿%.0f\n", listing.Price))
-			case "VND", "₫":
-				sb.WriteString(fmt.Sprintf("   Price: ₫%.0f\n", listing.Price))
-			case "GBP", "£":
-				sb.WriteString(fmt.Sprintf("   Price: £%.2f\n", listing.Price))
-			default:
-				sb.WriteString(fmt.Sprintf("   Price: %s %.2f\n", currency, listing.Price))
-			}
+			sb.WriteString(fmt.Sprintf("   Price: %s\n", currency.FormatPrice(listing.Price, listing.Currency)))
 		} else {
 			sb.WriteString("   Price: Not available\n")
 		}
@@ -1007,8 +2879,13 @@ func splitMessage(text string, maxLen int) []string {
 			// If a single line is too long, split it
 			if len(line) > maxLen {
 				for len(line) > maxLen {
-					parts = append(parts, line[:maxLen])
-					line = line[maxLen:]
+					// Cut at a rune boundary so every part stays valid UTF-8
+					cut := maxLen
+					for cut > 0 && !utf8.RuneStart(line[cut]) {
+						cut--
+					}
+					parts = append(parts, line[:cut])
+					line = line[cut:]
 				}
 				if len(line) > 0 {
 					current.WriteString(line)
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"bnb-fetcher/db"
+	"bnb-fetcher/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLen    int
+		wantParts int
+	}{
+		{"fits in one message", "line one\nline two", 100, 1},
+		{"splits at line breaks", strings.Repeat("0123456789\n", 10), 25, 5},
+		{"hard-splits an over-long line", strings.Repeat("a", 25), 10, 3},
+		{"hard-splits multi-byte runes", strings.Repeat("฿", 10), 10, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.text, tt.maxLen)
+			if len(parts) != tt.wantParts {
+				t.Errorf("splitMessage() returned %d parts, want %d: %q", len(parts), tt.wantParts, parts)
+			}
+
+			var joined strings.Builder
+			for i, part := range parts {
+				if len(part) > tt.maxLen {
+					t.Errorf("part %d is %d bytes, longer than %d", i, len(part), tt.maxLen)
+				}
+				if !utf8.ValidString(part) {
+					t.Errorf("part %d is not valid UTF-8: %q", i, part)
+				}
+				joined.WriteString(part)
+			}
+			// Line breaks may move between parts, but no text may be lost
+			strip := func(s string) string { return strings.ReplaceAll(s, "\n", "") }
+			if strip(joined.String()) != strip(tt.text) {
+				t.Errorf("parts %q do not add up to the input", parts)
+			}
+		})
+	}
+}
+
+func TestConfigExportRoundTrip(t *testing.T) {
+	original := &db.UserConfig{
+		MaxPages: 3, MaxTotalPages: 20, MaxListings: 50, MinReviews: 5,
+		MinPrice: 20, MaxPrice: 120.5, MinStars: 4.5,
+		SuperhostOnly: true, GuestFavoriteOnly: false, MaxMinimumNights: 7,
+		LocationContains: "Old Town", SortBy: "price_asc", DedupScope: "link", AutoWidenMin: 10, PriceStep: 25,
+		SpreadsheetPerRequest: true,
+	}
+
+	imported, err := parseConfigImport(db.ExportUserConfig(original), &db.UserConfig{})
+	if err != nil {
+		t.Fatalf("parseConfigImport() error = %v", err)
+	}
+	if *imported.MaxPages != 3 || *imported.MaxPrice != 120.5 || !*imported.SuperhostOnly ||
+		*imported.GuestFavoriteOnly || *imported.LocationContains != "Old Town" ||
+		*imported.SortBy != "price_asc" || *imported.DedupScope != "link" || *imported.AutoWidenMin != 10 || *imported.PriceStep != 25 ||
+		!*imported.SpreadsheetPerRequest {
+		t.Errorf("round trip changed the config: %s", db.ExportUserConfig(original))
+	}
+}
+
+func TestParseConfigImport(t *testing.T) {
+	current := &db.UserConfig{MinPrice: 0, MaxPrice: 100}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"partial config", `{"min_reviews": 20}`, false},
+		{"price range with current max", `{"min_price": 50}`, false},
+		{"malformed JSON", `{"min_reviews": 20`, true},
+		{"not an object", `min_reviews=20`, true},
+		{"unknown field", `{"min_reviewz": 20}`, true},
+		{"trailing data", `{"min_reviews": 20} {}`, true},
+		{"empty object", `{}`, true},
+		{"wrong type", `{"min_reviews": "20"}`, true},
+		{"zero max pages", `{"max_pages": 0}`, true},
+		{"max pages over the limit", `{"max_pages": 500}`, true},
+		{"negative count", `{"max_listings": -1}`, true},
+		{"zero price step", `{"price_step": 0}`, true},
+		{"stars out of range", `{"min_stars": 6}`, true},
+		{"min price above current max", `{"min_price": 150}`, true},
+		{"unknown sort option", `{"sort_by": "random"}`, true},
+		{"unknown dedup scope", `{"dedup_scope": "global"}`, true},
+		{"detail fields", `{"enrich_fields": "rooms,reviews"}`, false},
+		{"unknown detail field", `{"enrich_fields": "photos"}`, true},
+		{"negative price per guest", `{"max_price_per_guest": -5}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfigImport(tt.input, current)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseConfigImport(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPriceRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max float64
+		wantErr  bool
+	}{
+		{"valid range", 20, 100, false},
+		{"single price", 50, 50, false},
+		{"min above max", 150, 100, true},
+		{"negative min", -1, 100, true},
+	}
+
+	for _, tt := range tests {
+		if err := checkPriceRange(tt.min, tt.max); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkPriceRange(%v, %v) error = %v, wantErr %v", tt.name, tt.min, tt.max, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizePresetName(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Budget", "budget", false},
+		{"  premium stays ", "premium stays", false},
+		{"", "", true},
+		{"a|b", "", true},
+		{strings.Repeat("x", 33), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizePresetName(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("normalizePresetName(%q) = (%q, %v), want (%q, error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEstimateSearchDuration(t *testing.T) {
+	tests := []struct {
+		name          string
+		links         int
+		maxPages      int
+		maxTotalPages int
+		wantPages     int
+	}{
+		{"links times pages", 4, 5, 0, 20},
+		{"capped by page budget", 10, 5, 30, 30},
+		{"budget above total", 2, 3, 100, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, d := estimateSearchDuration(tt.links, tt.maxPages, tt.maxTotalPages, 30*time.Second)
+			if pages != tt.wantPages || d != time.Duration(tt.wantPages)*30*time.Second {
+				t.Errorf("estimateSearchDuration() = (%d, %s), want %d pages", pages, d, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestFormatEstimate(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{20 * time.Second, "under a minute"},
+		{45 * time.Minute, "45 min"},
+		{125 * time.Minute, "2h 05m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatEstimate(tt.d); got != tt.want {
+			t.Errorf("formatEstimate(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubscribeArgs(t *testing.T) {
+	const searchURL = "https://www.airbnb.com/s/Chiang-Mai/homes?adults=2"
+
+	tests := []struct {
+		name      string
+		args      string
+		wantRunAt string
+		wantEvery string
+		wantErr   bool
+	}{
+		{"default time", searchURL, defaultSavedSearchTime, db.RunDaily, false},
+		{"time after URL", searchURL + " 07:30", "07:30", db.RunDaily, false},
+		{"time before URL", "7:05 " + searchURL, "07:05", db.RunDaily, false},
+		{"daily with time", searchURL + " daily 07:30", "07:30", db.RunDaily, false},
+		{"hourly", searchURL + " Hourly", "", db.RunHourly, false},
+		{"hourly with time", searchURL + " hourly 07:30", "", "", true},
+		{"missing URL", "07:30", "", "", true},
+		{"invalid time", searchURL + " 25:00", "", "", true},
+		{"two URLs", searchURL + " " + searchURL, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotURL, gotRunAt, gotEvery, err := parseSubscribeArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubscribeArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// Hourly searches run relative to the previous run; their time is only informational
+			if gotURL != searchURL || gotEvery != tt.wantEvery || (tt.wantRunAt != "" && gotRunAt != tt.wantRunAt) {
+				t.Errorf("parseSubscribeArgs(%q) = (%q, %q, %q), want (%q, %q, %q)", tt.args, gotURL, gotRunAt, gotEvery, searchURL, tt.wantRunAt, tt.wantEvery)
+			}
+		})
+	}
+}
+
+func TestSheetsPageKeyboard(t *testing.T) {
+	tests := []struct {
+		name        string
+		page, pages int
+		want        []string // callback data of the buttons
+	}{
+		{"single page", 0, 1, nil},
+		{"first page", 0, 3, []string{"sheets|1"}},
+		{"middle page", 1, 3, []string{"sheets|0", "sheets|2"}},
+		{"last page", 2, 3, []string{"sheets|1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyboard := sheetsPageKeyboard(tt.page, tt.pages)
+			var got []string
+			if keyboard != nil {
+				for _, button := range keyboard.InlineKeyboard[0] {
+					got = append(got, *button.CallbackData)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sheetsPageKeyboard(%d, %d) buttons = %v, want %v", tt.page, tt.pages, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatStoredConfig(t *testing.T) {
+	text := formatStoredConfig(&db.UserConfig{UserID: 42, MaxPages: 5, SortBy: "price_asc", EnrichFields: "rooms"})
+
+	for _, want := range []string{"user_id: 42\n", "max_pages: 5\n", "sort_by: price_asc\n", "location_contains: \"\"\n", "enrich_fields: \"rooms\"\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatStoredConfig() is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatListingEntries(t *testing.T) {
+	text := formatListingEntries([]models.Listing{
+		{Title: "Tokyo flat", URL: "https://www.airbnb.com/rooms/1", Price: 12000, Currency: "¥", Stars: 4.87, ReviewCount: 12},
+		{Title: "No price"},
+	})
+
+	for _, want := range []string{"1. Tokyo flat\n", "   Price: ¥12000\n", "   Rating: 4.87\n", "2. No price\n", "   Price: Not available\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatListingEntries() is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatUserStats(t *testing.T) {
+	lastRequest := time.Date(2026, 5, 2, 9, 15, 0, 0, time.UTC)
+	text := formatUserStats(&db.UserStats{
+		Requests: 4, DoneRequests: 3, FailedRequests: 1, EmptyRequests: 1, Listings: 90, TopCurrency: "EUR",
+		LastRequestAt: sql.NullTime{Time: lastRequest, Valid: true},
+		LastEmptyAt:   sql.NullTime{Time: lastRequest, Valid: true},
+	})
+
+	for _, want := range []string{"Requests: 4 (3 done, 1 failed)\n", "Listings scraped: 90\n", "Average per request: 22.5\n",
+		"Most common currency: EUR\n", "Last request: 2026-05-02 09:15\n", "1 finished request(s) kept no listings, the latest on 2026-05-02"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatUserStats() is missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatUserStats(&db.UserStats{}); !strings.Contains(text, "No requests yet") {
+		t.Errorf("formatUserStats() without requests = %q", text)
+	}
+}
+
+func TestAddCurrencyToURLKeepsDates(t *testing.T) {
+	got := addCurrencyToURL("https://www.airbnb.com/s/Lisbon/homes?checkin=2026-06-01&checkout=2026-06-05&currency=EUR")
+	for _, want := range []string{"checkin=2026-06-01", "checkout=2026-06-05", "currency=USD"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("addCurrencyToURL() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestFormatFeedback(t *testing.T) {
+	from := &tgbotapi.User{ID: 42, UserName: "traveler"}
+	latest := &db.Request{ID: 12, Status: "done", ListingsCount: 30, CreatedAt: time.Date(2026, 5, 2, 9, 15, 0, 0, time.UTC)}
+
+	text := formatFeedback(7, from, latest, "Prices are empty", `{"max_pages":5}`)
+	for _, want := range []string{"Feedback #7 from user 42 (@traveler)", "\n\nPrices are empty\n\n",
+		"Latest request: #12 (done, 30 listings, 2026-05-02 09:15)\n", `Config: {"max_pages":5}`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatFeedback() is missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatFeedback(8, &tgbotapi.User{ID: 42}, nil, "Hi", ""); !strings.Contains(text, "Latest request: none") || strings.Contains(text, "Config:") {
+		t.Errorf("formatFeedback() without request or config = %q", text)
+	}
+}
+
+func TestParseForwardTarget(t *testing.T) {
+	const user, other = 1001, 1002
+	savedAllowed := allowedUserIDs
+	allowedUserIDs = map[int64]bool{user: true, other: true}
+	t.Cleanup(func() { allowedUserIDs = savedAllowed })
+
+	tests := []struct {
+		text    string
+		want    int64
+		wantErr bool
+	}{
+		{"1002", other, false},
+		{"off", 0, false},
+		{"OFF", 0, false},
+		{"1001", 0, true}, // the user themselves
+		{"123", 0, true},  // not allowed to use the bot
+		{"@someone", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseForwardTarget(tt.text, user)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseForwardTarget(%q) = %d, %v; want %d, error %t", tt.text, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
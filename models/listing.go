@@ -4,27 +4,59 @@ import "time"
 
 // Listing represents a Bnb listing
 type Listing struct {
-	Title       string
-	Price       float64
-	Currency    string // Currency symbol/code (฿, $, €, ₫, etc.)
-	Stars       float64
-	ReviewCount int
-	URL         string
+	Title           string
+	Price           float64
+	Currency        string // Currency symbol/code (฿, $, €, ₫, etc.)
+	Stars           float64
+	ReviewCount     int
+	URL             string
+	Location        string      // Neighborhood/city from the card subtitle, e.g. "Chiang Mai, Thailand"
+	DiscountPercent float64     // From the card's "x% off" badge, 0 if there is none
+	Badges          []string    // Badge labels shown on the card, e.g. "Rare find", "Guest favorite"
+	ThumbnailURL    string      // First photo of the card, the largest size offered; empty if none was found
 	PageNumber      int         // Page number where this listing was found
 	LinkNumber      int         // Which search link this listing came from (1-based, for multi-link requests)
 	PriceRangeLabel string      // Price range label (e.g., "$0-$50") for price range scanning
 	AllPrices       []PriceInfo // For debugging: all prices found
 
 	// Detail page fields
+	Enriched         bool // Detail page was fetched and parsed; detail fields are unknown otherwise
 	IsSuperhost      bool
 	IsGuestFavorite  bool
 	Bedrooms         float64
 	Bathrooms        float64
 	Beds             float64
+	MaxGuests        int
+	MinNights        int    // Minimum stay in nights, 0 if there is none or it is unknown
+	CheckIn          string // Check-in time as shown, e.g. "3:00 PM" or "3:00 PM - 10:00 PM"
+	CheckOut         string // Check-out time as shown, e.g. "11:00 AM"
+	SelfCheckIn      bool
 	Description      string
 	HouseRules       string
 	NewestReviewDate *time.Time
 	Reviews          []Review
+
+	// Computed fields
+	ActivityScore float64 // Review volume decayed by age of the newest review (see scoring.ActivityScore)
+	PriceUSD      float64 // Price converted to USD, 0 if the currency has no known rate
+}
+
+// PricePerGuestUSD returns the nightly USD price divided by the guest capacity,
+// or 0 when either the USD price or the capacity is unknown
+func (l Listing) PricePerGuestUSD() float64 {
+	if l.PriceUSD <= 0 || l.MaxGuests <= 0 {
+		return 0
+	}
+	return l.PriceUSD / float64(l.MaxGuests)
+}
+
+// PricePerBedroomUSD returns the nightly USD price divided by the number of bedrooms, or 0
+// when either the USD price or the bedroom count is unknown (studios have 0 bedrooms)
+func (l Listing) PricePerBedroomUSD() float64 {
+	if l.PriceUSD <= 0 || l.Bedrooms <= 0 {
+		return 0
+	}
+	return l.PriceUSD / l.Bedrooms
 }
 
 // PriceInfo represents a price found in the listing
@@ -42,4 +74,8 @@ type Review struct {
 	Score        float64
 	FullText     string
 	TimeOnAirbnb string // How long the user has been on Airbnb
+	// Who wrote the review and where they're from, as shown in the review header.
+	// Empty when the page doesn't show them.
+	ReviewerName     string
+	ReviewerLocation string
 }
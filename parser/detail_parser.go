@@ -3,25 +3,89 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
 	"bnb-fetcher/models"
+	"bnb-fetcher/sanitize"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
+// Detail field groups that can be left out of ParseDetailPage to save time
+const (
+	DetailRooms       = "rooms"       // bedrooms, bathrooms, beds and guest capacity
+	DetailReviews     = "reviews"     // reviews and the newest review date (the slowest)
+	DetailSuperhost   = "superhost"   // superhost and guest favorite badges
+	DetailRules       = "rules"       // house rules, check-in/check-out times and self check-in
+	DetailDescription = "description" // listing description
+)
+
+// AllDetailFields lists every detail field group in display order
+var AllDetailFields = []string{DetailRooms, DetailReviews, DetailSuperhost, DetailRules, DetailDescription}
+
+// DetailFields is a set of detail field groups. A nil set selects all of them.
+type DetailFields map[string]bool
+
+// Has reports whether the group is selected
+func (f DetailFields) Has(field string) bool {
+	return f == nil || f[field]
+}
+
+// ParseDetailFields parses a comma-separated list of field groups. An empty string
+// selects all of them and returns nil; a list without any names is an error.
+func ParseDetailFields(text string) (DetailFields, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	fields := make(DetailFields)
+	for _, name := range strings.Split(text, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(AllDetailFields, name) {
+			return nil, fmt.Errorf("unknown detail field %q (expected %s)", name, strings.Join(AllDetailFields, ", "))
+		}
+		fields[name] = true
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no detail fields given")
+	}
+	return fields, nil
+}
+
+// String returns the selected groups comma-separated in AllDetailFields order, or ""
+// when all of them are selected
+func (f DetailFields) String() string {
+	var names []string
+	for _, field := range AllDetailFields {
+		if f.Has(field) {
+			names = append(names, field)
+		}
+	}
+	if len(names) == len(AllDetailFields) {
+		return ""
+	}
+	return strings.Join(names, ",")
+}
+
 // DetailParser extracts detailed information from listing detail pages
-type DetailParser struct{}
+type DetailParser struct {
+	// Fields selects the field groups to extract; nil extracts all of them. Fields of
+	// groups left out stay zero. The minimum stay is always extracted.
+	Fields DetailFields
+}
 
 // NewDetailParser creates a new DetailParser instance
 func NewDetailParser() *DetailParser {
 	return &DetailParser{}
 }
 
-// ParseDetailPage extracts all detail information from a listing detail page
+// ParseDetailPage extracts the selected detail information from a listing detail page
 func (dp *DetailParser) ParseDetailPage(htmlContent string) (*models.Listing, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
@@ -30,26 +94,49 @@ func (dp *DetailParser) ParseDetailPage(htmlContent string) (*models.Listing, er
 
 	listing := &models.Listing{}
 
-	// Extract is_superhost
-	listing.IsSuperhost = dp.extractSuperhost(doc)
+	if dp.Fields.Has(DetailSuperhost) {
+		// Extract is_superhost
+		listing.IsSuperhost = dp.extractSuperhost(doc)
+
+		// Extract is_guest_favorite
+		listing.IsGuestFavorite = dp.extractGuestFavorite(doc)
+	}
+
+	if dp.Fields.Has(DetailRooms) {
+		// Extract bedrooms, bathrooms, beds
+		listing.Bedrooms, listing.Bathrooms, listing.Beds = dp.extractRoomCounts(doc)
 
-	// Extract is_guest_favorite
-	listing.IsGuestFavorite = dp.extractGuestFavorite(doc)
+		// Extract guest capacity
+		listing.MaxGuests = dp.extractMaxGuests(doc)
+	}
 
-	// Extract bedrooms, bathrooms, beds
-	listing.Bedrooms, listing.Bathrooms, listing.Beds = dp.extractRoomCounts(doc)
+	// Extract minimum stay
+	listing.MinNights = dp.extractMinNights(doc)
 
-	// Extract description
-	listing.Description = dp.extractDescription(doc)
+	// Extract location, for cards that didn't show it
+	listing.Location = dp.extractLocation(doc)
 
-	// Extract house rules
-	listing.HouseRules = dp.extractHouseRules(doc)
+	if dp.Fields.Has(DetailRules) {
+		// Extract check-in/check-out times
+		listing.CheckIn, listing.CheckOut = dp.extractCheckInOut(doc)
+		listing.SelfCheckIn = dp.extractSelfCheckIn(doc)
 
-	// Extract reviews
-	reviews, newestDate := dp.extractReviews(doc)
-	listing.Reviews = reviews
-	if newestDate != nil {
-		listing.NewestReviewDate = newestDate
+		// Extract house rules
+		listing.HouseRules = dp.extractHouseRules(doc)
+	}
+
+	if dp.Fields.Has(DetailDescription) {
+		// Extract description
+		listing.Description = dp.extractDescription(doc)
+	}
+
+	if dp.Fields.Has(DetailReviews) {
+		// Extract reviews
+		reviews, newestDate := dp.extractReviews(doc)
+		listing.Reviews = reviews
+		if newestDate != nil {
+			listing.NewestReviewDate = newestDate
+		}
 	}
 
 	return listing, nil
@@ -386,6 +473,148 @@ func (dp *DetailParser) extractRoomCounts(doc *goquery.Document) (bedrooms, bath
 	return bedrooms, bathrooms, beds
 }
 
+// extractMaxGuests extracts the guest capacity ("4 guests", "16+ guests"), falling back to
+// personCapacity in embedded JSON. Returns 0 if not found.
+func (dp *DetailParser) extractMaxGuests(doc *goquery.Document) int {
+	isValidGuestCount := func(val int) bool {
+		return val > 0 && val <= 50 // Sanity check: reject numbers that are clearly not a capacity
+	}
+
+	// Adjacent elements run together in doc.Text() ("4 guests2 bedrooms"), so only
+	// require that no letter follows
+	guestPattern := regexp.MustCompile(`(?i)\b(\d+)\+?\s*guests?(?:[^a-z]|$)`)
+	if matches := guestPattern.FindStringSubmatch(normalizeWhitespace(doc.Text())); len(matches) > 1 {
+		if val, err := strconv.Atoi(matches[1]); err == nil && isValidGuestCount(val) {
+			return val
+		}
+	}
+
+	capacityPattern := regexp.MustCompile(`"personCapacity"\s*:\s*(\d+)`)
+	var maxGuests int
+	doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		matches := capacityPattern.FindStringSubmatch(s.Text())
+		if len(matches) > 1 {
+			if val, err := strconv.Atoi(matches[1]); err == nil && isValidGuestCount(val) {
+				maxGuests = val
+				return false
+			}
+		}
+		return true
+	})
+	return maxGuests
+}
+
+// extractMinNights extracts the minimum stay from the booking sidebar or house rules
+// ("7 night minimum", "Minimum stay: 3 nights"), falling back to minNights in embedded
+// JSON. Returns 0 for "No minimum" or when not found.
+func (dp *DetailParser) extractMinNights(doc *goquery.Document) int {
+	isValidNights := func(val int) bool {
+		return val > 1 && val <= 365 // A 1 night minimum is no restriction
+	}
+
+	text := normalizeWhitespace(doc.Text())
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(\d+)[\s-]*nights?\s+minimum`),
+		regexp.MustCompile(`(?i)minimum\s+(?:stay|nights?)?\s*(?:is|of|:)?\s*(\d+)\s*nights?`),
+	}
+	for _, pattern := range patterns {
+		if matches := pattern.FindStringSubmatch(text); len(matches) > 1 {
+			if val, err := strconv.Atoi(matches[1]); err == nil && isValidNights(val) {
+				return val
+			}
+		}
+	}
+
+	minNightsPattern := regexp.MustCompile(`"minNights"\s*:\s*(\d+)`)
+	var minNights int
+	doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		matches := minNightsPattern.FindStringSubmatch(s.Text())
+		if len(matches) > 1 {
+			if val, err := strconv.Atoi(matches[1]); err == nil && isValidNights(val) {
+				minNights = val
+				return false
+			}
+		}
+		return true
+	})
+	return minNights
+}
+
+// extractLocation extracts the area from the overview heading ("Entire rental unit in
+// Sukhumvit, Bangkok, Thailand" -> "Sukhumvit, Bangkok, Thailand"), falling back to the
+// place line of the "Where you'll be" section ("Bangkok, Thailand"). Returns "" when
+// neither is found.
+func (dp *DetailParser) extractLocation(doc *goquery.Document) string {
+	location := ""
+	doc.Find("[data-section-id='OVERVIEW_DEFAULT_V2'] h2, [data-section-id*='OVERVIEW'] h2").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		location = parseLocation(s.Text())
+		return location == ""
+	})
+	if location != "" {
+		return location
+	}
+
+	// The section also holds a free text description of the neighborhood, so only short
+	// leaf lines that look like "Area, City, Country" are taken
+	doc.Find("[data-section-id*='LOCATION'] h3, [data-section-id*='LOCATION'] div, [data-section-id*='LOCATION'] span").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if s.Children().Length() > 0 {
+			return true
+		}
+		text := normalizeWhitespace(s.Text())
+		if len(text) <= 80 && strings.Contains(text, ",") && !strings.ContainsAny(text, "0123456789") &&
+			!strings.Contains(strings.ToLower(text), " in ") {
+			location = strings.Trim(text, " ,.")
+		}
+		return location == ""
+	})
+	return location
+}
+
+// timePattern matches a clock time such as "3:00 PM", "3 pm", "15:00" or "11 a.m."
+const timePattern = `\d{1,2}(?::\d{2}\s*(?:[ap]\.?m\.?)?|\s*[ap]\.?m\.?)`
+
+// extractCheckInOut extracts the check-in and check-out times from the house rules
+// ("Check-in after 3:00 PM", "Check-in: 2:00 PM - 8:00 PM", "Checkout before 11:00 AM").
+// A check-in without a time but described as flexible is returned as "Flexible".
+// Returns empty strings for times not found.
+func (dp *DetailParser) extractCheckInOut(doc *goquery.Document) (checkIn, checkOut string) {
+	text := normalizeWhitespace(doc.Text())
+
+	checkInPattern := regexp.MustCompile(`(?i)check[\s-]?in(?:\s+time)?\s*(?::|after|from|is|between)?\s*(` +
+		timePattern + `(?:\s*(?:-|–|to|and)\s*` + timePattern + `)?)`)
+	if matches := checkInPattern.FindStringSubmatch(text); len(matches) > 1 {
+		checkIn = normalizeTimeRange(matches[1])
+	} else if regexp.MustCompile(`(?i)flexible\s+check[\s-]?in`).MatchString(text) {
+		checkIn = "Flexible"
+	}
+
+	checkOutPattern := regexp.MustCompile(`(?i)check[\s-]?out(?:\s+time)?\s*(?::|before|by|until|is)?\s*(` + timePattern + `)`)
+	if matches := checkOutPattern.FindStringSubmatch(text); len(matches) > 1 {
+		checkOut = normalizeTimeRange(matches[1])
+	}
+
+	return checkIn, checkOut
+}
+
+// normalizeTimeRange tidies a matched time or time range: "2:00pm–8:00pm" -> "2:00 PM - 8:00 PM"
+func normalizeTimeRange(text string) string {
+	separator := regexp.MustCompile(`\s*(?:-|–|\bto\b|\band\b)\s*`)
+	meridiem := regexp.MustCompile(`(?i)\s*([ap])\.?m\.?$`)
+
+	parts := separator.Split(strings.TrimSpace(text), -1)
+	for i, part := range parts {
+		parts[i] = meridiem.ReplaceAllStringFunc(part, func(m string) string {
+			return " " + strings.ToUpper(meridiem.FindStringSubmatch(m)[1]) + "M"
+		})
+	}
+	return strings.Join(parts, " - ")
+}
+
+// extractSelfCheckIn reports whether the listing offers self check-in
+func (dp *DetailParser) extractSelfCheckIn(doc *goquery.Document) bool {
+	return regexp.MustCompile(`(?i)self[\s-]check[\s-]?in`).MatchString(doc.Text())
+}
+
 // extractDescription extracts the listing description
 func (dp *DetailParser) extractDescription(doc *goquery.Document) string {
 	// Common selectors for description
@@ -456,9 +685,7 @@ func (dp *DetailParser) extractHouseRules(doc *goquery.Document) string {
 				if len(rules) > 20 {
 					foundRules = strings.TrimSpace(rules)
 					// Limit length to avoid getting too much
-					if len(foundRules) > 500 {
-						foundRules = foundRules[:500] + "..."
-					}
+					foundRules = sanitize.Truncate(foundRules, 500)
 				}
 			}
 		}
@@ -595,14 +822,15 @@ func (dp *DetailParser) extractSingleReview(s *goquery.Selection) *models.Review
 		// Try to get text from the selection itself
 		review.FullText = strings.TrimSpace(s.Text())
 		// Limit length
-		if len(review.FullText) > 5000 {
-			review.FullText = review.FullText[:5000] + "..."
-		}
+		review.FullText = sanitize.Truncate(review.FullText, 5000)
 	}
 
 	// Extract time on Airbnb
 	review.TimeOnAirbnb = dp.extractTimeOnAirbnb(s)
 
+	// Extract who wrote it
+	review.ReviewerName, review.ReviewerLocation = dp.extractReviewer(s)
+
 	// Only return review if it has at least text or score
 	if review.FullText == "" && review.Score == 0 {
 		return nil
@@ -737,6 +965,53 @@ func (dp *DetailParser) extractTimeOnAirbnb(s *goquery.Selection) string {
 	return ""
 }
 
+// reviewerFieldMaxLength bounds the reviewer name and location: longer header text is
+// the review itself or a section heading, not a name or a place
+const reviewerFieldMaxLength = 80
+
+// extractReviewer extracts the reviewer's name and location from the review header block:
+// the name heading, followed by a line with where they live. Either is "" when missing;
+// reviewers without a location get "N years on Airbnb" there instead.
+func (dp *DetailParser) extractReviewer(s *goquery.Selection) (name string, location string) {
+	nameSelectors := []string{
+		"[data-testid='review-author']",
+		"[data-testid*='reviewer-name']",
+		"h3",
+		"h2",
+	}
+
+	var nameElem *goquery.Selection
+	for _, selector := range nameSelectors {
+		elem := s.Find(selector).First()
+		text := normalizeWhitespace(elem.Text())
+		if isReviewerField(text) && !strings.Contains(strings.ToLower(text), "review") {
+			name, nameElem = text, elem
+			break
+		}
+	}
+
+	locationElem := s.Find("[data-testid='reviewer-location'], [data-testid*='reviewer-location']").First()
+	if locationElem.Length() == 0 && nameElem != nil {
+		// The location is the line right below the name. Outside the name's block comes
+		// the rating or the review itself, so a name without a sibling has no location.
+		locationElem = nameElem.Next()
+	}
+	text := normalizeWhitespace(locationElem.Text())
+	if isReviewerField(text) && !reviewerDetailPattern.MatchString(text) {
+		location = text
+	}
+	return name, location
+}
+
+// reviewerDetailPattern matches header lines that aren't a location: time on Airbnb,
+// review dates and stay details
+var reviewerDetailPattern = regexp.MustCompile(`(?i)\d|on airbnb|joined|member since|ago|stayed|review`)
+
+// isReviewerField reports whether header text can be a reviewer name or location
+func isReviewerField(text string) bool {
+	return text != "" && len([]rune(text)) <= reviewerFieldMaxLength
+}
+
 // parseDate parses various date formats including relative dates
 func (dp *DetailParser) parseDate(dateStr string) (time.Time, error) {
 	// Handle relative dates like "2 months ago", "3 weeks ago"
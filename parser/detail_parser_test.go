@@ -283,3 +283,259 @@ func TestNormalizeWhitespace(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractMaxGuests(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected int
+	}{
+		{"overview line", `<body><ol><li>4 guests</li><li>2 bedrooms</li></ol></body>`, 4},
+		{"single guest", `<body>1 guest · 1 bed · 1 bath</body>`, 1},
+		{"capped capacity", `<body>16+ guests</body>`, 16},
+		{"json fallback", `<body><script>{"personCapacity":6}</script></body>`, 6},
+		{"not found", `<body>Lovely flat</body>`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			parser := NewDetailParser()
+			if got := parser.extractMaxGuests(doc); got != tt.expected {
+				t.Errorf("extractMaxGuests() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractMinNights(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected int
+	}{
+		{"sidebar minimum", `<body><div>7 night minimum</div></body>`, 7},
+		{"hyphenated", `<body>This place has a 30-night minimum</body>`, 30},
+		{"house rules", `<body><h2>House rules</h2><div>Check-in after 3:00 PM</div><div>Minimum stay: 3 nights</div></body>`, 3},
+		{"json fallback", `<body><script>{"minNights":5}</script></body>`, 5},
+		{"no minimum", `<body>No minimum stay</body>`, 0},
+		{"one night is no restriction", `<body>1 night minimum</body>`, 0},
+		{"not found", `<body>Lovely flat</body>`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			parser := NewDetailParser()
+			if got := parser.extractMinNights(doc); got != tt.expected {
+				t.Errorf("extractMinNights() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractCheckInOut(t *testing.T) {
+	tests := []struct {
+		name         string
+		html         string
+		wantCheckIn  string
+		wantCheckOut string
+	}{
+		{"after and before", `<body><div>Check-in after 3:00 PM</div><div>Checkout before 11:00 AM</div></body>`, "3:00 PM", "11:00 AM"},
+		{"range", `<body><div>Check-in: 2:00 PM - 8:00 PM</div><div>Check out: 10:00 AM</div></body>`, "2:00 PM - 8:00 PM", "10:00 AM"},
+		{"en dash and lowercase", `<body>Check-in 2pm–8pm · Checkout 11am</body>`, "2 PM - 8 PM", "11 AM"},
+		{"24-hour clock", `<body><li>Check-in from 15:00</li><li>Check-out until 11:00</li></body>`, "15:00", "11:00"},
+		{"run-together elements", `<body><div>Check-in after 4:00 PM</div><div>Checkout before 10:00 AM</div></body>`, "4:00 PM", "10:00 AM"},
+		{"flexible check-in", `<body>Flexible check-in · Checkout before 12:00 PM</body>`, "Flexible", "12:00 PM"},
+		{"not found", `<body>Lovely flat</body>`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			parser := NewDetailParser()
+			checkIn, checkOut := parser.extractCheckInOut(doc)
+			if checkIn != tt.wantCheckIn {
+				t.Errorf("extractCheckInOut() check-in = %q, want %q", checkIn, tt.wantCheckIn)
+			}
+			if checkOut != tt.wantCheckOut {
+				t.Errorf("extractCheckInOut() check-out = %q, want %q", checkOut, tt.wantCheckOut)
+			}
+		})
+	}
+}
+
+func TestExtractSelfCheckIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected bool
+	}{
+		{"self check-in", `<body><h3>Self check-in</h3><div>Check yourself in with the keypad.</div></body>`, true},
+		{"hyphenated", `<body>Self-check-in with lockbox</body>`, true},
+		{"host greets", `<body>Check-in after 3:00 PM</body>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			parser := NewDetailParser()
+			if got := parser.extractSelfCheckIn(doc); got != tt.expected {
+				t.Errorf("extractSelfCheckIn() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDetailFields(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string // String() of the result
+		wantErr bool
+	}{
+		{"", "", false},
+		{"rooms, Superhost", "rooms,superhost", false},
+		{"description,rooms,reviews,superhost,rules", "", false},
+		{"rooms,photos", "", true},
+		{" , ", "", true},
+	}
+
+	for _, tt := range tests {
+		fields, err := ParseDetailFields(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDetailFields(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got := fields.String(); !tt.wantErr && got != tt.want {
+			t.Errorf("ParseDetailFields(%q).String() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseDetailPageSkipsUnselectedFields(t *testing.T) {
+	html := `<body><span>Superhost</span><div>2 bedrooms · 3 beds</div><h2>About this place</h2><div>A quiet loft.</div></body>`
+
+	parser := NewDetailParser()
+	parser.Fields = DetailFields{DetailRooms: true}
+	listing, err := parser.ParseDetailPage(html)
+	if err != nil {
+		t.Fatalf("ParseDetailPage() error = %v", err)
+	}
+	if listing.Bedrooms != 2 {
+		t.Errorf("Bedrooms = %v, want 2", listing.Bedrooms)
+	}
+	if listing.IsSuperhost || listing.Description != "" {
+		t.Errorf("unselected fields were extracted: superhost %v, description %q", listing.IsSuperhost, listing.Description)
+	}
+}
+
+func TestExtractDetailLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			"overview heading",
+			`<div data-section-id="OVERVIEW_DEFAULT_V2"><h2>Entire rental unit in Sukhumvit, Bangkok, Thailand</h2></div>`,
+			"Sukhumvit, Bangkok, Thailand",
+		},
+		{
+			"private room heading",
+			`<div data-section-id="OVERVIEW_DEFAULT_V2"><h2>Private room in Bangkok</h2></div>`,
+			"Bangkok",
+		},
+		{
+			"where you'll be section",
+			`<div data-section-id="LOCATION_DEFAULT"><h2>Where you'll be</h2><div><div>Located in the heart of the city, near 3 malls, cafes.</div><div>Chiang Mai, Thailand</div></div></div>`,
+			"Chiang Mai, Thailand",
+		},
+		{"no location", `<div><h2>Entire home</h2></div>`, ""},
+	}
+
+	dp := NewDetailParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dp.extractLocation(doc); got != tt.want {
+				t.Errorf("extractLocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSingleReviewReviewer(t *testing.T) {
+	tests := []struct {
+		name         string
+		html         string
+		wantName     string
+		wantLocation string
+	}{
+		{
+			"name and location",
+			`<div data-review-id="1"><div><h3>Maria</h3><div>Lisbon, Portugal</div></div>` +
+				`<div><span aria-label="Rating, 5 stars">★★★★★</span><span>March 2026</span></div>` +
+				`<p>Lovely flat, spotless and close to everything.</p></div>`,
+			"Maria", "Lisbon, Portugal",
+		},
+		{
+			"time on Airbnb instead of location",
+			`<div data-review-id="2"><div><h3>Tom</h3><div>6 years on Airbnb</div></div>` +
+				`<p>Great host, would stay again any time.</p></div>`,
+			"Tom", "",
+		},
+		{
+			"test ids",
+			`<div data-review-id="3"><span data-testid="review-author">Aiko</span>` +
+				`<span data-testid="reviewer-location">Osaka, Japan</span><p>Quiet street, comfortable beds.</p></div>`,
+			"Aiko", "Osaka, Japan",
+		},
+		{
+			"short review text after the name's block",
+			`<div data-review-id="5"><div><h3>Lena</h3></div><p>Great stay!</p></div>`,
+			"Lena", "",
+		},
+		{
+			"no header",
+			`<div data-review-id="4"><p>Nice place, friendly host and easy check-in.</p></div>`,
+			"", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			parser := NewDetailParser()
+			review := parser.extractSingleReview(doc.Find("[data-review-id]").First())
+			if review == nil {
+				t.Fatal("extractSingleReview() = nil")
+			}
+			if review.ReviewerName != tt.wantName || review.ReviewerLocation != tt.wantLocation {
+				t.Errorf("reviewer = %q from %q, want %q from %q", review.ReviewerName, review.ReviewerLocation, tt.wantName, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -7,17 +7,30 @@ import (
 	"strconv"
 	"strings"
 
+	"bnb-fetcher/currency"
 	"bnb-fetcher/models"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 // Parser extracts listing data from HTML
-type Parser struct{}
+type Parser struct {
+	// IncludeSimilarDates keeps listings shown under "Available for similar dates",
+	// which are dropped by default
+	IncludeSimilarDates bool
+
+	prices *pricePatterns // built from the currency symbols known when the parser is created
+}
 
 // NewParser creates a new Parser instance
 func NewParser() *Parser {
-	return &Parser{}
+	return &Parser{prices: newPricePatterns()}
+}
+
+// HasListings reports whether the HTML parses to at least one listing
+func (p *Parser) HasListings(htmlContent string) bool {
+	listings, err := p.ParseHTML(htmlContent)
+	return err == nil && len(listings) > 0
 }
 
 // ParseHTML extracts listings from HTML content
@@ -34,7 +47,7 @@ func (p *Parser) ParseHTML(htmlContent string) ([]models.Listing, error) {
 	// Try common selectors - these may need adjustment based on actual HTML structure
 	doc.Find("[data-testid='listing-card'], ._14n5tpj, [itemprop='itemListElement']").Each(func(i int, s *goquery.Selection) {
 		// Check if this listing is in "Available for similar dates" section
-		if p.isInSimilarDatesSection(s) {
+		if !p.IncludeSimilarDates && p.isInSimilarDatesSection(s) {
 			return
 		}
 		// Check if this listing is inside a div with the specific background color style
@@ -51,7 +64,7 @@ func (p *Parser) ParseHTML(htmlContent string) ([]models.Listing, error) {
 	if len(listings) == 0 {
 		doc.Find("div[data-listing-id], a[href*='/rooms/']").Each(func(i int, s *goquery.Selection) {
 			// Check if this listing is in "Available for similar dates" section
-			if p.isInSimilarDatesSection(s) {
+			if !p.IncludeSimilarDates && p.isInSimilarDatesSection(s) {
 				return
 			}
 			// Check if this listing is inside a div with the specific background color style
@@ -69,35 +82,21 @@ func (p *Parser) ParseHTML(htmlContent string) ([]models.Listing, error) {
 	return listings, nil
 }
 
-// isInSimilarDatesSection checks if a listing is within "Available for similar dates" section
+// isInSimilarDatesSection checks if a listing is within "Available for similar dates" section.
+// The section starts at its heading, so a listing is in it when the heading comes before
+// the listing (or one of its ancestors) among its siblings.
 func (p *Parser) isInSimilarDatesSection(s *goquery.Selection) bool {
-	// Check parent elements for "Available for similar dates" text
-	// Only filter if we find a clear section heading
-	parent := s.Parent()
+	node := s
 	for i := 0; i < 10; i++ { // Check up to 10 levels up
+		if hasSimilarDatesHeading(node.PrevAll()) {
+			return true
+		}
+
+		parent := node.Parent()
 		if parent.Length() == 0 {
 			break
 		}
 
-		// Look for headings that clearly indicate "similar dates" section
-		// Check siblings and parent's previous siblings for section headers
-		parent.Find("h1, h2, h3, h4").Each(func(i int, heading *goquery.Selection) {
-			headingText := strings.ToLower(strings.TrimSpace(heading.Text()))
-			// Only match exact phrases about similar dates in headings
-			if (headingText == "available for similar dates" ||
-				strings.HasPrefix(headingText, "available for similar dates") ||
-				headingText == "similar dates") &&
-				len(headingText) < 50 { // Heading should be short and specific
-				// Found a clear heading - check if this listing is in that section
-				// by checking if the heading is a sibling or parent of this listing's container
-				// This logic needs to be more robust to ensure the listing is actually *under* this heading
-				// For now, a simple check for the heading's presence in a parent is enough to be lenient.
-				// A more precise check would involve DOM traversal to ensure the listing is a descendant of the section.
-				// For now, we return true if a relevant heading is found in an ancestor.
-				return // This return is for the Each loop, not the function
-			}
-		})
-
 		// Also check if parent itself is a section with "similar" in class or data attribute
 		parentClass := strings.ToLower(parent.AttrOr("class", ""))
 		parentDataTestId := strings.ToLower(parent.AttrOr("data-testid", ""))
@@ -109,11 +108,26 @@ func (p *Parser) isInSimilarDatesSection(s *goquery.Selection) bool {
 			}
 		}
 
-		parent = parent.Parent()
+		node = parent
 	}
 	return false
 }
 
+// hasSimilarDatesHeading reports whether any of the elements is, or contains, a
+// "similar dates" section heading
+func hasSimilarDatesHeading(elements *goquery.Selection) bool {
+	headings := elements.Filter("h1, h2, h3, h4").AddSelection(elements.Find("h1, h2, h3, h4"))
+	found := false
+	headings.EachWithBreak(func(i int, heading *goquery.Selection) bool {
+		headingText := strings.ToLower(strings.TrimSpace(heading.Text()))
+		// Only match exact phrases about similar dates in short headings
+		found = (headingText == "similar dates" || strings.HasPrefix(headingText, "available for similar dates")) &&
+			len(headingText) < 50
+		return !found
+	})
+	return found
+}
+
 // isInFullBleedSection checks if a listing is within a div with the specific background color style
 func (p *Parser) isInFullBleedSection(s *goquery.Selection) bool {
 	// Check parent elements for the specific style attribute
@@ -167,6 +181,12 @@ func (p *Parser) extractListing(s *goquery.Selection) *models.Listing {
 	}
 	listing.AllPrices = allPrices // Always populate AllPrices for debugging
 
+	// Extract discount badge ("20% off") near the price
+	listing.DiscountPercent = p.extractDiscountFromListing(s, fullText)
+
+	// Extract scarcity and status badges ("Rare find", "Guest favorite")
+	listing.Badges = p.extractBadges(s)
+
 	// Extract star rating - try multiple approaches
 	starText := s.Find("[data-testid='listing-card-rating'], span[class*='rating'], div[class*='rating'], span[aria-label*='star']").First().Text()
 	if starText == "" {
@@ -195,6 +215,12 @@ func (p *Parser) extractListing(s *goquery.Selection) *models.Listing {
 		}
 	}
 
+	// Extract location from the card subtitle ("Entire home in Chiang Mai, Thailand")
+	listing.Location = p.extractLocation(s)
+
+	// Extract the first photo of the card for the sheet's thumbnail column
+	listing.ThumbnailURL = p.extractThumbnail(s)
+
 	// Only return listing if it has at least a title or URL
 	if listing.Title != "" || listing.URL != "" {
 		return listing
@@ -203,58 +229,133 @@ func (p *Parser) extractListing(s *goquery.Selection) *models.Listing {
 	return nil
 }
 
+// discountRegex matches a discount badge such as "20% off" or "15 % OFF"
+var discountRegex = regexp.MustCompile(`(?i)(\d{1,2}(?:\.\d+)?)\s*%\s*off\b`)
+
+// extractDiscountFromListing returns the percentage of the card's discount badge, 0 if
+// there is none. The badge sits next to the price, so the price container's parent is
+// searched before the whole card.
+func (p *Parser) extractDiscountFromListing(s *goquery.Selection, fullText string) float64 {
+	priceContainer := s.Find("[data-testid='listing-card-price'], [data-testid='price'], ._tyxjp1, ._1jo4hgw").First()
+	if priceContainer.Length() > 0 {
+		if discount := p.extractDiscount(priceContainer.Parent().Text()); discount > 0 {
+			return discount
+		}
+	}
+	return p.extractDiscount(fullText)
+}
+
+// extractDiscount extracts the percentage from "x% off" text, 0 if there is none
+func (p *Parser) extractDiscount(text string) float64 {
+	matches := discountRegex.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return 0
+	}
+	discount, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0
+	}
+	return discount
+}
+
+// badgeSelector matches the badge overlays of a listing card
+const badgeSelector = "[data-testid='listing-card-badge'], [data-testid*='badge'], [class*='badge'], [class*='Badge']"
+
+// maxBadgeLength drops matches that are whole card sections rather than a badge label
+const maxBadgeLength = 40
+
+// extractBadges returns the visible badge labels of a card in page order, without
+// duplicates. Labels are kept as shown so new badges need no code change. Only the
+// innermost badge elements are read, so a wrapper around two badges doesn't merge them.
+func (p *Parser) extractBadges(s *goquery.Selection) []string {
+	var badges []string
+	seen := make(map[string]bool)
+	s.Find(badgeSelector).Each(func(i int, badge *goquery.Selection) {
+		if badge.Find(badgeSelector).Length() > 0 {
+			return
+		}
+		text := strings.Join(strings.Fields(badge.Text()), " ")
+		if text == "" || len(text) > maxBadgeLength || seen[strings.ToLower(text)] {
+			return
+		}
+		seen[strings.ToLower(text)] = true
+		badges = append(badges, text)
+	})
+	return badges
+}
+
+// pricePatterns are the price patterns around the currency symbols and codes of the
+// currency package, which include those added with CURRENCY_MAP_PATH. Alternatives are
+// longest first, so "R$100" matches R$ rather than $.
+type pricePatterns struct {
+	// Pattern 1: Currency symbol at start: "$100", "฿1,000", "₫37,748,822"
+	symbolPrefix *regexp.Regexp
+	// Pattern 2: Currency symbol or code at end: "1000 ฿", "1000THB", "37,748,822 ₫"
+	symbolSuffix *regexp.Regexp
+	// Pattern 3: Currency code with space: "100 USD", "1000 THB"
+	codeSuffix *regexp.Regexp
+}
+
+// newPricePatterns builds the price patterns for the currencies known now
+func newPricePatterns() *pricePatterns {
+	symbolPattern := alternation(currency.Symbols())
+	codePattern := alternation(currency.Codes())
+	return &pricePatterns{
+		symbolPrefix: regexp.MustCompile(`(` + symbolPattern + `)\s*` + amountPattern),
+		symbolSuffix: regexp.MustCompile(amountPattern + `\s*(` + symbolPattern + `|` + codePattern + `)`),
+		codeSuffix:   regexp.MustCompile(amountPattern + `\s+(` + codePattern + `)`),
+	}
+}
+
+// amountPattern matches an amount with optional thousands separators and decimals,
+// e.g. the Vietnamese Dong amount in "₫37,748,822"
+const amountPattern = `([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)`
+
+// alternation returns a regexp group matching any of the literal values, in order
+func alternation(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return "(?:" + strings.Join(quoted, "|") + ")"
+}
+
+// pricePatterns returns the parser's price patterns, built for the currencies known now
+// when the Parser was not made by NewParser
+func (p *Parser) pricePatterns() *pricePatterns {
+	if p.prices == nil {
+		return newPricePatterns()
+	}
+	return p.prices
+}
+
 // extractPrice extracts price and currency from text
 // Returns (price, currency)
 func (p *Parser) extractPrice(text string) (float64, string) {
-	// Map of currency symbols to currency codes
-	currencyMap := map[string]string{
-		"$":   "USD",
-		"€":   "EUR",
-		"£":   "GBP",
-		"¥":   "JPY",
-		"฿":   "THB",
-		"₫":   "VND",
-		"USD": "USD",
-		"EUR": "EUR",
-		"GBP": "GBP",
-		"THB": "THB",
-		"VND": "VND",
-	}
+	prices := p.pricePatterns()
 
-	// Pattern 1: Currency symbol at start: "$100", "฿1,000", "₫37,748,822"
-	// Handle Vietnamese Dong with commas: ₫37,748,822
-	re := regexp.MustCompile(`([\$€£¥฿₫])\s*([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)`)
-	matches := re.FindStringSubmatch(text)
+	// Pattern 1: Currency symbol at start
+	matches := prices.symbolPrefix.FindStringSubmatch(text)
 	if len(matches) >= 3 {
 		currencySymbol := matches[1]
 		priceStr := strings.ReplaceAll(strings.ReplaceAll(matches[2], ",", ""), " ", "")
 		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-			currency := currencyMap[currencySymbol]
-			if currency == "" {
-				currency = currencySymbol
-			}
-			return price, currency
+			return price, currency.Code(currencySymbol)
 		}
 	}
 
-	// Pattern 2: Currency symbol at end: "1000 ฿", "1000 THB", "37,748,822 ₫"
-	re = regexp.MustCompile(`([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)\s*([\$€£¥฿₫]|USD|EUR|GBP|THB|VND)`)
-	matches = re.FindStringSubmatch(text)
+	// Pattern 2: Currency symbol or code at end
+	matches = prices.symbolSuffix.FindStringSubmatch(text)
 	if len(matches) >= 3 {
 		priceStr := strings.ReplaceAll(strings.ReplaceAll(matches[1], ",", ""), " ", "")
 		currencySymbol := strings.TrimSpace(matches[2])
 		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-			currency := currencyMap[currencySymbol]
-			if currency == "" {
-				currency = currencySymbol
-			}
-			return price, currency
+			return price, currency.Code(currencySymbol)
 		}
 	}
 
-	// Pattern 3: Currency code with space: "100 USD", "1000 THB"
-	re = regexp.MustCompile(`([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)\s+(USD|EUR|GBP|THB|VND)`)
-	matches = re.FindStringSubmatch(text)
+	// Pattern 3: Currency code with space
+	matches = prices.codeSuffix.FindStringSubmatch(text)
 	if len(matches) >= 3 {
 		priceStr := strings.ReplaceAll(strings.ReplaceAll(matches[1], ",", ""), " ", "")
 		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
@@ -263,7 +364,7 @@ func (p *Parser) extractPrice(text string) (float64, string) {
 	}
 
 	// Pattern 4: With "per night" or similar text (no explicit currency symbol, assume default)
-	re = regexp.MustCompile(`([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)\s*(?:per|/|night)`)
+	re := regexp.MustCompile(`([\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?)\s*(?:per|/|night)`)
 	matches = re.FindStringSubmatch(text)
 	if len(matches) >= 2 {
 		priceStr := strings.ReplaceAll(strings.ReplaceAll(matches[1], ",", ""), " ", "")
@@ -281,15 +382,23 @@ func (p *Parser) extractPrice(text string) (float64, string) {
 func (p *Parser) extractPriceFromListing(s *goquery.Selection, fullText string) (float64, string, []models.PriceInfo) {
 	var priceElements []*goquery.Selection
 	seenTexts := make(map[string]bool)
+	// A currency symbol followed by an amount, e.g. "$120" or "₱1,500" for a symbol from CURRENCY_MAP_PATH
+	priceRegex := p.pricePatterns().symbolPrefix
 
 	// First, try to find a specific price container
 	priceContainer := s.Find("[data-testid='listing-card-price'], [data-testid='price'], ._tyxjp1, ._1jo4hgw").First()
 
 	// If we found a price container, look for all price elements within it
 	if priceContainer.Length() > 0 {
+		// Add the container itself first: its text starts with the original price when
+		// discounted ("$150 $120"), so its children must come later to win the selection
+		text := strings.TrimSpace(priceContainer.Text())
+		if len(text) > 0 && priceRegex.MatchString(text) {
+			seenTexts[text] = true
+			priceElements = append(priceElements, priceContainer)
+		}
 		// Look for all child elements that might contain prices
-		priceRegex := regexp.MustCompile(`[\$€£¥฿₫]\s*[\d]`)
-		priceContainer.Find("span, div").Each(func(i int, elem *goquery.Selection) {
+		priceContainer.Find("span, div, " + strikeTags).Each(func(i int, elem *goquery.Selection) {
 			text := strings.TrimSpace(elem.Text())
 			// Check if this element contains a price pattern
 			if len(text) > 0 && priceRegex.MatchString(text) {
@@ -299,20 +408,11 @@ func (p *Parser) extractPriceFromListing(s *goquery.Selection, fullText string)
 				}
 			}
 		})
-		// Also add the container itself if it has price text
-		text := strings.TrimSpace(priceContainer.Text())
-		if len(text) > 0 && priceRegex.MatchString(text) {
-			if !seenTexts[text] {
-				seenTexts[text] = true
-				priceElements = append(priceElements, priceContainer)
-			}
-		}
 	}
 
 	// Also search for any element containing price patterns in the entire listing card
 	// This is a fallback if specific price containers don't yield results or to catch other patterns
-	priceRegex := regexp.MustCompile(`[\$€£¥฿₫]\s*[\d]{1,3}(?:[,\s]\d{3})*(?:\.[\d]+)?`)
-	s.Find("span, div, b, strong").Each(func(i int, elem *goquery.Selection) {
+	s.Find("span, div, b, strong, " + strikeTags).Each(func(i int, elem *goquery.Selection) {
 		text := strings.TrimSpace(elem.Text())
 		// Only consider elements with short text (price elements are usually short)
 		if len(text) > 0 && len(text) < 50 && priceRegex.MatchString(text) {
@@ -328,7 +428,19 @@ func (p *Parser) extractPriceFromListing(s *goquery.Selection, fullText string)
 	var allPricesInfo []models.PriceInfo
 	for i, elem := range priceElements {
 		isStrike := p.isStrikethrough(elem)
-		price, currency := p.extractPrice(elem.Text())
+		text := elem.Text()
+		if !isStrike {
+			// Skip struck-through prices nested inside the element ("<span><s>$200</s> $150</span>");
+			// if nothing priced is left, the element only shows an original price
+			if current := textWithoutStrikethrough(elem); current != text {
+				if price, _ := p.extractPrice(current); price > 0 {
+					text = current
+				} else {
+					isStrike = true
+				}
+			}
+		}
+		price, currency := p.extractPrice(text)
 		if price > 0 {
 			allPricesInfo = append(allPricesInfo, models.PriceInfo{
 				Price:    price,
@@ -384,10 +496,26 @@ func (p *Parser) extractPriceFromListing(s *goquery.Selection, fullText string)
 	return 0, "", allPricesInfo
 }
 
+// strikeTags are the HTML elements that render their content struck through
+const strikeTags = "s, strike, del"
+
+// strikethroughSelector matches elements whose content is shown struck through
+const strikethroughSelector = strikeTags + ", [class*='strike'], [class*='line-through'], [class*='linethrough'], [style*='line-through']"
+
+// textWithoutStrikethrough returns the element's text leaving out struck-through descendants
+func textWithoutStrikethrough(s *goquery.Selection) string {
+	if s.Find(strikethroughSelector).Length() == 0 {
+		return s.Text()
+	}
+	clone := s.Clone()
+	clone.Find(strikethroughSelector).Remove()
+	return clone.Text()
+}
+
 // isStrikethrough checks if an element has strikethrough styling
 func (p *Parser) isStrikethrough(s *goquery.Selection) bool {
 	// Check for strikethrough tags
-	if s.Is("s, strike, del") {
+	if s.Is(strikeTags) {
 		return true
 	}
 
@@ -460,3 +588,97 @@ func (p *Parser) extractReviewCount(text string) string {
 	}
 	return ""
 }
+
+// extractLocation returns the location from the card's title/subtitle lines, trying each
+// until one looks like a location
+func (p *Parser) extractLocation(s *goquery.Selection) string {
+	location := ""
+	s.Find("[data-testid='listing-card-title'], [data-testid='listing-card-subtitle']").EachWithBreak(func(i int, line *goquery.Selection) bool {
+		location = parseLocation(line.Text())
+		return location == ""
+	})
+	return location
+}
+
+// extractThumbnail returns the URL of the card's first photo, or "" if it has none.
+// Cards offer several sizes in srcset; the largest one is used so the thumbnail stays
+// sharp when the sheet row is enlarged. Photos outside the viewport are lazy-loaded: the
+// real URL is in data-srcset/data-src while src holds an inline placeholder, so only
+// http(s) URLs are accepted.
+func (p *Parser) extractThumbnail(s *goquery.Selection) string {
+	url := ""
+	s.Find("img, picture source").EachWithBreak(func(i int, img *goquery.Selection) bool {
+		for _, attr := range []string{"srcset", "data-srcset"} {
+			if url = largestSrcsetURL(img.AttrOr(attr, "")); url != "" {
+				return false
+			}
+		}
+		for _, attr := range []string{"data-src", "data-original-src", "src"} {
+			if url = imageURL(img.AttrOr(attr, "")); url != "" {
+				return false
+			}
+		}
+		return true
+	})
+	return url
+}
+
+// largestSrcsetURL returns the candidate of a srcset attribute with the largest width
+// ("640w") or density ("2x") descriptor. Candidates without a descriptor count as 1x.
+func largestSrcsetURL(srcset string) string {
+	best, bestSize := "", 0.0
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		url := imageURL(fields[0])
+		if url == "" {
+			continue
+		}
+		// A srcset uses either widths or densities, so the numbers compare directly
+		size := 1.0
+		if len(fields) > 1 {
+			if value, err := strconv.ParseFloat(strings.TrimRight(strings.ToLower(fields[1]), "wx"), 64); err == nil {
+				size = value
+			}
+		}
+		if best == "" || size > bestSize {
+			best, bestSize = url, size
+		}
+	}
+	return best
+}
+
+// imageURL returns src as an absolute image URL, or "" for placeholders such as inline
+// data: URIs and empty or relative values
+func imageURL(src string) string {
+	src = strings.TrimSpace(src)
+	if strings.HasPrefix(src, "//") {
+		src = "https:" + src
+	}
+	if !strings.HasPrefix(src, "https://") && !strings.HasPrefix(src, "http://") {
+		return ""
+	}
+	return src
+}
+
+// parseLocation extracts the location from a card subtitle. It strips the property-type
+// prefix ("Entire home in Chiang Mai, Thailand" -> "Chiang Mai, Thailand") and anything
+// after a "·" separator. Lines without "in" are only accepted when they look like a
+// place ("Old Town, Chiang Mai"): they contain a comma and no digits.
+func parseLocation(text string) string {
+	text = normalizeWhitespace(text)
+	if idx := strings.Index(text, "·"); idx >= 0 {
+		text = strings.TrimSpace(text[:idx])
+	}
+
+	if idx := strings.LastIndex(strings.ToLower(text), " in "); idx >= 0 {
+		return strings.Trim(text[idx+len(" in "):], " ,.")
+	}
+
+	if strings.Contains(text, ",") && !strings.ContainsAny(text, "0123456789") {
+		return strings.Trim(text, " ,.")
+	}
+	return ""
+}
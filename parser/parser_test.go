@@ -0,0 +1,479 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"bnb-fetcher/currency"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"entire home with country", "Entire home in Chiang Mai, Thailand", "Chiang Mai, Thailand"},
+		{"room without country", "Room in Old Town", "Old Town"},
+		{"multi-word property type", "Private room in rental unit in Hoi An", "Hoi An"},
+		{"lowercase type", "apartment in Nimman, Chiang Mai", "Nimman, Chiang Mai"},
+		{"trailing details", "Condo in Bangkok · 2 beds", "Bangkok"},
+		{"non-breaking spaces", "Home in Da Lat, Vietnam", "Da Lat, Vietnam"},
+		{"place only", "Old Town, Chiang Mai", "Old Town, Chiang Mai"},
+		{"listing name", "Cozy loft near the river", ""},
+		{"room counts", "2 beds, 1 bath", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLocation(tt.input); got != tt.expected {
+				t.Errorf("parseLocation(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseHTMLLocation(t *testing.T) {
+	html := `<body>
+		<div data-testid="listing-card">
+			<div data-testid="listing-card-title">Cozy loft near the river</div>
+			<div data-testid="listing-card-subtitle">Entire loft in Chiang Mai, Thailand</div>
+			<a href="/rooms/1">Cozy loft</a>
+		</div>
+		<div data-testid="listing-card">
+			<div data-testid="listing-card-title">Home in Old Town</div>
+			<a href="/rooms/2">Old Town house</a>
+		</div>
+	</body>`
+
+	listings, err := NewParser().ParseHTML(html)
+	if err != nil {
+		t.Fatalf("ParseHTML() error = %v", err)
+	}
+	if len(listings) != 2 {
+		t.Fatalf("ParseHTML() returned %d listings, want 2", len(listings))
+	}
+	want := []string{"Chiang Mai, Thailand", "Old Town"}
+	for i, listing := range listings {
+		if listing.Location != want[i] {
+			t.Errorf("listing %d Location = %q, want %q", i, listing.Location, want[i])
+		}
+	}
+}
+
+func TestExtractPrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantPrice    float64
+		wantCurrency string
+	}{
+		// Pattern 1: currency symbol before the amount
+		{"dollar", "$100", 100, "USD"},
+		{"dollar with night", "$85 night", 85, "USD"},
+		{"euro with space and decimals", "€ 85.50", 85.5, "EUR"},
+		{"pound", "£1,200 total", 1200, "GBP"},
+		{"baht with comma", "฿1,250 / night", 1250, "THB"},
+		{"dong with commas", "₫37,748,822", 37748822, "VND"},
+		{"first price wins", "$150 $120 night", 150, "USD"},
+
+		// Pattern 2: currency symbol or code after the amount
+		{"baht suffix", "1,000 ฿", 1000, "THB"},
+		{"dong suffix", "37,748,822 ₫", 37748822, "VND"},
+		{"code suffix without space", "1,500THB", 1500, "THB"},
+
+		// Pattern 3: currency code after a space (also matched by pattern 2)
+		{"code suffix", "100 USD", 100, "USD"},
+		{"code suffix with comma", "2,400 VND per night", 2400, "VND"},
+
+		// Pattern 4: no currency, amount before "per"/"night"
+		{"per night", "120 per night", 120, ""},
+		{"slash night", "1,500 / night", 1500, ""},
+
+		// No price
+		{"empty", "", 0, ""},
+		{"no price", "Entire home in Chiang Mai", 0, ""},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, currency := p.extractPrice(tt.input)
+			if price != tt.wantPrice || currency != tt.wantCurrency {
+				t.Errorf("extractPrice(%q) = (%v, %q), want (%v, %q)", tt.input, price, currency, tt.wantPrice, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestExtractPriceFromListing(t *testing.T) {
+	tests := []struct {
+		name         string
+		html         string
+		wantPrice    float64
+		wantCurrency string
+	}{
+		{
+			"single price",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span>$120</span> night</div></div>`,
+			120, "USD",
+		},
+		{
+			"discount in price container",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span style="text-decoration: line-through">$150</span> <span>$120</span> night</div></div>`,
+			120, "USD",
+		},
+		{
+			"discount with strike tag",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span><del>฿2,000</del></span><span>฿1,600</span></div></div>`,
+			1600, "THB",
+		},
+		{
+			"discount with strikethrough class, no container",
+			`<div data-testid="listing-card"><div><span class="price-strikethrough">₫1,200,000</span> <span>₫950,000</span></div></div>`,
+			950000, "VND",
+		},
+		{
+			"all prices struck through",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span class="strike">$90</span></div></div>`,
+			90, "USD",
+		},
+		{
+			"full text fallback",
+			`<div data-testid="listing-card"><p>120 per night</p></div>`,
+			120, "",
+		},
+		{
+			"no price",
+			`<div data-testid="listing-card"><p>Entire home</p></div>`,
+			0, "",
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			card := doc.Find("[data-testid='listing-card']")
+
+			price, currency, _ := p.extractPriceFromListing(card, card.Text())
+			if price != tt.wantPrice || currency != tt.wantCurrency {
+				t.Errorf("extractPriceFromListing() = (%v, %q), want (%v, %q)", price, currency, tt.wantPrice, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestExtractStars(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"stars suffix", "4.85 stars", "4.85"},
+		{"star glyph", "4.9 ★", "4.9"},
+		{"emoji", "4.7⭐", "4.7"},
+		{"slash five", "4.5/5", "4.5"},
+		{"out of five", "Rated 4.92 out of 5", "4.92"},
+		{"aria label", "4.88 out of 5 average rating, 120 reviews", "4.88"},
+		{"integer rating", "5 stars", "5"},
+		{"no rating", "New", ""},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.extractStars(tt.input); got != tt.expected {
+				t.Errorf("extractStars(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractReviewCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"reviews", "123 reviews", "123"},
+		{"single review", "1 review", "1"},
+		{"parenthesized reviews", "(45 reviews)", "45"},
+		{"thousands separator", "1,234 reviews", "1234"},
+		{"parentheses only", "4.9 (87)", "87"},
+		{"parentheses with comma", "4.95 (1,024)", "1024"},
+		{"rating then reviews", "4.88 out of 5 average rating, 120 reviews", "120"},
+		{"no reviews", "New", ""},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.extractReviewCount(tt.input); got != tt.expected {
+				t.Errorf("extractReviewCount(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractPriceFromListingStrikethrough(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		wantPrice float64
+	}{
+		{
+			"original before current",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span><s>$200</s></span> <span>$150</span> night</div></div>`,
+			150,
+		},
+		{
+			"original after current",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span>$150</span> <span><s>$200</s></span> night</div></div>`,
+			150,
+		},
+		{
+			"bare strike tag without price container",
+			`<div data-testid="listing-card"><div><s>$200</s> <span>$150</span> night</div></div>`,
+			150,
+		},
+		{
+			"strikethrough class a few levels up",
+			`<div data-testid="listing-card"><div class="price-strikethrough"><div><div><span>$200</span></div></div></div><span>$150</span></div>`,
+			150,
+		},
+		{
+			"both strikethrough falls back to the last",
+			`<div data-testid="listing-card"><div><s>$200</s> <s>$150</s> night</div></div>`,
+			150,
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			card := doc.Find("[data-testid='listing-card']")
+
+			price, _, allPrices := p.extractPriceFromListing(card, card.Text())
+			if price != tt.wantPrice {
+				t.Errorf("extractPriceFromListing() price = %v, want %v (all prices: %+v)", price, tt.wantPrice, allPrices)
+			}
+			for _, info := range allPrices {
+				if info.Price == 200 && !info.IsStrike {
+					t.Errorf("original price %q not marked as strikethrough", info.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractPriceFromListingMappedCurrency(t *testing.T) {
+	// ₱ is only known from a currency map (CURRENCY_MAP_PATH)
+	currency.Configure(currency.Settings{SymbolCodes: map[string]string{"$": "USD", "₱": "PHP"}})
+	defer currency.Configure(currency.Settings{})
+
+	html := `<div data-testid="listing-card"><div data-testid="listing-card-price"><span><s>₱2,000</s></span> <span>₱1,500</span> night</div></div>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	card := doc.Find("[data-testid='listing-card']")
+
+	p := NewParser()
+	price, currencyCode, allPrices := p.extractPriceFromListing(card, card.Text())
+	if price != 1500 || currencyCode != "PHP" {
+		t.Errorf("extractPriceFromListing() = %v %s, want 1500 PHP (all prices: %+v)", price, currencyCode, allPrices)
+	}
+}
+
+func TestParseHTMLSimilarDates(t *testing.T) {
+	html := `<body><main>
+		<div>
+			<div data-testid="listing-card"><a href="/rooms/1">Exact match one</a></div>
+			<div data-testid="listing-card"><a href="/rooms/2">Exact match two</a></div>
+		</div>
+		<div>
+			<h2>Available for similar dates</h2>
+			<div>
+				<div data-testid="listing-card"><a href="/rooms/3">Similar dates one</a></div>
+			</div>
+		</div>
+		<div data-testid="listing-card"><a href="/rooms/4">Similar dates two</a></div>
+	</main></body>`
+
+	tests := []struct {
+		name                string
+		includeSimilarDates bool
+		wantURLs            []string
+	}{
+		{"drops similar dates by default", false, []string{"/rooms/1", "/rooms/2"}},
+		{"keeps similar dates when enabled", true, []string{"/rooms/1", "/rooms/2", "/rooms/3", "/rooms/4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser()
+			p.IncludeSimilarDates = tt.includeSimilarDates
+			listings, err := p.ParseHTML(html)
+			if err != nil {
+				t.Fatalf("ParseHTML() error = %v", err)
+			}
+			if len(listings) != len(tt.wantURLs) {
+				t.Fatalf("ParseHTML() returned %d listings, want %d", len(listings), len(tt.wantURLs))
+			}
+			for i, listing := range listings {
+				if !strings.HasSuffix(listing.URL, tt.wantURLs[i]) {
+					t.Errorf("listing %d URL = %q, want suffix %q", i, listing.URL, tt.wantURLs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractDiscountFromListing(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want float64
+	}{
+		{
+			"badge next to the price",
+			`<div data-testid="listing-card"><div><span>20% off</span><div data-testid="listing-card-price"><s>$150</s> <span>$120</span></div></div></div>`,
+			20,
+		},
+		{
+			"uppercase badge with space",
+			`<div data-testid="listing-card"><p>Cozy loft</p><span>15 % OFF</span></div>`,
+			15,
+		},
+		{
+			"no badge",
+			`<div data-testid="listing-card"><div data-testid="listing-card-price"><span>$120</span> night</div></div>`,
+			0,
+		},
+		{
+			"percent without off",
+			`<div data-testid="listing-card"><p>100% of guests recommend</p></div>`,
+			0,
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			card := doc.Find("[data-testid='listing-card']")
+
+			if got := p.extractDiscountFromListing(card, card.Text()); got != tt.want {
+				t.Errorf("extractDiscountFromListing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractBadges(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			"rare find badge",
+			`<div data-testid="listing-card"><div data-testid="listing-card-badge"><span>Rare find</span></div><p>Cozy loft</p></div>`,
+			[]string{"Rare find"},
+		},
+		{
+			"two badges in one wrapper",
+			`<div data-testid="listing-card"><div class="badges"><div class="badge-pill">Guest favorite</div><div class="badge-pill">In high demand</div></div></div>`,
+			[]string{"Guest favorite", "In high demand"},
+		},
+		{
+			"no badges",
+			`<div data-testid="listing-card"><p>Cozy loft</p><div data-testid="listing-card-price"><span>$120</span> night</div></div>`,
+			nil,
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			card := doc.Find("[data-testid='listing-card']")
+
+			if got := p.extractBadges(card); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("extractBadges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractThumbnail(t *testing.T) {
+	const photo = "https://a0.muscache.com/im/pictures/miso/Hosting-1/original/abc.jpeg"
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			"largest srcset width",
+			`<div data-testid="listing-card"><picture><source srcset="` + photo + `?im_w=320 320w, ` + photo + `?im_w=1200 1200w, ` + photo + `?im_w=720 720w"><img src="` + photo + `?im_w=320"></picture></div>`,
+			photo + "?im_w=1200",
+		},
+		{
+			"largest srcset density",
+			`<div data-testid="listing-card"><img srcset="` + photo + `?im_w=720 1x, ` + photo + `?im_w=1440 2x"></div>`,
+			photo + "?im_w=1440",
+		},
+		{
+			"lazy image behind a placeholder",
+			`<div data-testid="listing-card"><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-src="` + photo + `?im_w=720"></div>`,
+			photo + "?im_w=720",
+		},
+		{
+			"protocol-relative src",
+			`<div data-testid="listing-card"><img src="//a0.muscache.com/im/pictures/abc.jpeg"></div>`,
+			"https://a0.muscache.com/im/pictures/abc.jpeg",
+		},
+		{
+			"placeholder only",
+			`<div data-testid="listing-card"><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="><p>Cozy loft</p></div>`,
+			"",
+		},
+		{
+			"no image",
+			`<div data-testid="listing-card"><p>Cozy loft</p></div>`,
+			"",
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			card := doc.Find("[data-testid='listing-card']")
+
+			if got := p.extractThumbnail(card); got != tt.want {
+				t.Errorf("extractThumbnail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -144,9 +144,68 @@ func ExtractPriceRangeLabel(urlStr string) string {
 		priceMax, _ = strconv.Atoi(priceMaxStr)
 	}
 
+	if priceMaxStr == "" {
+		return fmt.Sprintf("$%d+", priceMin)
+	}
+
 	return fmt.Sprintf("$%d-$%d", priceMin, priceMax)
 }
 
+// WidenURL returns the URL without its price_max, so the search also covers listings above
+// the original price range. ok is false when the URL has no price_max to drop.
+func WidenURL(urlStr string) (widened string, ok bool, err error) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	query := parsedURL.Query()
+	if query.Get("price_max") == "" {
+		return urlStr, false, nil
+	}
+	query.Del("price_max")
+
+	// Drop the matching entry from the filter order list too
+	for _, k := range []string{"selected_filter_order[]", "selected_filter_order%5B%5D"} {
+		values, found := query[k]
+		if !found {
+			continue
+		}
+		var filtered []string
+		for _, val := range values {
+			if !strings.HasPrefix(val, "price_max:") {
+				filtered = append(filtered, val)
+			}
+		}
+		query[k] = filtered
+	}
+
+	parsedURL.RawQuery = query.Encode()
+	return parsedURL.String(), true, nil
+}
+
+// CountURLRanges returns how many links GeneratePriceRangeURLs splits the URL into
+// at the given step: one per step between price_min and price_max, or 1 without price_max
+func CountURLRanges(urlStr string, step int) int {
+	if step <= 0 {
+		step = DefaultStep
+	}
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return 1
+	}
+	query := parsedURL.Query()
+	priceMax, err := strconv.Atoi(query.Get("price_max"))
+	if err != nil {
+		return 1
+	}
+	priceMin, err := strconv.Atoi(query.Get("price_min"))
+	if err != nil {
+		priceMin = 0
+	}
+	return CountRanges(priceMin, priceMax, step)
+}
+
 // CountRanges returns how many $step ranges fit between priceMin and priceMax
 func CountRanges(priceMin, priceMax, step int) int {
 	if step <= 0 || priceMax <= priceMin {
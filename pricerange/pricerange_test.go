@@ -0,0 +1,93 @@
+package pricerange
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestWidenURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantOK    bool
+		wantQuery url.Values
+	}{
+		{
+			"drops price_max and keeps price_min",
+			"https://www.airbnb.com/s/Chiang-Mai/homes?adults=2&price_min=50&price_max=100",
+			true,
+			url.Values{"adults": {"2"}, "price_min": {"50"}},
+		},
+		{
+			"drops price_max from the filter order",
+			"https://www.airbnb.com/s/homes?price_max=100&selected_filter_order[]=price_min:0&selected_filter_order[]=price_max:100",
+			true,
+			url.Values{"selected_filter_order[]": {"price_min:0"}},
+		},
+		{
+			"no price_max",
+			"https://www.airbnb.com/s/homes?adults=2&price_min=50",
+			false,
+			url.Values{"adults": {"2"}, "price_min": {"50"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			widened, ok, err := WidenURL(tt.input)
+			if err != nil {
+				t.Fatalf("WidenURL() error = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("WidenURL() ok = %v, want %v", ok, tt.wantOK)
+			}
+			parsed, err := url.Parse(widened)
+			if err != nil {
+				t.Fatalf("WidenURL() returned invalid URL %q: %v", widened, err)
+			}
+			if got := parsed.Query(); got.Encode() != tt.wantQuery.Encode() {
+				t.Errorf("WidenURL() query = %q, want %q", got.Encode(), tt.wantQuery.Encode())
+			}
+		})
+	}
+}
+
+func TestExtractPriceRangeLabel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.airbnb.com/s/homes?price_min=50&price_max=100", "$50-$100"},
+		{"https://www.airbnb.com/s/homes?price_max=100", "$0-$100"},
+		{"https://www.airbnb.com/s/homes?price_min=50", "$50+"},
+		{"https://www.airbnb.com/s/homes", "all prices"},
+	}
+
+	for _, tt := range tests {
+		if got := ExtractPriceRangeLabel(tt.input); got != tt.expected {
+			t.Errorf("ExtractPriceRangeLabel(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCountURLRangesMatchesGeneratedURLs(t *testing.T) {
+	urls := []string{
+		"https://www.airbnb.com/s/homes?price_min=0&price_max=200",
+		"https://www.airbnb.com/s/homes?price_min=30&price_max=175",
+		"https://www.airbnb.com/s/homes?price_max=60",
+		"https://www.airbnb.com/s/homes?price_min=100&price_max=100",
+		"https://www.airbnb.com/s/homes",
+	}
+
+	for _, u := range urls {
+		for _, step := range []int{25, 50, 100, 200} {
+			generated, err := GeneratePriceRangeURLs(u, step)
+			if err != nil {
+				t.Fatalf("GeneratePriceRangeURLs(%q, %d) error = %v", u, step, err)
+			}
+			if got := CountURLRanges(u, step); got != len(generated) {
+				t.Errorf("CountURLRanges(%q, %d) = %d, want %d", u, step, got, len(generated))
+			}
+		}
+	}
+}
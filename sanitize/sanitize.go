@@ -0,0 +1,99 @@
+// Package sanitize cleans scraped free text (descriptions, house rules, titles) before it
+// is written to a sheet cell or sent in a Telegram message
+package sanitize
+
+import (
+	"html"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaxLength is the default length limit in runes. A sheet cell holds at most 50,000
+// characters; descriptions are cut well below that to keep rows readable.
+const defaultMaxLength = 2000
+
+// MaxLength limits free text fields (runes, 0 = only the sheet cell limit), set with
+// Configure at startup
+var MaxLength = defaultMaxLength
+
+// Settings are the sanitize options taken from the environment
+type Settings struct {
+	MaxLength int // see MaxLength
+}
+
+// SettingsFromEnv reads the sanitize settings from MAX_TEXT_LENGTH
+func SettingsFromEnv() Settings {
+	return Settings{MaxLength: maxLengthFromEnv()}
+}
+
+// Configure replaces the sanitize settings. Call it once at startup, before text is
+// sanitized: the settings are read without locking.
+func Configure(s Settings) {
+	MaxLength = s.MaxLength
+}
+
+// cellLimit is the maximum number of characters in a Google Sheets cell
+const cellLimit = 50000
+
+// maxLengthFromEnv reads MAX_TEXT_LENGTH, falling back to defaultMaxLength when it is unset or invalid
+func maxLengthFromEnv() int {
+	value := os.Getenv("MAX_TEXT_LENGTH")
+	if value == "" {
+		return defaultMaxLength
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Warning: Invalid MAX_TEXT_LENGTH=%q (expected a number of characters), using %d\n", value, defaultMaxLength)
+		return defaultMaxLength
+	}
+	log.Printf("Using MAX_TEXT_LENGTH=%d\n", n)
+	return n
+}
+
+// Text removes control characters except line breaks and tabs, repairs invalid UTF-8 and
+// cuts the text to maxLen runes, ending with "…" when cut. maxLen 0 (or above the sheet
+// cell limit) cuts at the cell limit.
+func Text(s string, maxLen int) string {
+	if maxLen <= 0 || maxLen > cellLimit {
+		maxLen = cellLimit
+	}
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return Truncate(strings.TrimSpace(s), maxLen)
+}
+
+// HTML is Text with the result escaped for Telegram's HTML parse mode. Escaping comes after
+// cutting, so an entity is never cut in half.
+func HTML(s string, maxLen int) string {
+	return html.EscapeString(Text(s, maxLen))
+}
+
+// Truncate cuts s to at most maxLen runes, ending with "…" when cut, never inside a
+// multi-byte rune
+func Truncate(s string, maxLen int) string {
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	// Keep maxLen-1 runes to leave room for the ellipsis
+	count := 0
+	for i := range s {
+		if count == maxLen-1 {
+			return strings.TrimRightFunc(s[:i], unicode.IsSpace) + "…"
+		}
+		count++
+	}
+	return s
+}
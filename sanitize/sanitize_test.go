@@ -0,0 +1,58 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   string
+	}{
+		{"short text unchanged", "Cozy loft", 20, "Cozy loft"},
+		{"control characters removed", "Quiet\x00 room\x1b\r\nNo parties\tplease", 100, "Quiet room\nNo parties\tplease"},
+		{"invalid UTF-8 dropped", "Nice\xffview", 100, "Niceview"},
+		{"cut with ellipsis", "0123456789", 5, "0123…"},
+		{"cut at a rune boundary", "บ้านริมน้ำ", 4, "บ้า…"},
+		{"trailing space before ellipsis", "one two three", 5, "one…"},
+		{"exactly max length", "฿฿฿", 3, "฿฿฿"},
+		{"zero means cell limit", strings.Repeat("a", 10), 0, strings.Repeat("a", 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Text(tt.input, tt.maxLen)
+			if got != tt.want {
+				t.Errorf("Text(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Text(%q, %d) = %q is not valid UTF-8", tt.input, tt.maxLen, got)
+			}
+		})
+	}
+
+	if got := Text(strings.Repeat("x", cellLimit+10), 0); utf8.RuneCountInString(got) != cellLimit {
+		t.Errorf("Text() kept %d runes, want the cell limit %d", utf8.RuneCountInString(got), cellLimit)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		input  string
+		maxLen int
+		want   string
+	}{
+		{`<b>Pets</b> & "kids"`, 100, "&lt;b&gt;Pets&lt;/b&gt; &amp; &#34;kids&#34;"},
+		{"Tom & Jerry's", 6, "Tom &amp;…"}, // cut before escaping
+	}
+
+	for _, tt := range tests {
+		if got := HTML(tt.input, tt.maxLen); got != tt.want {
+			t.Errorf("HTML(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+		}
+	}
+}
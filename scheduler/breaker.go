@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"bnb-fetcher/fetcher"
+)
+
+// defaultBlockAbortAfter is how many links in a row may be blocked before a request is aborted
+const defaultBlockAbortAfter = 3
+
+// blockErrorKinds maps the names accepted in BLOCK_ERRORS to fetcher errors
+var blockErrorKinds = map[string]error{
+	"bot_challenge": fetcher.ErrBotChallenge,
+	"rate_limited":  fetcher.ErrRateLimited,
+	"navigation":    fetcher.ErrNavigation,
+	"no_pages":      fetcher.ErrNoPages,
+}
+
+// defaultBlockErrors are the errors counted as blocks when BLOCK_ERRORS is unset
+const defaultBlockErrors = "bot_challenge,rate_limited"
+
+// blockBreaker is a circuit breaker across the links of a request: once threshold links
+// in a row failed with a block-type error, the site is blocking this server and retrying
+// the remaining links would only make it worse, so the request is aborted.
+type blockBreaker struct {
+	threshold int     // consecutive blocked links that abort the request, 0 disables the breaker
+	kinds     []error // link errors counted as blocks
+}
+
+// blockBreakerFromEnv reads the threshold from BLOCK_ABORT_AFTER and the comma separated
+// block-type errors from BLOCK_ERRORS (bot_challenge, rate_limited, navigation, no_pages)
+func blockBreakerFromEnv() blockBreaker {
+	b := blockBreaker{threshold: defaultBlockAbortAfter}
+	if value := os.Getenv("BLOCK_ABORT_AFTER"); value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 {
+			log.Printf("Warning: Invalid BLOCK_ABORT_AFTER=%q (expected a link count, 0 disables), using %d\n", value, defaultBlockAbortAfter)
+		} else {
+			log.Printf("Using BLOCK_ABORT_AFTER=%d\n", threshold)
+			b.threshold = threshold
+		}
+	}
+
+	kinds, err := parseBlockErrors(defaultBlockErrors)
+	if err != nil {
+		panic(err) // the default is a constant
+	}
+	if value := os.Getenv("BLOCK_ERRORS"); value != "" {
+		if parsed, err := parseBlockErrors(value); err != nil {
+			log.Printf("Warning: Invalid BLOCK_ERRORS=%q (%v), using %s\n", value, err, defaultBlockErrors)
+		} else {
+			log.Printf("Using BLOCK_ERRORS=%s\n", value)
+			kinds = parsed
+		}
+	}
+	b.kinds = kinds
+	return b
+}
+
+// parseBlockErrors parses a comma separated list of blockErrorKinds names
+func parseBlockErrors(text string) ([]error, error) {
+	var kinds []error
+	for _, name := range strings.Split(text, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		kind, ok := blockErrorKinds[name]
+		if !ok {
+			return nil, errors.New("unknown error kind " + strconv.Quote(name))
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return nil, errors.New("no error kinds")
+	}
+	return kinds, nil
+}
+
+// isBlock reports whether a link error counts as a block
+func (b blockBreaker) isBlock(err error) bool {
+	for _, kind := range b.kinds {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// record updates the streak of blocked links after a failed link and reports whether
+// the request must be aborted. Failures that are not blocks reset the streak.
+func (b blockBreaker) record(streak int, err error) (int, bool) {
+	if !b.isBlock(err) {
+		return 0, false
+	}
+	streak++
+	return streak, b.threshold > 0 && streak >= b.threshold
+}
+
+// decide picks the reaction to a failed link, the breaker first. While the breaker is
+// enabled, blocks below its threshold don't pause the request: the link is retried later
+// (or failed after 3 attempts) and the next one tried, so the streak can reach the
+// threshold within the run instead of starting over on every resume. streak is the
+// blocked links in a row before this failure; the updated streak is returned with the
+// action.
+func (b blockBreaker) decide(streak int, err error, consecutiveFailures int, retryCount int) (int, linkFailureAction) {
+	streak, abort := b.record(streak, err)
+	switch {
+	case abort:
+		return streak, abortRequest
+	case b.threshold > 0 && streak > 0:
+		if retryCount < 3 {
+			return streak, retryLink
+		}
+		return streak, failLink
+	}
+	return streak, decideLinkFailure(err, consecutiveFailures, retryCount)
+}
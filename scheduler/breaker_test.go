@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"bnb-fetcher/fetcher"
+)
+
+func TestBlockBreaker(t *testing.T) {
+	challengeErr := fmt.Errorf("fetch failed: %w", fetcher.ErrBotChallenge)
+	rateLimitErr := fmt.Errorf("fetch failed: %w", fetcher.ErrRateLimited)
+	navigationErr := fmt.Errorf("fetch failed: %w", fetcher.ErrNavigation)
+
+	kinds, err := parseBlockErrors(defaultBlockErrors)
+	if err != nil {
+		t.Fatalf("parseBlockErrors(%q) error = %v", defaultBlockErrors, err)
+	}
+	b := blockBreaker{threshold: 3, kinds: kinds}
+
+	tests := []struct {
+		name      string
+		errs      []error // consecutive link failures
+		wantAbort bool
+	}{
+		{"consecutive blocks abort", []error{challengeErr, rateLimitErr, challengeErr}, true},
+		{"fewer blocks than the threshold", []error{challengeErr, challengeErr}, false},
+		{"other failure resets the streak", []error{challengeErr, challengeErr, navigationErr, rateLimitErr}, false},
+		{"other failures never abort", []error{navigationErr, navigationErr, navigationErr, errors.New("parse failed")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streak, abort := 0, false
+			for i, err := range tt.errs {
+				if abort {
+					t.Fatalf("aborted before failure %d", i+1)
+				}
+				streak, abort = b.record(streak, err)
+			}
+			if abort != tt.wantAbort {
+				t.Errorf("record() abort = %v after %d failures, want %v", abort, len(tt.errs), tt.wantAbort)
+			}
+		})
+	}
+
+	disabled := blockBreaker{kinds: kinds}
+	if _, abort := disabled.record(10, challengeErr); abort {
+		t.Error("record() aborted with the breaker disabled")
+	}
+}
+
+func TestBlockBreakerDecide(t *testing.T) {
+	challengeErr := fmt.Errorf("fetch failed: %w", fetcher.ErrBotChallenge)
+	rateLimitErr := fmt.Errorf("fetch failed: %w", fetcher.ErrRateLimited)
+
+	kinds, err := parseBlockErrors(defaultBlockErrors)
+	if err != nil {
+		t.Fatalf("parseBlockErrors(%q) error = %v", defaultBlockErrors, err)
+	}
+	b := blockBreaker{threshold: defaultBlockAbortAfter, kinds: kinds}
+
+	// With the defaults, blocked links in a row are retried rather than pausing the
+	// request, so the breaker aborts it within one run
+	errs := []error{challengeErr, rateLimitErr, challengeErr}
+	want := []linkFailureAction{retryLink, retryLink, abortRequest}
+
+	streak, consecutiveFailures := 0, 0
+	for i, err := range errs {
+		consecutiveFailures++
+		var action linkFailureAction
+		streak, action = b.decide(streak, err, consecutiveFailures, 0)
+		if action != want[i] {
+			t.Fatalf("failure %d: decide() = %v, want %v (streak %d)", i+1, action, want[i], streak)
+		}
+	}
+	if streak != defaultBlockAbortAfter {
+		t.Errorf("streak = %d at the abort, want %d", streak, defaultBlockAbortAfter)
+	}
+
+	// A block on a link out of retries fails the link, and other failures reset the streak
+	if streak, action := b.decide(1, challengeErr, 2, 3); streak != 2 || action != failLink {
+		t.Errorf("decide(1, challenge, retries spent) = (%d, %v), want (2, fail)", streak, action)
+	}
+	if streak, action := b.decide(2, errors.New("parse failed"), 1, 0); streak != 0 || action != retryLink {
+		t.Errorf("decide(2, parse failure) = (%d, %v), want (0, retry)", streak, action)
+	}
+
+	// With the breaker off, a bot check still pauses the request
+	off := blockBreaker{kinds: kinds}
+	if streak, action := off.decide(0, challengeErr, 1, 0); action != pauseRequest {
+		t.Errorf("decide() with the breaker off = (%d, %v), want pause", streak, action)
+	}
+}
+
+func TestParseBlockErrors(t *testing.T) {
+	kinds, err := parseBlockErrors(" Navigation, no_pages ")
+	if err != nil || len(kinds) != 2 || kinds[0] != fetcher.ErrNavigation || kinds[1] != fetcher.ErrNoPages {
+		t.Errorf("parseBlockErrors() = (%v, %v), want [navigation no_pages]", kinds, err)
+	}
+	for _, text := range []string{"", " , ", "bot_challenge,captcha"} {
+		if _, err := parseBlockErrors(text); err == nil {
+			t.Errorf("parseBlockErrors(%q) error = nil, want an error", text)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// memStatsInterval is how often memory usage is logged while the scheduler runs
+const memStatsInterval = 5 * time.Minute
+
+// defaultRestartHeapMB is the live heap size above which the browser is recreated once
+// no request is running
+const defaultRestartHeapMB = 512
+
+// restartHeapMBFromEnv reads the restart threshold from RESTART_HEAP_MB. 0 recreates
+// the browser after every request.
+func restartHeapMBFromEnv() uint64 {
+	value := os.Getenv("RESTART_HEAP_MB")
+	if value == "" {
+		return defaultRestartHeapMB
+	}
+	mb, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid RESTART_HEAP_MB=%q (expected megabytes), using %d\n", value, defaultRestartHeapMB)
+		return defaultRestartHeapMB
+	}
+	log.Printf("Using RESTART_HEAP_MB=%d\n", mb)
+	return mb
+}
+
+// readMemStats returns the current memory statistics
+func readMemStats() runtime.MemStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m
+}
+
+// logMemStats logs heap and process memory usage
+func logMemStats(m runtime.MemStats) {
+	log.Printf("Memory: heap %d MB in use (%d MB reserved), %d MB from OS, %d GC cycles, %d goroutines\n",
+		bytesToMB(m.HeapAlloc), bytesToMB(m.HeapSys), bytesToMB(m.Sys), m.NumGC, runtime.NumGoroutine())
+}
+
+// bytesToMB converts a byte count to whole megabytes
+func bytesToMB(bytes uint64) uint64 {
+	return bytes / (1024 * 1024)
+}
+
+// liveHeapMB collects garbage and returns the live heap size in megabytes, so the
+// restart decision isn't triggered by garbage that is about to be freed
+func liveHeapMB() uint64 {
+	runtime.GC()
+	m := readMemStats()
+	logMemStats(m)
+	return bytesToMB(m.HeapAlloc)
+}
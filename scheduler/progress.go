@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"bnb-fetcher/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// minProgressEditInterval throttles edits of the progress message; Telegram rejects
+// frequent edits of the same message
+const minProgressEditInterval = time.Second
+
+// progressState is the single editable progress message of a request
+type progressState struct {
+	messageID int // 0 until the message is sent
+
+	link, links        int
+	page, pages        int
+	enriched, toEnrich int
+	status             string // latest status line, shown under the bar
+
+	lastEdit time.Time
+}
+
+// progressEditFromEnv reports whether progress is shown by editing one message
+// (PROGRESS_MODE=edit, the default) or as separate messages (PROGRESS_MODE=messages)
+func progressEditFromEnv() bool {
+	switch mode := os.Getenv("PROGRESS_MODE"); mode {
+	case "", "edit":
+		return true
+	case "messages":
+		return false
+	default:
+		log.Printf("Warning: Unknown PROGRESS_MODE=%q, using edit\n", mode)
+		return true
+	}
+}
+
+// bar renders the counters, e.g. "Link 3/10 · Page 4/5 (80%) · Enriched 40/60"
+func (p *progressState) bar() string {
+	var parts []string
+	if p.links > 0 {
+		parts = append(parts, fmt.Sprintf("Link %d/%d", p.link, p.links))
+	}
+	if p.pages > 0 {
+		parts = append(parts, fmt.Sprintf("Page %d/%d (%d%%)", p.page, p.pages, percent(p.page, p.pages)))
+	}
+	if p.toEnrich > 0 {
+		parts = append(parts, fmt.Sprintf("Enriched %d/%d", p.enriched, p.toEnrich))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// pageStatusInterval is the least time between two fetched page status lines of a link.
+// Pages in between only move the progress counters, so a fast fetcher doesn't flood the
+// chat in PROGRESS_MODE=messages.
+const pageStatusInterval = 5 * time.Second
+
+// pageThrottle decides which fetched pages of a link get a status line
+type pageThrottle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// allow reports whether page pageNum of maxPages, fetched at now, gets a status line:
+// the first and last page always do, the others when interval has passed since the last one
+func (t *pageThrottle) allow(pageNum, maxPages int, now time.Time) bool {
+	if pageNum > 1 && pageNum < maxPages && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// percent returns done out of total as a whole percentage, 0 when total is not positive
+func percent(done, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	return min(100, done*100/total)
+}
+
+// reportProgress applies update to the request's progress counters and shows status.
+// In edit mode the request's progress message is edited in place (throttled; a new
+// status line always goes through). Otherwise status is sent as a separate message and
+// counter-only updates (empty status) are not shown.
+func (s *Scheduler) reportProgress(req *db.Request, status string, update func(p *progressState)) {
+	if !s.progressEdit {
+		if status != "" {
+			s.sendStatusUpdate(req.TelegramMessageID, req.UserID, status)
+		}
+		return
+	}
+
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+
+	p, ok := s.progress[req.ID]
+	if !ok {
+		p = &progressState{}
+		s.progress[req.ID] = p
+	}
+	if update != nil {
+		update(p)
+	}
+	if status != "" {
+		p.status = status
+	} else if time.Since(p.lastEdit) < minProgressEditInterval {
+		return
+	}
+	p.lastEdit = time.Now()
+
+	text := "⏳ " + p.bar()
+	if p.status != "" {
+		text += "\n" + p.status
+	}
+
+	if p.messageID == 0 {
+		msg := tgbotapi.NewMessage(req.UserID, text)
+		msg.ReplyToMessageID = req.TelegramMessageID
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		sent, err := s.bot.Send(msg)
+		if err != nil {
+			log.Printf("Error sending progress message: %v\n", err)
+			return
+		}
+		p.messageID = sent.MessageID
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(req.UserID, p.messageID, text)
+	edit.ParseMode = "HTML"
+	edit.DisableWebPagePreview = true
+	if _, err := s.bot.Send(edit); err != nil {
+		log.Printf("Error editing progress message: %v\n", err)
+	}
+}
+
+// clearProgress forgets the request's progress message once the request stops running
+func (s *Scheduler) clearProgress(requestID int) {
+	s.progressMu.Lock()
+	delete(s.progress, requestID)
+	s.progressMu.Unlock()
+}
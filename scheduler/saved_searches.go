@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"bnb-fetcher/currency"
+	"bnb-fetcher/db"
+	"bnb-fetcher/models"
+	"bnb-fetcher/pricerange"
+	"bnb-fetcher/searchurl"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// enqueueDueSavedSearches queues today's run of every saved search whose time has come
+func (s *Scheduler) enqueueDueSavedSearches(now time.Time) {
+	due, err := s.db.GetDueSavedSearches(now)
+	if err != nil {
+		log.Printf("Error getting due saved searches: %v\n", err)
+		return
+	}
+	for _, search := range due {
+		if err := s.enqueueSavedSearch(search, now); err != nil {
+			log.Printf("Error queuing saved search %d: %v\n", search.ID, err)
+		}
+	}
+}
+
+// enqueueSavedSearch creates a request for one run of the saved search, split into price
+// ranges at the user's step like a search sent in the chat
+func (s *Scheduler) enqueueSavedSearch(search db.SavedSearch, now time.Time) error {
+	// Marked first so a search that fails to queue is not retried on every tick
+	if err := s.db.MarkSavedSearchRun(search.ID, now); err != nil {
+		return fmt.Errorf("failed to mark run: %w", err)
+	}
+
+	existingReq, err := s.db.FindActiveRequestByURL(search.UserID, search.URL)
+	if err != nil {
+		return err
+	}
+	if existingReq != nil {
+		log.Printf("Skipping saved search %d: request %d for the same URL is still %s\n", search.ID, existingReq.ID, existingReq.Status)
+		return nil
+	}
+
+	urls := savedSearchLinkURLs(s.db, search)
+	msg := tgbotapi.NewMessage(search.UserID, fmt.Sprintf(
+		"⏰ Running saved search #%d (%d link(s)). Only listings not found by earlier runs will be added.", search.ID, len(urls)))
+	sentMsg, err := s.bot.Send(msg)
+	if err != nil {
+		return fmt.Errorf("failed to send start message: %w", err)
+	}
+
+	req, err := s.db.CreateScheduledRequest(search.UserID, sentMsg.MessageID, search.URL, search.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if _, err := s.db.CreateSearchLinks(req.ID, urls); err != nil {
+		return fmt.Errorf("failed to create search links: %w", err)
+	}
+	log.Printf("Queued saved search %d as request ID %d with %d search links\n", search.ID, req.ID, len(urls))
+	return nil
+}
+
+// savedSearchLinkURLs splits the saved search URL into price range URLs at the step of
+// its settings snapshot
+func savedSearchLinkURLs(database *db.DB, search db.SavedSearch) []string {
+	priceStep := pricerange.DefaultStep
+	if userConfig, err := savedSearchConfig(database, search.UserID, search.ID); err != nil {
+		log.Printf("Warning: Failed to load price step, using $%d: %v\n", priceStep, err)
+	} else if userConfig.PriceStep > 0 {
+		priceStep = userConfig.PriceStep
+	}
+
+	ranges, err := pricerange.GeneratePriceRangeURLs(search.URL, priceStep)
+	if err != nil || len(ranges) == 0 {
+		return []string{search.URL}
+	}
+	urls := make([]string, len(ranges))
+	for i, r := range ranges {
+		urls[i] = r.URL
+	}
+	return urls
+}
+
+// savedSearchConfig returns the settings a run of the saved search uses: the user's
+// current ones with the snapshot taken when the search was saved applied on top.
+// Searches saved before snapshots were taken use the current settings.
+func savedSearchConfig(database *db.DB, userID int64, savedSearchID int) (*db.UserConfig, error) {
+	userConfig, err := database.GetUserConfig(userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := database.GetSavedSearchConfig(savedSearchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings of saved search %d: %w", savedSearchID, err)
+	}
+	if snapshot != "" {
+		if err := userConfig.ApplySnapshot(snapshot); err != nil {
+			return nil, fmt.Errorf("saved search %d: %w", savedSearchID, err)
+		}
+	}
+	return userConfig, nil
+}
+
+// maxRunChangeEntries limits the new listings named in a run's change summary
+const maxRunChangeEntries = 10
+
+// priceDrop is a listing found at a lower price than the user saw it at before
+type priceDrop struct {
+	listing  models.Listing
+	oldPrice float64
+}
+
+// diffRuns compares the listings of a saved search run with the ones of earlier runs, by
+// searchurl.ListingKey: added are the listings no earlier run had, pricedDown the ones
+// now cheaper than last seen (in the same currency). Listings missing from curr are not
+// reported, a search only shows a sample of what is available.
+func diffRuns(prev, curr []models.Listing) (added, pricedDown []models.Listing) {
+	prevByKey := listingsByKey(prev)
+	seen := make(map[string]bool, len(curr))
+	for _, listing := range curr {
+		key := searchurl.ListingKey(listing.URL)
+		if seen[key] {
+			continue // found again by another price range link
+		}
+		seen[key] = true
+
+		old, found := prevByKey[key]
+		switch {
+		case !found:
+			added = append(added, listing)
+		case listing.Price > 0 && listing.Price < old.Price && currency.Code(old.Currency) == currency.Code(listing.Currency):
+			pricedDown = append(pricedDown, listing)
+		}
+	}
+	return added, pricedDown
+}
+
+// listingsByKey indexes listings by searchurl.ListingKey
+func listingsByKey(listings []models.Listing) map[string]models.Listing {
+	byKey := make(map[string]models.Listing, len(listings))
+	for _, listing := range listings {
+		byKey[searchurl.ListingKey(listing.URL)] = listing
+	}
+	return byKey
+}
+
+// notifyRunChanges sends what changed since the earlier runs of the saved search: new
+// listings and price drops. The sheet keeps the full results; this message is only the
+// deltas. The first run has nothing to compare to and only records the listings.
+func (s *Scheduler) notifyRunChanges(req *db.Request) {
+	savedSearchID := int(req.SavedSearchID.Int64)
+	curr, err := s.db.GetRunListings(req.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to load listings of saved search run %d: %v\n", req.ID, err)
+		return
+	}
+	defer func() {
+		if err := s.db.DeleteRunListings(req.ID); err != nil {
+			log.Printf("Warning: Failed to delete listings of saved search run %d: %v\n", req.ID, err)
+		}
+	}()
+
+	prev, err := s.db.GetSavedSearchListings(savedSearchID)
+	if err != nil {
+		log.Printf("Warning: Failed to load earlier listings of saved search %d: %v\n", savedSearchID, err)
+		return
+	}
+	if len(prev) > 0 {
+		added, pricedDown := diffRuns(prev, curr)
+		prevByKey := listingsByKey(prev)
+		drops := make([]priceDrop, 0, len(pricedDown))
+		for _, listing := range pricedDown {
+			drops = append(drops, priceDrop{listing: listing, oldPrice: prevByKey[searchurl.ListingKey(listing.URL)].Price})
+		}
+		if text := formatRunChanges(savedSearchID, added, drops); text != "" {
+			s.sendStatusUpdate(req.TelegramMessageID, req.UserID, text)
+		}
+	}
+
+	if err := s.db.SaveSavedSearchListings(savedSearchID, curr); err != nil {
+		log.Printf("Warning: Failed to save listings of saved search %d: %v\n", savedSearchID, err)
+	}
+}
+
+// formatRunChanges formats the changes of a saved search run (HTML), "" when nothing changed
+func formatRunChanges(savedSearchID int, added []models.Listing, drops []priceDrop) string {
+	if len(added) == 0 && len(drops) == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("🔔 Saved search #%d changed since the last run:", savedSearchID)}
+	if len(added) > 0 {
+		lines = append(lines, "", fmt.Sprintf("🆕 %d new listing(s):", len(added)))
+		for i, listing := range added {
+			if i == maxRunChangeEntries {
+				lines = append(lines, fmt.Sprintf("…and %d more in the sheet", len(added)-i))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• <a href=\"%s\">%s</a>: %s",
+				html.EscapeString(listing.URL), html.EscapeString(listingLabel(listing)), currency.FormatPrice(listing.Price, listing.Currency)))
+		}
+	}
+	if len(drops) > 0 {
+		lines = append(lines, "", formatPriceDrops(drops))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listingLabel names a listing by its title, or its URL path when it has none
+func listingLabel(listing models.Listing) string {
+	if listing.Title == "" {
+		return extractURLPath(listing.URL)
+	}
+	return listing.Title
+}
+
+// formatPriceDrops formats a price drop alert (HTML)
+func formatPriceDrops(drops []priceDrop) string {
+	lines := []string{fmt.Sprintf("📉 Price drop on %d listing(s):", len(drops))}
+	for _, drop := range drops {
+		listing := drop.listing
+		lines = append(lines, fmt.Sprintf("• <a href=\"%s\">%s</a>: %s → %s (-%.0f%%)",
+			html.EscapeString(listing.URL), html.EscapeString(listingLabel(listing)),
+			currency.FormatPrice(drop.oldPrice, listing.Currency), currency.FormatPrice(listing.Price, listing.Currency),
+			(drop.oldPrice-listing.Price)/drop.oldPrice*100))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,25 +2,34 @@ package scheduler
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"html"
 	"log"
 	"math"
 	"net/url"
 	"os"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"bnb-fetcher/config"
+	"bnb-fetcher/currency"
 	"bnb-fetcher/db"
+	"bnb-fetcher/errclass"
 	"bnb-fetcher/fetcher"
 	"bnb-fetcher/filter"
 	"bnb-fetcher/models"
 	"bnb-fetcher/parser"
 	"bnb-fetcher/pricerange"
+	"bnb-fetcher/sanitize"
+	"bnb-fetcher/scoring"
 	"bnb-fetcher/sheets"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -38,20 +47,57 @@ type Scheduler struct {
 	requestsMutex  sync.Mutex
 	lastMsgMu      sync.Mutex
 	lastMsgTime    time.Time
+
+	activityHalfLifeDays float64             // Decay half-life for listing activity scores
+	usdConverter         *currency.Converter // Fills the Price (USD) column
+
+	progressEdit bool                   // edit one progress message per request instead of sending many
+	progressMu   sync.Mutex             // guards progress
+	progress     map[int]*progressState // request ID -> progress message
+
+	paused        atomic.Bool           // admin kill switch: requests queue up but are not processed
+	fetchStrategy fetcher.Strategy      // how search pages are fetched (FETCHER env)
+	detailBrowser fetcher.DetailBrowser // where detail pages are opened (DETAIL_BROWSER env)
+	restartHeapMB uint64                // recreate the browser when idle with a larger live heap (RESTART_HEAP_MB env)
+	breaker       blockBreaker          // aborts requests whose links keep getting blocked (BLOCK_ABORT_AFTER, BLOCK_ERRORS env)
+
+	browser *fetcher.RodFetcher // kept between requests; only used by the run goroutine
 }
 
 // NewScheduler creates a new scheduler (browser will be created on-demand)
 func NewScheduler(database *db.DB, bot *tgbotapi.BotAPI, writer *sheets.Writer, spreadsheetURL string) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Scheduler{
+	s := &Scheduler{
 		db:             database,
 		bot:            bot,
 		writer:         writer,
 		spreadsheetURL: spreadsheetURL,
 		ctx:            ctx,
 		cancel:         cancel,
+
+		activityHalfLifeDays: scoring.HalfLifeDaysFromEnv(),
+		usdConverter:         currency.NewConverterFromEnv(),
+
+		progressEdit: progressEditFromEnv(),
+		progress:     make(map[int]*progressState),
+
+		fetchStrategy: fetcher.StrategyFromEnv(),
+		detailBrowser: fetcher.DetailBrowserFromEnv(),
+		restartHeapMB: restartHeapMBFromEnv(),
+		breaker:       blockBreakerFromEnv(),
+	}
+
+	// Stay paused across restarts
+	paused, err := database.GetProcessingPaused()
+	if err != nil {
+		log.Printf("Warning: Failed to load paused state, processing is running: %v\n", err)
 	}
+	s.paused.Store(paused)
+	if paused {
+		log.Println("Request processing is paused; the admin can /resume it")
+	}
+	return s
 }
 
 // Start starts the scheduler in a goroutine
@@ -59,6 +105,21 @@ func (s *Scheduler) Start() {
 	go s.run()
 }
 
+// SetPaused pauses or resumes request processing and stores the state so it survives a
+// restart. A request being processed when pausing runs to completion.
+func (s *Scheduler) SetPaused(paused bool) error {
+	if err := s.db.SetProcessingPaused(paused); err != nil {
+		return fmt.Errorf("failed to store paused state: %w", err)
+	}
+	s.paused.Store(paused)
+	return nil
+}
+
+// Paused reports whether request processing is paused
+func (s *Scheduler) Paused() bool {
+	return s.paused.Load()
+}
+
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	s.cancel()
@@ -69,14 +130,23 @@ func (s *Scheduler) Stop() {
 func (s *Scheduler) run() {
 	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
 	defer ticker.Stop()
+	memTicker := time.NewTicker(memStatsInterval)
+	defer memTicker.Stop()
 
 	for {
 		select {
 		case <-s.ctx.Done():
+			s.closeBrowser()
 			log.Println("Scheduler stopped")
 			return
+		case <-memTicker.C:
+			logMemStats(readMemStats())
 		case <-ticker.C:
-			s.processNextRequest()
+			// Saved searches keep queuing while paused, like requests sent in the chat
+			s.enqueueDueSavedSearches(time.Now())
+			if !s.Paused() {
+				s.processNextRequest()
+			}
 		}
 	}
 }
@@ -110,9 +180,26 @@ func (s *Scheduler) decrementActiveRequest() {
 	s.requestsMutex.Unlock()
 	log.Printf("Active requests: %d\n", activeCount)
 
-	// If no active requests, trigger restart after a short delay to ensure cleanup
+	// Keep the session's cookies even if the process is killed before the browser is closed
+	if activeCount == 0 && s.browser != nil {
+		s.browser.SaveCookies()
+	}
+
+	// If no active requests and the heap grew past the threshold, recreate the browser to
+	// reclaim memory; otherwise keep it warm for the next request. Only if the browser
+	// can't be recreated, trigger a process restart after a short delay to ensure cleanup.
 	if activeCount == 0 {
-		log.Println("No active requests remaining. Scheduling restart in 2 seconds...")
+		heapMB := liveHeapMB()
+		if heapMB < s.restartHeapMB {
+			log.Printf("No active requests remaining. Heap at %d MB (restart threshold %d MB), keeping the browser\n", heapMB, s.restartHeapMB)
+			return
+		}
+		log.Printf("No active requests remaining and heap at %d MB (restart threshold %d MB). Recreating the browser...\n", heapMB, s.restartHeapMB)
+		err := s.restartBrowser()
+		if err == nil {
+			return
+		}
+		log.Printf("Error recreating browser: %v. Scheduling restart in 2 seconds...\n", err)
 		go func() {
 			time.Sleep(2 * time.Second)
 			// Double-check no new requests started
@@ -126,6 +213,57 @@ func (s *Scheduler) decrementActiveRequest() {
 	}
 }
 
+// getBrowser returns the browser kept between requests, launching it on first use or
+// relaunching it if its process died
+func (s *Scheduler) getBrowser(requestID int) (*fetcher.RodFetcher, error) {
+	if s.browser != nil && s.browser.Alive() {
+		log.Printf("Reusing browser for request ID %d\n", requestID)
+		return s.browser, nil
+	}
+	if s.browser != nil {
+		log.Printf("Browser stopped responding, relaunching for request ID %d...\n", requestID)
+		if err := s.browser.Restart(); err != nil {
+			s.browser = nil
+			return nil, err
+		}
+		return s.browser, nil
+	}
+
+	log.Printf("Initializing browser for request ID %d...\n", requestID)
+	rodFetcher, err := fetcher.NewRodFetcher()
+	if err != nil {
+		return nil, err
+	}
+	s.browser = rodFetcher
+	return rodFetcher, nil
+}
+
+// restartBrowser recreates the browser kept between requests and returns freed memory
+// to the OS. Without a browser there is nothing to recreate.
+func (s *Scheduler) restartBrowser() error {
+	defer releaseMemory()
+	if s.browser == nil {
+		return nil
+	}
+	if err := s.browser.Restart(); err != nil {
+		s.browser = nil
+		return err
+	}
+	log.Println("🔄 Browser recreated to reclaim memory")
+	return nil
+}
+
+// closeBrowser closes the browser kept between requests
+func (s *Scheduler) closeBrowser() {
+	if s.browser == nil {
+		return
+	}
+	if err := s.browser.Close(); err != nil {
+		log.Printf("Warning: Failed to close browser: %v\n", err)
+	}
+	s.browser = nil
+}
+
 // requestRestart exits the process to allow process manager to restart it
 func (s *Scheduler) requestRestart() {
 	log.Println("🔄 Restarting service to clean up memory...")
@@ -154,6 +292,9 @@ func (s *Scheduler) processNextRequest() {
 	defer releaseMemory()
 
 	log.Printf("Processing request ID %d for user %d\n", req.ID, req.UserID)
+	requestStart := time.Now()
+	var metrics db.RequestMetrics
+	defer s.clearProgress(req.ID)
 
 	// Update status to 'in_progress'
 	if err := s.db.UpdateRequestStatus(req.ID, "in_progress"); err != nil {
@@ -181,10 +322,17 @@ func (s *Scheduler) processNextRequest() {
 	}
 
 	totalLinks := len(searchLinks)
-	s.sendStatusUpdate(req.TelegramMessageID, req.UserID, fmt.Sprintf("🔄 Processing request with %d link(s)...", totalLinks))
-
-	// Get user config
-	userConfig, err := s.db.GetUserConfig(req.UserID)
+	s.reportProgress(req, fmt.Sprintf("🔄 Processing request with %d link(s)...", totalLinks), func(p *progressState) {
+		p.links = totalLinks
+	})
+
+	// Get user config, as it was when the search was saved for its runs
+	var userConfig *db.UserConfig
+	if req.SavedSearchID.Valid {
+		userConfig, err = savedSearchConfig(s.db, req.UserID, int(req.SavedSearchID.Int64))
+	} else {
+		userConfig, err = s.db.GetUserConfig(req.UserID)
+	}
 	if err != nil {
 		log.Printf("Error getting user config: %v\n", err)
 		s.handleRequestError(req, err)
@@ -197,62 +345,125 @@ func (s *Scheduler) processNextRequest() {
 	cfg.Filters.MinPrice = userConfig.MinPrice
 	cfg.Filters.MaxPrice = userConfig.MaxPrice
 	cfg.Filters.MinStars = userConfig.MinStars
+	cfg.Filters.SuperhostOnly = userConfig.SuperhostOnly
+	cfg.Filters.GuestFavoriteOnly = userConfig.GuestFavoriteOnly
+	cfg.Filters.LocationContains = userConfig.LocationContains
+	cfg.Filters.DiscountedOnly = userConfig.DiscountedOnly
+	cfg.Filters.MaxMinimumNights = userConfig.MaxMinimumNights
+	cfg.Filters.MaxPricePerGuest = userConfig.MaxPricePerGuest
 
 	// Create sheet at start (or reuse when resuming)
 	filterInfo := fmt.Sprintf("Min Reviews: %d, Min Price: %.2f, Max Price: %.2f, Min Stars: %.2f",
 		cfg.Filters.MinReviews, cfg.Filters.MinPrice, cfg.Filters.MaxPrice, cfg.Filters.MinStars)
+	if cfg.Filters.SuperhostOnly {
+		filterInfo += ", Superhost Only"
+	}
+	if cfg.Filters.GuestFavoriteOnly {
+		filterInfo += ", Guest Favorite Only"
+	}
+	if cfg.Filters.DiscountedOnly {
+		filterInfo += ", Discounted Only"
+	}
+	if cfg.Filters.MaxMinimumNights > 0 {
+		filterInfo += fmt.Sprintf(", Max Minimum Nights: %d", cfg.Filters.MaxMinimumNights)
+	}
+	if cfg.Filters.MaxPricePerGuest > 0 {
+		filterInfo += fmt.Sprintf(", Max Price per Guest: $%.2f", cfg.Filters.MaxPricePerGuest)
+	}
+	if cfg.Filters.LocationContains != "" {
+		filterInfo += fmt.Sprintf(", Location: %q", cfg.Filters.LocationContains)
+	}
 	metadataURL := req.URL
 	if totalLinks > 1 {
 		metadataURL = fmt.Sprintf("%d links - see Link # column", totalLinks)
 	}
 
+	// Runs of a saved search may all go to one tab, told apart by their run time
+	appendRuns := userConfig.AppendSavedSearches && req.SavedSearchID.Valid
+
+	// Requests with a spreadsheet of their own keep writing to it when resumed
+	writer := s.writer.ForSpreadsheet(req.SpreadsheetID.String)
+
 	var sheetName string
 	var sheetID int64
 	if req.SheetName.Valid && req.SheetName.String != "" {
 		sheetName = req.SheetName.String
-		sheetID = 0 // resume: no gid for deep link
+		sheetID = req.SheetGID.Int64 // 0 for requests created before the gid was stored
 		log.Printf("Reusing existing sheet '%s' for request ID %d\n", sheetName, req.ID)
 	} else {
 		sheetName = fmt.Sprintf("Request_%d_%s", req.ID, time.Now().Format("20060102_150405"))
 		var createErr error
-		sheetName, sheetID, createErr = s.writer.CreateEmptySheet(sheetName, metadataURL, filterInfo)
+		sheetStart := time.Now()
+		// Runs appended to one tab stay in the shared spreadsheet even for users who
+		// want a spreadsheet per request
+		newSpreadsheet := userConfig.SpreadsheetPerRequest && !appendRuns && !req.SpreadsheetID.Valid
+		if newSpreadsheet {
+			var spreadsheetID string
+			spreadsheetID, createErr = s.writer.CreateSpreadsheet(fmt.Sprintf("Airbnb request #%d (%s)", req.ID, time.Now().Format("2006-01-02 15:04")))
+			if createErr != nil {
+				log.Printf("Error creating spreadsheet: %v\n", createErr)
+				s.handleRequestError(req, createErr)
+				return
+			}
+			if err := s.db.UpdateRequestSpreadsheet(req.ID, spreadsheetID); err != nil {
+				log.Printf("Warning: Failed to store spreadsheet of request %d: %v\n", req.ID, err)
+			}
+			req.SpreadsheetID = sql.NullString{String: spreadsheetID, Valid: true}
+			writer = s.writer.ForSpreadsheet(spreadsheetID)
+		}
+		if appendRuns {
+			sheetName, sheetID, createErr = writer.EnsureRunSheet(sheets.SavedSearchTabName(int(req.SavedSearchID.Int64)), metadataURL, stayDatesInfo(req), filterInfo)
+		} else {
+			sheetName, sheetID, createErr = writer.CreateEmptySheet(sheetName, metadataURL, stayDatesInfo(req), filterInfo)
+		}
+		if createErr == nil && newSpreadsheet {
+			// The empty default tab of the new spreadsheet (always gid 0) is not needed
+			if err := writer.DeleteSheet(0); err != nil {
+				log.Printf("Warning: Failed to delete the default tab of request %d's spreadsheet: %v\n", req.ID, err)
+			}
+		}
+		metrics.SheetsWrite += time.Since(sheetStart)
 		if createErr != nil {
 			log.Printf("Error creating sheet: %v\n", createErr)
 			s.handleRequestError(req, createErr)
 			return
 		}
-		if err := s.db.UpdateRequestSheetName(req.ID, sheetName); err != nil {
+		if err := s.db.UpdateRequestSheetName(req.ID, sheetName, sheetID); err != nil {
 			log.Printf("Warning: Failed to update sheet name: %v\n", err)
 		}
-		sheetURL := s.createSheetURL(sheetID)
+		sheetURL := s.createSheetURL(req, sheetID)
 		s.sendStatusUpdate(req.TelegramMessageID, req.UserID, fmt.Sprintf("📊 Sheet ready: %s", sheetURL))
 	}
 
-	// Create browser only when needed (on-demand)
-	log.Printf("Initializing browser for request ID %d...\n", req.ID)
-	rodFetcher, err := fetcher.NewRodFetcher()
+	// Create browser only when needed (on-demand); it stays open for the next request
+	launchStart := time.Now()
+	rodFetcher, err := s.getBrowser(req.ID)
 	if err != nil {
 		log.Printf("Error creating fetcher: %v\n", err)
 		s.handleRequestError(req, err)
 		return
 	}
-	defer func() {
-		log.Printf("Closing browser after request ID %d...\n", req.ID)
-		if err := rodFetcher.Close(); err != nil {
-			log.Printf("Warning: Failed to close browser: %v\n", err)
-		} else {
-			log.Printf("Browser closed successfully for request ID %d\n", req.ID)
-		}
-	}()
+	metrics.BrowserLaunch = time.Since(launchStart)
 
-	fetcherInstance := fetcher.Fetcher(rodFetcher)
 	filterInstance := filter.NewFilter(cfg)
 	parserInstance := parser.NewParser()
-	detailFetcher := fetcher.NewDetailFetcher(rodFetcher.GetBrowser())
+	parserInstance.IncludeSimilarDates = userConfig.IncludeSimilarDates
+	fetcherInstance := fetcher.NewSearchFetcher(s.fetchStrategy, rodFetcher, parserInstance.HasListings)
+	detailFetcher, err := fetcher.NewDetailFetcherFor(s.detailBrowser, rodFetcher)
+	if err != nil {
+		log.Printf("Warning: %v; opening detail pages in the search browser\n", err)
+		detailFetcher = fetcher.NewDetailFetcher(rodFetcher.GetBrowser())
+	}
+	defer func() {
+		if err := detailFetcher.Close(); err != nil {
+			log.Printf("Warning: Failed to close detail page browser: %v\n", err)
+		}
+	}()
 	detailParser := parser.NewDetailParser()
+	detailParser.Fields = detailFieldsFor(userConfig)
 
-	// Track seen listing URLs across all links for deduplication
-	seenListingURLs := make(map[string]int) // URL -> link number that first found it
+	// Track seen listing URLs for deduplication, across links or per link depending on the user's scope
+	deduper := filter.NewDeduper(userConfig.DedupScope)
 
 	// On resume: load seen URLs from already-completed (done) links so we dedupe correctly
 	doneLinkNumbers := make(map[int]bool)
@@ -266,33 +477,74 @@ func (s *Scheduler) processNextRequest() {
 		if err == nil {
 			for _, row := range existingListings {
 				if doneLinkNumbers[row.LinkNumber] {
-					seenListingURLs[row.URL] = row.LinkNumber
+					deduper.Add(row.URL, row.LinkNumber)
 				}
 			}
 		}
 	}
 
+	// A scheduled run of a saved search reports only listings earlier runs didn't
+	previousRuns := 0
+	if req.SavedSearchID.Valid {
+		runs, reportedURLs, err := s.db.GetSavedSearchHistory(int(req.SavedSearchID.Int64), req.ID)
+		if err != nil {
+			log.Printf("Warning: Failed to load earlier runs of saved search %d: %v\n", req.SavedSearchID.Int64, err)
+		}
+		previousRuns = runs
+		for _, url := range reportedURLs {
+			deduper.Exclude(url)
+		}
+	}
+
 	// Collect all listings from all links
 	var allEnrichedListings []models.Listing
 	var allUnfilteredListings []models.Listing
 	var totalListingsBeforeFilter int
 	var totalPagesFetched int
+	var totalParseFailures int
 
 	// Track statistics (declared before queue build so we can count skipped done links on resume)
 	linksSuccessful := 0
 	linksFailed := 0
 	consecutiveFailures := 0
+	// Blocked links in a row; the breaker aborts the request when it reaches its threshold
+	blockStreak := 0
 
 	// Create retry queue from search links (skip links already done, e.g. on resume)
 	type queueItem struct {
 		link       db.SearchLink
 		retryCount int
 	}
+	// Auto-widen runs at most one extra pass without price_max. Its links already existing
+	// (on resume) means the pass was started before.
+	widenURLs := widenedSearchURLs(req.URL)
+	isWidenLink := make(map[string]bool, len(widenURLs))
+	for _, u := range widenURLs {
+		isWidenLink[u] = true
+	}
+	linkLabel := func(link db.SearchLink) string {
+		label := pricerange.ExtractPriceRangeLabel(link.URL)
+		if isWidenLink[link.URL] {
+			return "widened " + label
+		}
+		return label
+	}
+	widened := false
+	listingsKept := 0 // filtered listings of all done links, for the auto-widen floor
+	widenedAdded := 0 // of which found by the widened links
+
 	queue := make([]queueItem, 0, len(searchLinks))
 	for _, link := range searchLinks {
+		if isWidenLink[link.URL] {
+			widened = true
+		}
 		if link.Status == "done" {
 			linksSuccessful++
 			totalListingsBeforeFilter += link.ListingsCount
+			listingsKept += link.ListingsCount
+			if isWidenLink[link.URL] {
+				widenedAdded += link.ListingsCount
+			}
 			continue
 		}
 		queue = append(queue, queueItem{link: link, retryCount: link.RetryCount})
@@ -307,33 +559,84 @@ func (s *Scheduler) processNextRequest() {
 	}
 	var priceRangeStats []priceRangeStat
 
+	// Request-level page budget and listing cap shared by all links (0 = unlimited)
+	pageBudget := EffectivePageBudget(userConfig.MaxTotalPages)
+	remainingPages := pageBudget
+	listingCap := userConfig.MaxListings
+	remainingListings := listingCap
+	var skippedLinks []int // link numbers, reported in the success message
+	var skipReason string
+
 	// Process links with retry queue
-	for len(queue) > 0 {
+	for {
+		if len(queue) == 0 {
+			// Too few listings kept: queue the widened links once, unless a budget stopped the request
+			if widened || userConfig.AutoWidenMin <= 0 || listingsKept >= userConfig.AutoWidenMin || len(skippedLinks) > 0 {
+				break
+			}
+			widened = true
+			for _, link := range s.widenSearch(req, widenURLs, listingsKept, userConfig.AutoWidenMin) {
+				queue = append(queue, queueItem{link: link})
+				totalLinks++
+			}
+			if len(queue) == 0 {
+				break
+			}
+		}
+
 		// Pop first item from queue
 		item := queue[0]
 		queue = queue[1:]
 		link := item.link
 
+		// Once a budget is spent, remaining links are marked failed so /retry can run them later
+		skipReason = ""
+		if pageBudget > 0 && remainingPages <= 0 {
+			skipReason = fmt.Sprintf("page budget of %d exhausted", pageBudget)
+		} else if listingCap > 0 && remainingListings <= 0 {
+			skipReason = fmt.Sprintf("listing cap of %d reached", listingCap)
+		}
+		if skipReason != "" {
+			skipErr := "skipped: " + skipReason
+			if err := s.db.UpdateSearchLinkStatus(link.ID, "failed", &skipErr); err != nil {
+				log.Printf("Error updating search link status to failed: %v\n", err)
+			}
+			log.Printf("Link %d %s\n", link.LinkNumber, skipErr)
+			skippedLinks = append(skippedLinks, link.LinkNumber)
+			continue
+		}
+		limits := linkLimits{maxPages: userConfig.MaxPages, maxListings: remainingListings, sortBy: userConfig.SortBy}
+		if pageBudget > 0 && remainingPages < limits.maxPages {
+			limits.maxPages = remainingPages
+		}
+
 		// Check if this is a retry and we need to wait
 		if item.retryCount > 0 {
 			waitMinutes := 3 + (item.retryCount-1) // 3 min for first retry, 4 for second, 5 for third
 			if waitMinutes > 5 {
 				waitMinutes = 5
 			}
-			s.sendStatusUpdate(req.TelegramMessageID, req.UserID, 
-				fmt.Sprintf("⏳ Waiting %d minutes before retrying link %d...", waitMinutes, link.LinkNumber))
+			s.reportProgress(req,
+				fmt.Sprintf("⏳ Waiting %d minutes before retrying link %d...", waitMinutes, link.LinkNumber), nil)
 			log.Printf("Waiting %d minutes before retrying link %d\n", waitMinutes, link.LinkNumber)
 			time.Sleep(time.Duration(waitMinutes) * time.Minute)
 		}
 
 		// Notify user we're starting this link (with clickable URL, no preview)
-		rangeLabel := pricerange.ExtractPriceRangeLabel(link.URL)
+		rangeLabel := linkLabel(link)
+		startLink := func(p *progressState) {
+			p.links = totalLinks
+			p.link = link.LinkNumber
+			p.page, p.pages, p.enriched, p.toEnrich = 0, 0, 0, 0
+		}
 		if item.retryCount > 0 {
-			s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-				fmt.Sprintf("🔄 Retrying link %d/%d (attempt %d/3) [%s]: <a href=\"%s\">open</a>", link.LinkNumber, totalLinks, item.retryCount+1, rangeLabel, link.URL))
+			s.reportProgress(req,
+				fmt.Sprintf("🔄 Retrying link %d/%d (attempt %d/3) [%s]: <a href=\"%s\">open</a>", link.LinkNumber, totalLinks, item.retryCount+1, rangeLabel, link.URL),
+				startLink)
 		} else {
-			s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-				fmt.Sprintf("🔗 Starting link %d/%d [%s]: <a href=\"%s\">open</a>", link.LinkNumber, totalLinks, rangeLabel, link.URL))
+			s.reportProgress(req,
+				fmt.Sprintf("🔗 Starting link %d/%d [%s]: <a href=\"%s\">open</a>", link.LinkNumber, totalLinks, rangeLabel, link.URL),
+				startLink)
 		}
 
 		// Update link status to in_progress
@@ -342,29 +645,42 @@ func (s *Scheduler) processNextRequest() {
 		}
 
 		// Process this link
-		linkListings, linkUnfiltered, pagesFetched, listingsBeforeFilter, linkErr := s.processSearchLink(
-			req, link, userConfig, fetcherInstance, filterInstance, parserInstance,
-			detailFetcher, detailParser, seenListingURLs, cfg,
+		linkListings, linkUnfiltered, pagesFetched, listingsBeforeFilter, parseFailures, linkErr := s.processSearchLink(
+			req, link, limits, fetcherInstance, filterInstance, parserInstance,
+			detailFetcher, detailParser, deduper, cfg, &metrics,
 		)
+		remainingPages -= pagesFetched
+		remainingListings -= len(linkListings)
 
 		if linkErr != nil {
 			errStr := linkErr.Error()
 			log.Printf("Link %d failed: %v\n", link.LinkNumber, linkErr)
 			consecutiveFailures++
 
-			// Block detection: pause after 2 consecutive failures so user can continue later
-			if consecutiveFailures >= 2 {
+			var action linkFailureAction
+			blockStreak, action = s.breaker.decide(blockStreak, linkErr, consecutiveFailures, item.retryCount)
+			switch action {
+			case abortRequest:
+				remaining := make([]db.SearchLink, 0, len(queue))
+				for _, queued := range queue {
+					remaining = append(remaining, queued.link)
+				}
+				s.abortBlockedRequest(req, link, errStr, remaining, blockStreak)
+				return
+			case pauseRequest:
+				// Likely blocked: pause so the user can continue later
+				if errors.Is(linkErr, fetcher.ErrBotChallenge) {
+					s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
+						fmt.Sprintf("🤖 Link %d was served a bot check instead of results.", link.LinkNumber))
+				}
 				_ = s.db.UpdateSearchLinkStatus(link.ID, "pending", nil) // so it gets retried on resume
 				if err := s.db.UpdateRequestStatus(req.ID, "paused"); err != nil {
 					log.Printf("Error updating request status to paused: %v\n", err)
 				}
 				s.sendPausedWithContinueButton(req.TelegramMessageID, req.UserID, req.ID)
-				log.Printf("Request %d paused after %d consecutive failures; user can continue later\n", req.ID, consecutiveFailures)
+				log.Printf("Request %d paused after %d consecutive failures (%v); user can continue later\n", req.ID, consecutiveFailures, linkErr)
 				return
-			}
-
-			// Check if we should retry
-			if item.retryCount < 3 {
+			case retryLink:
 				// Push to end of queue for retry
 				if err := s.db.IncrementSearchLinkRetry(link.ID); err != nil {
 					log.Printf("Error incrementing retry count: %v\n", err)
@@ -379,8 +695,8 @@ func (s *Scheduler) processNextRequest() {
 				}
 				s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
 					fmt.Sprintf("⚠️ Link %d failed, will retry later (attempt %d/3): %s", 
-						link.LinkNumber, item.retryCount+1, truncateError(errStr)))
-			} else {
+						link.LinkNumber, item.retryCount+1, errclass.Message(linkErr)))
+			case failLink:
 				// Max retries reached, mark as permanently failed
 				if err := s.db.UpdateSearchLinkStatus(link.ID, "failed", &errStr); err != nil {
 					log.Printf("Error updating search link status to failed: %v\n", err)
@@ -388,11 +704,12 @@ func (s *Scheduler) processNextRequest() {
 				linksFailed++
 				s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
 					fmt.Sprintf("❌ Link %d permanently failed after 3 attempts: %s", 
-						link.LinkNumber, truncateError(errStr)))
+						link.LinkNumber, errclass.Message(linkErr)))
 			}
 		} else {
 			// Success!
 			consecutiveFailures = 0
+			blockStreak = 0
 			if err := s.db.UpdateSearchLinkStatus(link.ID, "done", nil); err != nil {
 				log.Printf("Error updating search link status to done: %v\n", err)
 			}
@@ -403,9 +720,9 @@ func (s *Scheduler) processNextRequest() {
 			linksSuccessful++
 			totalPagesFetched += pagesFetched
 			totalListingsBeforeFilter += listingsBeforeFilter
+			totalParseFailures += parseFailures
 
-			// Extract price range label from the link URL and set it on listings
-			rangeLabel := pricerange.ExtractPriceRangeLabel(link.URL)
+			// Set the link's price range label on its listings
 			for i := range linkListings {
 				linkListings[i].PriceRangeLabel = rangeLabel
 			}
@@ -413,6 +730,10 @@ func (s *Scheduler) processNextRequest() {
 				linkUnfiltered[i].PriceRangeLabel = rangeLabel
 			}
 
+			listingsKept += len(linkListings)
+			if isWidenLink[link.URL] {
+				widenedAdded += len(linkListings)
+			}
 			allEnrichedListings = append(allEnrichedListings, linkListings...)
 			allUnfilteredListings = append(allUnfilteredListings, linkUnfiltered...)
 
@@ -426,13 +747,21 @@ func (s *Scheduler) processNextRequest() {
 
 			// Append this link's listings to the sheet immediately (filtered + unfiltered mixed)
 			allLinkListings := append(linkListings, linkUnfiltered...)
-			if err := s.writer.AppendListingsToSheet(sheetName, allLinkListings); err != nil {
-				log.Printf("Warning: Failed to append listings to sheet: %v\n", err)
+			appendStart := time.Now()
+			var appendErr error
+			if appendRuns {
+				appendErr = writer.AppendRunListingsToSheet(sheetName, allLinkListings, req.CreatedAt)
+			} else {
+				appendErr = writer.AppendListingsToSheet(sheetName, allLinkListings)
 			}
+			if appendErr != nil {
+				log.Printf("Warning: Failed to append listings to sheet: %v\n", appendErr)
+			}
+			metrics.SheetsWrite += time.Since(appendStart)
 
-			s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-				fmt.Sprintf("✅ Link %d [%s] completed: %d listings found (%d new after dedup)", 
-					link.LinkNumber, rangeLabel, listingsBeforeFilter, len(linkListings)))
+			s.reportProgress(req,
+				fmt.Sprintf("✅ Link %d [%s] completed: %d listings found (%d new after dedup)%s",
+					link.LinkNumber, rangeLabel, listingsBeforeFilter, len(linkListings), formatParseFailures(parseFailures)), nil)
 		}
 	}
 
@@ -446,6 +775,28 @@ func (s *Scheduler) processNextRequest() {
 		return
 	}
 
+	// Rows were appended link by link in scrape order; apply the user's sort once at the end
+	// (a shared run tab keeps the runs in order and has no summary row)
+	finalizeStart := time.Now()
+	if option, ok := filter.LookupSortOption(userConfig.SortBy); ok && option.Column != "" && !appendRuns {
+		if err := writer.SortListingRows(sheetName, option.Column, option.Descending); err != nil {
+			log.Printf("Warning: Failed to sort sheet by %s: %v\n", option.Key, err)
+		}
+	}
+
+	// Fill in the aggregates row at the top of the sheet
+	if !appendRuns {
+		if err := writer.WriteSummary(sheetName, allEnrichedListings); err != nil {
+			log.Printf("Warning: Failed to write summary row: %v\n", err)
+		}
+	}
+	metrics.SheetsWrite += time.Since(finalizeStart)
+	metrics.Total = time.Since(requestStart)
+	if err := s.db.AddRequestMetrics(req.ID, metrics); err != nil {
+		log.Printf("Warning: Failed to save request metrics: %v\n", err)
+	}
+	log.Printf("Request %d timings: %s\n", req.ID, formatMetrics(metrics))
+
 	// Update request counts
 	if err := s.db.UpdateRequestCounts(req.ID, totalFilteredListings, totalPagesFetched); err != nil {
 		log.Printf("Error updating request counts: %v\n", err)
@@ -456,9 +807,12 @@ func (s *Scheduler) processNextRequest() {
 		log.Printf("Error updating request status to done: %v\n", err)
 		return
 	}
+	if err := s.db.RefreshFlatView(); err != nil {
+		log.Printf("Warning: %v\n", err)
+	}
 
 	// Create URL that opens the specific sheet
-	sheetURL := s.createSheetURL(sheetID)
+	sheetURL := s.createSheetURL(req, sheetID)
 
 	// Build price range summary if we have stats
 	var priceRangeSummary string
@@ -477,18 +831,20 @@ func (s *Scheduler) processNextRequest() {
 		successMsg = fmt.Sprintf(
 			"✅ Successfully fetched and added %d listings to Google Sheets!\n\n"+
 				"Found %d listings before filtering.\n"+
-				"Pages: %d fetched (requested: %d)\n\n"+
+				"Pages: %d fetched (requested: %d)%s\n\n"+
 				"View spreadsheet: %s",
-			totalFilteredListings, totalListingsBeforeFilter, totalPagesFetched, userConfig.MaxPages, sheetURL)
+			totalFilteredListings, totalListingsBeforeFilter, totalPagesFetched, userConfig.MaxPages,
+			formatParseFailures(totalParseFailures), sheetURL)
 	} else {
 		successMsg = fmt.Sprintf(
 			"✅ Completed processing %d links!\n\n"+
 				"Links: %d successful, %d failed\n"+
 				"Listings: %d after filtering (from %d total)\n"+
-				"Pages: %d fetched\n\n"+
+				"Pages: %d fetched%s\n\n"+
 				"View spreadsheet: %s",
 			totalLinks, linksSuccessful, linksFailed,
-			totalFilteredListings, totalListingsBeforeFilter, totalPagesFetched, sheetURL)
+			totalFilteredListings, totalListingsBeforeFilter, totalPagesFetched,
+			formatParseFailures(totalParseFailures), sheetURL)
 	}
 
 	// Append price range summary if available
@@ -496,87 +852,349 @@ func (s *Scheduler) processNextRequest() {
 		successMsg += priceRangeSummary
 	}
 
+	if widened {
+		successMsg += fmt.Sprintf("\n\n🔍 Search widened without the maximum price: %d listing(s) added (labeled \"widened\" in the Price Range column).",
+			widenedAdded)
+	}
+
+	if req.SavedSearchID.Valid {
+		successMsg += "\n\n" + formatSavedSearchRun(int(req.SavedSearchID.Int64), previousRuns, totalFilteredListings)
+	}
+
+	successMsg += "\n\n⏱ " + formatMetrics(metrics)
+
+	if len(skippedLinks) > 0 {
+		slices.Sort(skippedLinks) // retried links may be skipped out of order
+		successMsg += fmt.Sprintf("\n\n⚠️ Request limits reached (%s): %d link(s) skipped (%s). Use /retry %d to fetch them.",
+			skipReason, len(skippedLinks), formatLinkNumbers(skippedLinks), req.ID)
+	}
+
 	s.sendStatusUpdate(req.TelegramMessageID, req.UserID, successMsg)
+	if userConfig.ForwardTo != 0 && userConfig.ForwardTo != req.UserID {
+		s.sendStatusUpdate(0, userConfig.ForwardTo, fmt.Sprintf("📨 Forwarded from user %d (request #%d):\n\n", req.UserID, req.ID)+successMsg)
+	}
+
+	if req.SavedSearchID.Valid {
+		s.notifyRunChanges(req)
+	}
+}
+
+// formatSavedSearchRun describes the result of a scheduled run of a saved search
+func formatSavedSearchRun(savedSearchID int, previousRuns int, newListings int) string {
+	switch {
+	case previousRuns == 0:
+		return fmt.Sprintf("⏰ First run of saved search #%d: later runs report only listings not found before.", savedSearchID)
+	case newListings == 0:
+		return fmt.Sprintf("⏰ Saved search #%d: no new listings since the last run.", savedSearchID)
+	default:
+		return fmt.Sprintf("⏰ Saved search #%d: %d new listing(s) since the last run.", savedSearchID, newListings)
+	}
+}
+
+// widenedSearchURLs returns the URLs of the auto-widen pass: each of the request's
+// original URLs that has a price_max, without it
+func widenedSearchURLs(requestURL string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range strings.Split(requestURL, "\n") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		widened, ok, err := pricerange.WidenURL(u)
+		if err != nil || !ok || seen[widened] {
+			continue
+		}
+		seen[widened] = true
+		urls = append(urls, widened)
+	}
+	return urls
+}
+
+// widenSearch creates the links of the auto-widen pass and tells the user about it.
+// It returns no links if the search has no maximum price to drop.
+func (s *Scheduler) widenSearch(req *db.Request, widenURLs []string, kept int, floor int) []db.SearchLink {
+	if len(widenURLs) == 0 {
+		s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
+			fmt.Sprintf("ℹ️ Only %d listing(s) kept (minimum %d), but the search has no maximum price to drop, so it was not widened.", kept, floor))
+		return nil
+	}
+
+	links, err := s.db.AppendSearchLinks(req.ID, widenURLs)
+	if err != nil {
+		log.Printf("Error creating widened search links: %v\n", err)
+		return nil
+	}
+	s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
+		fmt.Sprintf("🔍 Only %d listing(s) kept (minimum %d): widening the search once without the maximum price (%d extra link(s)).",
+			kept, floor, len(links)))
+	return links
+}
+
+// linkFailureAction is what the scheduler does after a search link failed
+type linkFailureAction int
+
+const (
+	retryLink    linkFailureAction = iota // requeue the link at the end of the queue
+	failLink                              // give up on the link, the request goes on
+	pauseRequest                          // stop the request so the user can continue it later
+	abortRequest                          // fail the request, its links keep getting blocked
+)
+
+// decideLinkFailure picks the reaction to a failed link. A bot challenge pauses the
+// request right away since the following links would be blocked too, as do two failures
+// in a row. Other failures (navigation errors, rate limiting, unparseable pages) are
+// retried up to 3 times.
+func decideLinkFailure(err error, consecutiveFailures int, retryCount int) linkFailureAction {
+	switch {
+	case errors.Is(err, fetcher.ErrBotChallenge):
+		return pauseRequest
+	case consecutiveFailures >= 2:
+		return pauseRequest
+	case retryCount < 3:
+		return retryLink
+	default:
+		return failLink
+	}
+}
+
+// defaultPageBudget is the page budget of users who haven't set one (MAX_TOTAL_PAGES,
+// 0 = unlimited), set with Configure at startup. It bounds the runtime of requests split
+// into many links.
+var defaultPageBudget int
+
+// Settings are the scheduler options taken from the environment
+type Settings struct {
+	DefaultPageBudget int // see DefaultPageBudget
+}
+
+// SettingsFromEnv reads the scheduler settings from MAX_TOTAL_PAGES
+func SettingsFromEnv() Settings {
+	return Settings{DefaultPageBudget: pageBudgetFromEnv()}
+}
+
+// Configure replaces the scheduler settings. Call it once at startup, before the
+// scheduler starts: the settings are read without locking.
+func Configure(s Settings) {
+	defaultPageBudget = s.DefaultPageBudget
+}
+
+// pageBudgetFromEnv reads MAX_TOTAL_PAGES, falling back to 0 (unlimited) when it is unset or invalid
+func pageBudgetFromEnv() int {
+	value := os.Getenv("MAX_TOTAL_PAGES")
+	if value == "" {
+		return 0
+	}
+	budget, err := strconv.Atoi(value)
+	if err != nil || budget < 0 {
+		log.Printf("Warning: Invalid MAX_TOTAL_PAGES=%q (expected a page count), using unlimited\n", value)
+		return 0
+	}
+	log.Printf("Using MAX_TOTAL_PAGES=%d\n", budget)
+	return budget
+}
+
+// DefaultPageBudget returns the page budget applied when a user's is 0 (0 = unlimited)
+func DefaultPageBudget() int {
+	return defaultPageBudget
+}
+
+// EffectivePageBudget returns the page budget shared by all links of a request: the
+// user's own budget, or the default when they haven't set one. 0 means unlimited.
+func EffectivePageBudget(maxTotalPages int) int {
+	if maxTotalPages > 0 {
+		return maxTotalPages
+	}
+	return defaultPageBudget
+}
+
+// formatLinkNumbers lists link numbers compactly, e.g. "3, 5-8"
+func formatLinkNumbers(numbers []int) string {
+	var parts []string
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(numbers[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// linkLimits caps the work done for a single search link
+type linkLimits struct {
+	maxPages    int    // pages to fetch, already capped by the remaining request page budget
+	maxListings int    // filtered listings to enrich, 0 = no cap
+	sortBy      string // sort option deciding which listings are kept when capped
+}
+
+// detailFieldsFor returns the detail field groups to extract for the user: the groups
+// they selected plus those their filters and sort order depend on. nil extracts all.
+func detailFieldsFor(userConfig *db.UserConfig) parser.DetailFields {
+	fields, err := parser.ParseDetailFields(userConfig.EnrichFields)
+	if err != nil {
+		log.Printf("Warning: Invalid enrich fields %q, extracting all: %v\n", userConfig.EnrichFields, err)
+		return nil
+	}
+	if fields == nil {
+		return nil
+	}
+	if userConfig.SuperhostOnly || userConfig.GuestFavoriteOnly {
+		fields[parser.DetailSuperhost] = true
+	}
+	if userConfig.MaxPricePerGuest > 0 {
+		fields[parser.DetailRooms] = true
+	}
+	switch userConfig.SortBy {
+	case "activity_desc":
+		fields[parser.DetailReviews] = true
+	case "price_per_guest_asc", "price_per_bedroom_asc":
+		fields[parser.DetailRooms] = true
+	}
+	return fields
 }
 
-// processSearchLink processes a single search link and returns the enriched listings
+// processSearchLink processes a single search link and returns the enriched listings.
+// parseFailures counts fetched pages that could not be parsed; if every page fails
+// to parse the link is reported as failed so it gets retried.
 func (s *Scheduler) processSearchLink(
 	req *db.Request,
 	link db.SearchLink,
-	userConfig *db.UserConfig,
+	limits linkLimits,
 	fetcherInstance fetcher.Fetcher,
 	filterInstance *filter.Filter,
 	parserInstance *parser.Parser,
 	detailFetcher *fetcher.DetailFetcher,
 	detailParser *parser.DetailParser,
-	seenListingURLs map[string]int, // Shared across links for deduplication
+	deduper *filter.Deduper, // shared across links of the request
 	cfg *config.FilterConfig,
-) (enrichedListings []models.Listing, unfilteredListings []models.Listing, pagesFetched int, totalListings int, err error) {
-
-	// Fetch pages for this link
-	log.Printf("Fetching link %d: %s (maxPages: %d)\n", link.LinkNumber, shortenURL(link.URL), userConfig.MaxPages)
-	htmlPages, err := fetcherInstance.Fetch(link.URL, userConfig.MaxPages)
-	if err != nil {
-		return nil, nil, 0, 0, fmt.Errorf("fetch failed: %w", err)
-	}
-	pagesFetched = len(htmlPages)
-
-	if len(htmlPages) == 0 {
-		return nil, nil, 0, 0, fmt.Errorf("no HTML pages collected")
-	}
+	metrics *db.RequestMetrics, // fetch and enrich durations are added to it
+) (enrichedListings []models.Listing, unfilteredListings []models.Listing, pagesFetched int, totalListings int, parseFailures int, err error) {
 
-	// Parse listings
+	// Fetch pages for this link, parsing and filtering each page as soon as it arrives
+	log.Printf("Fetching link %d: %s (maxPages: %d)\n", link.LinkNumber, shortenURL(link.URL), limits.maxPages)
 	rangeLabel := pricerange.ExtractPriceRangeLabel(link.URL)
-	var allListings []models.Listing
-	for i, html := range htmlPages {
-		pageNum := i + 1
-		log.Printf("Link %d: Parsing page %d/%d\n", link.LinkNumber, pageNum, pagesFetched)
-
+	var allListings, filteredListings []models.Listing
+	var parseDuration time.Duration
+
+	// Fetchers that report pages as they fetch them keep the user posted during the fetch
+	// phase, which takes minutes for fetchers that return all pages at once. For the
+	// others, pages are shown as they are parsed.
+	throttle := &pageThrottle{interval: pageStatusInterval}
+	reportsFetched := fetcher.SetPageProgress(fetcherInstance, func(pageNum, maxPages int) {
+		counters := func(p *progressState) { p.page, p.pages = pageNum, maxPages }
+		if !throttle.allow(pageNum, maxPages, time.Now()) {
+			s.reportProgress(req, "", counters)
+			return
+		}
 		pageURL := buildSearchPageURL(link.URL, pageNum)
-		s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-			fmt.Sprintf("📄 Link %d [%s]: Parsing page %d/%d - <a href=\"%s\">open</a>", link.LinkNumber, rangeLabel, pageNum, pagesFetched, pageURL))
+		s.reportProgress(req,
+			fmt.Sprintf("🌐 Link %d [%s]: Fetched page %d/%d (%d%%) - <a href=\"%s\">open</a>",
+				link.LinkNumber, rangeLabel, pageNum, maxPages, percent(pageNum, maxPages), pageURL),
+			counters)
+	})
+	defer fetcher.SetPageProgress(fetcherInstance, nil)
+
+	fetchStart := time.Now()
+	err = fetcher.FetchEach(fetcherInstance, link.URL, limits.maxPages, func(pageNum int, html string) error {
+		pagesFetched = pageNum
+		parseStart := time.Now()
+		defer func() { parseDuration += time.Since(parseStart) }()
+		log.Printf("Link %d: Parsing page %d (max %d)\n", link.LinkNumber, pageNum, limits.maxPages)
+
+		if !reportsFetched {
+			pageURL := buildSearchPageURL(link.URL, pageNum)
+			s.reportProgress(req,
+				fmt.Sprintf("📄 Link %d [%s]: Parsing page %d/%d (%d%%) - <a href=\"%s\">open</a>",
+					link.LinkNumber, rangeLabel, pageNum, limits.maxPages, percent(pageNum, limits.maxPages), pageURL),
+				func(p *progressState) { p.page, p.pages = pageNum, limits.maxPages })
+		}
 
 		listings, err := parserInstance.ParseHTML(html)
 		if err != nil {
 			log.Printf("Warning: Failed to parse page %d: %v\n", pageNum, err)
-			continue
+			parseFailures++
+			return nil
 		}
 		log.Printf("Link %d: Parsed page %d: found %d listings\n", link.LinkNumber, pageNum, len(listings))
-		
+
 		// Set page number and link number for each listing
 		for j := range listings {
 			listings[j].PageNumber = pageNum
 			listings[j].LinkNumber = link.LinkNumber
 		}
 		allListings = append(allListings, listings...)
-		htmlPages[i] = "" // release HTML
+		filteredListings = append(filteredListings, filterInstance.ApplyFilters(listings)...)
+		return nil
+	})
+	// Parsing happens while fetching; keep it out of the fetch timing
+	fetchDuration := time.Since(fetchStart) - parseDuration
+	metrics.Fetch += fetchDuration
+	if err := s.db.UpdateSearchLinkFetchDuration(link.ID, fetchDuration); err != nil {
+		log.Printf("Warning: Failed to save fetch duration for link %d: %v\n", link.LinkNumber, err)
+	}
+	if errors.Is(err, fetcher.ErrNoListings) {
+		// A valid empty search (e.g. a price range with no homes), not worth retrying
+		log.Printf("Link %d: search returned no listings\n", link.LinkNumber)
+		return nil, nil, 1, 0, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, 0, 0, fmt.Errorf("fetch failed: %w", err)
+	}
+
+	if pagesFetched == 0 {
+		return nil, nil, 0, 0, 0, fetcher.ErrNoPages
 	}
-	htmlPages = nil
 
 	totalListings = len(allListings)
-	log.Printf("Link %d: Total listings parsed: %d\n", link.LinkNumber, totalListings)
+	log.Printf("Link %d: Total listings parsed: %d (%d pages failed to parse)\n", link.LinkNumber, totalListings, parseFailures)
+
+	// Every page fetched but none parsed: fail the link so it gets retried instead of reporting zero listings
+	if parseFailures == pagesFetched {
+		return nil, nil, pagesFetched, 0, parseFailures, fmt.Errorf("all %d fetched pages failed to parse", pagesFetched)
+	}
 
 	// 0 listings is valid (e.g. empty price range like 0–50$) — treat as success so we don't fail/retry the link
 	if len(allListings) == 0 {
-		return nil, nil, pagesFetched, 0, nil
+		return nil, nil, pagesFetched, 0, parseFailures, nil
+	}
+
+	// Saved search runs: collect every listing the search filters keep, also the ones earlier runs
+	// reported, to compare the run with the earlier ones once it finished
+	if req.SavedSearchID.Valid {
+		if err := s.db.AddRunListings(req.ID, filteredListings); err != nil {
+			log.Printf("Warning: Failed to record listings of saved search run %d: %v\n", req.ID, err)
+		}
 	}
 
-	// Apply filters
-	filteredListings := filterInstance.ApplyFilters(allListings)
-	
 	// Deduplicate against already seen listings
 	uniqueFilteredListings := make([]models.Listing, 0, len(filteredListings))
 	for _, listing := range filteredListings {
-		if _, seen := seenListingURLs[listing.URL]; !seen {
-			seenListingURLs[listing.URL] = link.LinkNumber
+		if isNew, firstLink := deduper.Add(listing.URL, link.LinkNumber); isNew {
 			uniqueFilteredListings = append(uniqueFilteredListings, listing)
 		} else {
-			log.Printf("Link %d: Skipping duplicate listing (first seen in link %d): %s\n", 
-				link.LinkNumber, seenListingURLs[listing.URL], extractURLPath(listing.URL))
+			log.Printf("Link %d: Skipping duplicate listing (first seen in link %d): %s\n",
+				link.LinkNumber, firstLink, extractURLPath(listing.URL))
 		}
 	}
 	filteredListings = uniqueFilteredListings
 
+	// Cap the listings to enrich, keeping the best ones by the user's sort. The rest are
+	// written with the unfiltered listings (they are already marked as seen).
+	if limits.maxListings > 0 && len(filteredListings) > limits.maxListings {
+		filter.SortListings(filteredListings, limits.sortBy)
+		unfilteredListings = append(unfilteredListings, filteredListings[limits.maxListings:]...)
+		s.reportProgress(req,
+			fmt.Sprintf("✂️ Link %d: %d listings matched, enriching only the first %d (Max Listings)",
+				link.LinkNumber, len(filteredListings), limits.maxListings), nil)
+		filteredListings = filteredListings[:limits.maxListings]
+	}
+
 	filteredCount := len(filteredListings)
 	log.Printf("Link %d: %d listings after filtering and deduplication\n", link.LinkNumber, filteredCount)
 
@@ -589,8 +1207,7 @@ func (s *Scheduler) processSearchLink(
 	// Keep unfiltered listings (deduplicated)
 	for _, listing := range allListings {
 		if !filteredURLs[listing.URL] {
-			if _, seen := seenListingURLs[listing.URL]; !seen {
-				seenListingURLs[listing.URL] = link.LinkNumber
+			if isNew, _ := deduper.Add(listing.URL, link.LinkNumber); isNew {
 				listing.LinkNumber = link.LinkNumber
 				unfilteredListings = append(unfilteredListings, listing)
 			}
@@ -599,15 +1216,16 @@ func (s *Scheduler) processSearchLink(
 
 	if filteredCount == 0 {
 		// No filtered listings, but that's not an error
-		s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-			fmt.Sprintf("📋 Link %d: %d listings parsed, 0 matched filters", link.LinkNumber, totalListings))
-		return nil, unfilteredListings, pagesFetched, totalListings, nil
+		s.reportProgress(req,
+			fmt.Sprintf("📋 Link %d: %d listings parsed, 0 matched filters", link.LinkNumber, totalListings), nil)
+		return nil, unfilteredListings, pagesFetched, totalListings, parseFailures, nil
 	}
 
 	// Notify about filtering results
-	s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-		fmt.Sprintf("📋 Link %d: %d listings parsed, %d matched filters. Enriching details...", 
-			link.LinkNumber, totalListings, filteredCount))
+	s.reportProgress(req,
+		fmt.Sprintf("📋 Link %d: %d listings parsed, %d matched filters. Enriching details...",
+			link.LinkNumber, totalListings, filteredCount),
+		func(p *progressState) { p.enriched, p.toEnrich = 0, filteredCount })
 
 	// Save basic listings to database
 	urlToIDMap := make(map[string]int)
@@ -631,24 +1249,42 @@ func (s *Scheduler) processSearchLink(
 		}
 
 		// Save basic listing with link number
-		err := s.db.SaveListingWithLinkNumber(req.ID, link.LinkNumber, listing.Title, listing.URL, price, currency, stars, reviewCount)
+		listingID, err := s.db.SaveListingWithLinkNumber(req.ID, link.LinkNumber, listing.Title, listing.URL, price, currency, stars, reviewCount)
 		if err != nil {
 			log.Printf("Warning: Failed to save listing to database: %v\n", err)
 			continue
 		}
-
-		listingID, err := s.db.GetListingIDByURL(req.ID, listing.URL)
-		if err != nil {
-			log.Printf("Warning: Failed to get listing ID: %v\n", err)
-			continue
-		}
 		urlToIDMap[listing.URL] = listingID
 	}
 
-	// Enrich listings with detail pages
-	enrichedListings = s.enrichListings(filteredListings, urlToIDMap, detailFetcher, detailParser, req, link.LinkNumber)
+	// Enrich listings with detail pages, unless this is a quick request
+	if req.SkipEnrichment {
+		log.Printf("Link %d: quick request, skipping enrichment of %d listings\n", link.LinkNumber, len(filteredListings))
+		enrichedListings = filteredListings
+	} else {
+		enrichStart := time.Now()
+		enrichedListings = s.enrichListings(filteredListings, urlToIDMap, detailFetcher, detailParser, req, link.LinkNumber)
+		metrics.Enrich += time.Since(enrichStart)
+	}
 
-	return enrichedListings, unfilteredListings, pagesFetched, totalListings, nil
+	// The per-guest price cap is in USD, so convert before the detail filters
+	s.usdConverter.ApplyUSDPrices(enrichedListings)
+
+	// Drop listings that fail detail-based filters; they go to the sheet with the unfiltered ones
+	var droppedListings []models.Listing
+	enrichedListings, droppedListings = filterInstance.ApplyDetailFilters(enrichedListings)
+	if len(droppedListings) > 0 {
+		log.Printf("Link %d: %d listings removed by detail filters\n", link.LinkNumber, len(droppedListings))
+		unfilteredListings = append(unfilteredListings, droppedListings...)
+	}
+
+	// Newest review dates are only known after enrichment
+	scoring.ApplyActivityScores(enrichedListings, time.Now(), s.activityHalfLifeDays)
+
+	// Unfiltered listings are written to the sheet too, so they need a comparable price
+	s.usdConverter.ApplyUSDPrices(unfilteredListings)
+
+	return enrichedListings, unfilteredListings, pagesFetched, totalListings, parseFailures, nil
 }
 
 // enrichListings fetches detail pages and enriches listings
@@ -693,15 +1329,14 @@ func (s *Scheduler) enrichListings(
 			for job := range jobs {
 				// Notify user every 5th detail page (with link, no preview)
 				if job.index%5 == 0 {
-					title := truncateText(job.listing.Title, 40)
+					title := sanitize.HTML(job.listing.Title, 40)
 					if title == "" {
 						title = "listing"
 					}
-					title = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(title, "&", "&amp;"), "<", "&lt;"), ">", "&gt;")
-					s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-						fmt.Sprintf("🔍 Link %d: Enriching %d/%d - <a href=\"%s\">%s</a>", linkNumber, job.index+1, filteredCount, job.listing.URL, title))
+					s.reportProgress(req,
+						fmt.Sprintf("🔍 Link %d: Enriching %d/%d - <a href=\"%s\">%s</a>", linkNumber, job.index+1, filteredCount, html.EscapeString(job.listing.URL), title), nil)
 				}
-				detailHTML, err := detailFetcher.FetchDetailPage(job.listing.URL)
+				detailHTML, err := detailFetcher.FetchDetailPage(s.ctx, job.listing.URL)
 				if err != nil {
 					log.Printf("Worker %d: Failed to fetch detail page: %v\n", workerID, err)
 					results <- struct {
@@ -728,20 +1363,30 @@ func (s *Scheduler) enrichListings(
 				}
 
 				// Merge detail data
+				job.listing.Enriched = true
 				job.listing.IsSuperhost = detailData.IsSuperhost
 				job.listing.IsGuestFavorite = detailData.IsGuestFavorite
 				job.listing.Bedrooms = detailData.Bedrooms
 				job.listing.Bathrooms = detailData.Bathrooms
 				job.listing.Beds = detailData.Beds
+				job.listing.MaxGuests = detailData.MaxGuests
+				job.listing.MinNights = detailData.MinNights
+				job.listing.CheckIn = detailData.CheckIn
+				job.listing.CheckOut = detailData.CheckOut
+				job.listing.SelfCheckIn = detailData.SelfCheckIn
 				job.listing.Description = detailData.Description
 				job.listing.HouseRules = detailData.HouseRules
 				job.listing.NewestReviewDate = detailData.NewestReviewDate
 				job.listing.Reviews = detailData.Reviews
+				if job.listing.Location == "" {
+					job.listing.Location = detailData.Location
+				}
 
 				// Update database
-				var isSuperhost, isGuestFavorite *bool
+				var isSuperhost, isGuestFavorite, selfCheckIn *bool
 				var bedrooms, bathrooms, beds *float64
-				var description, houseRules *string
+				var maxGuests, minNights *int
+				var checkIn, checkOut, description, houseRules *string
 				var newestReviewDate *time.Time
 
 				if job.listing.Bedrooms > 0 {
@@ -753,8 +1398,26 @@ func (s *Scheduler) enrichListings(
 				if job.listing.Beds > 0 {
 					beds = &job.listing.Beds
 				}
-				isSuperhost = &job.listing.IsSuperhost
-				isGuestFavorite = &job.listing.IsGuestFavorite
+				if job.listing.MaxGuests > 0 {
+					maxGuests = &job.listing.MaxGuests
+				}
+				if job.listing.MinNights > 0 {
+					minNights = &job.listing.MinNights
+				}
+				if job.listing.CheckIn != "" {
+					checkIn = &job.listing.CheckIn
+				}
+				if job.listing.CheckOut != "" {
+					checkOut = &job.listing.CheckOut
+				}
+				// Booleans of skipped field groups are unknown, not false
+				if detailParser.Fields.Has(parser.DetailSuperhost) {
+					isSuperhost = &job.listing.IsSuperhost
+					isGuestFavorite = &job.listing.IsGuestFavorite
+				}
+				if detailParser.Fields.Has(parser.DetailRules) {
+					selfCheckIn = &job.listing.SelfCheckIn
+				}
 				if job.listing.Description != "" {
 					description = &job.listing.Description
 				}
@@ -763,7 +1426,7 @@ func (s *Scheduler) enrichListings(
 				}
 				newestReviewDate = job.listing.NewestReviewDate
 
-				s.db.UpdateListingDetails(job.listingID, isSuperhost, isGuestFavorite, bedrooms, bathrooms, beds, description, houseRules, newestReviewDate)
+				s.db.UpdateListingDetails(job.listingID, isSuperhost, isGuestFavorite, bedrooms, bathrooms, beds, maxGuests, minNights, checkIn, checkOut, selfCheckIn, description, houseRules, newestReviewDate)
 
 				if len(job.listing.Reviews) > 0 {
 					s.db.SaveReviews(job.listingID, job.listing.Reviews)
@@ -780,7 +1443,7 @@ func (s *Scheduler) enrichListings(
 		}(w)
 	}
 
-	// Request timing (for reference):
+	// Request timing (for reference, defaults; see fetcher.Timings for env overrides):
 	// - Search first page: 3s after load (rod_scraper), then WaitStable.
 	// - Between search pages: 7s before next page, then 5s+WaitStable+3s after navigate.
 	// - Between links: no extra delay.
@@ -829,11 +1492,12 @@ func (s *Scheduler) enrichListings(
 			enrichedListings[result.index] = result.listing
 		}
 		
-		// Send update every 20 listings or on completion
+		// Send update every 20 listings or on completion; the progress bar counts every one
+		var status string
 		if processedCount%20 == 0 || processedCount == filteredCount {
-			s.sendStatusUpdate(req.TelegramMessageID, req.UserID,
-				fmt.Sprintf("🔍 Link %d: Enriched %d/%d listings...", linkNumber, processedCount, filteredCount))
+			status = fmt.Sprintf("🔍 Link %d: Enriched %d/%d listings...", linkNumber, processedCount, filteredCount)
 		}
+		s.reportProgress(req, status, func(p *progressState) { p.enriched, p.toEnrich = processedCount, filteredCount })
 	}
 
 	// Filter out empty (failed) listings
@@ -869,57 +1533,89 @@ func shortenURL(urlStr string) string {
 	// Remove www.
 	urlStr = strings.TrimPrefix(urlStr, "www.")
 	// Truncate if too long
-	if len(urlStr) > 50 {
-		urlStr = urlStr[:47] + "..."
-	}
+	urlStr = sanitize.Truncate(urlStr, 50)
 	return urlStr
 }
 
-// truncateError truncates error message for display
-func truncateError(errStr string) string {
-	if len(errStr) > 100 {
-		return errStr[:97] + "..."
+// formatMetrics formats request stage durations for logs and status messages
+func formatMetrics(m db.RequestMetrics) string {
+	round := func(d time.Duration) time.Duration { return d.Round(time.Second) }
+	return fmt.Sprintf("Browser %s · Fetch %s · Enrich %s · Sheets %s · Total %s",
+		round(m.BrowserLaunch), round(m.Fetch), round(m.Enrich), round(m.SheetsWrite), round(m.Total))
+}
+
+// formatParseFailures returns " (N pages failed to parse)" for status messages, or "" when there were none
+func formatParseFailures(count int) string {
+	switch {
+	case count == 0:
+		return ""
+	case count == 1:
+		return " (1 page failed to parse)"
+	default:
+		return fmt.Sprintf(" (%d pages failed to parse)", count)
 	}
-	return errStr
 }
 
-// truncateText truncates text to maxLen runes for display (e.g. listing title in notifications)
-func truncateText(s string, maxLen int) string {
-	r := []rune(s)
-	if len(r) <= maxLen {
-		return s
+// stayDatesInfo describes the request's stay dates for the sheet metadata, so it is clear
+// whether prices are for those dates or Airbnb's "from" estimates
+func stayDatesInfo(req *db.Request) string {
+	if !req.CheckIn.Valid || !req.CheckOut.Valid {
+		return "None (prices are \"from\" estimates)"
 	}
-	return string(r[:maxLen-3]) + "..."
+	nights := int(req.CheckOut.Time.Sub(req.CheckIn.Time).Hours() / 24)
+	return fmt.Sprintf("%s to %s (%d nights)", req.CheckIn.Time.Format("2006-01-02"), req.CheckOut.Time.Format("2006-01-02"), nights)
 }
 
-// handleRequestError handles errors during request processing
+// handleRequestError handles errors during request processing. The user gets an
+// explanation of the error's class; the error itself is only logged.
 func (s *Scheduler) handleRequestError(req *db.Request, err error) {
+	log.Printf("Request %d failed: %v\n", req.ID, err)
 	if updateErr := s.db.UpdateRequestStatus(req.ID, "failed"); updateErr != nil {
 		log.Printf("Error updating request status to failed: %v\n", updateErr)
 	}
 
-	errorMsg := fmt.Sprintf("❌ Error processing request: %v", err)
+	errorMsg := fmt.Sprintf("❌ Request #%d failed. %s", req.ID, errclass.Message(err))
 	s.sendStatusUpdate(req.TelegramMessageID, req.UserID, errorMsg)
 }
 
+// abortBlockedRequest fails a request whose links keep getting blocked: the failed link
+// and the remaining ones are marked failed so /retry can fetch them once the block is lifted
+func (s *Scheduler) abortBlockedRequest(req *db.Request, failed db.SearchLink, errStr string, remaining []db.SearchLink, blockStreak int) {
+	if err := s.db.UpdateSearchLinkStatus(failed.ID, "failed", &errStr); err != nil {
+		log.Printf("Error updating search link status to failed: %v\n", err)
+	}
+	skipErr := fmt.Sprintf("skipped: request aborted after %d blocked links in a row", blockStreak)
+	for _, link := range remaining {
+		if err := s.db.UpdateSearchLinkStatus(link.ID, "failed", &skipErr); err != nil {
+			log.Printf("Error updating search link status to failed: %v\n", err)
+		}
+	}
+	if err := s.db.UpdateRequestStatus(req.ID, "failed"); err != nil {
+		log.Printf("Error updating request status to failed: %v\n", err)
+	}
+	log.Printf("Request %d aborted after %d blocked links in a row (%s); %d link(s) not fetched\n",
+		req.ID, blockStreak, errStr, len(remaining)+1)
+
+	s.sendStatusUpdate(req.TelegramMessageID, req.UserID, fmt.Sprintf(
+		"🛑 Request stopped: %d links in a row were blocked by Airbnb.\n\n"+
+			"Retrying now would only prolong the block. Listings found so far are in the sheet; "+
+			"try again later with /retry %d to fetch the remaining %d link(s).",
+		blockStreak, req.ID, len(remaining)+1))
+}
+
 func releaseMemory() {
 	runtime.GC()
 	debug.FreeOSMemory()
 }
 
 
-// createSheetURL creates a URL that opens a specific sheet in the spreadsheet
-func (s *Scheduler) createSheetURL(sheetID int64) string {
-	// Extract spreadsheet ID from the base URL
-	spreadsheetID := sheets.ExtractSpreadsheetID(s.spreadsheetURL)
-	if spreadsheetID == "" {
-		// Fallback to original URL if we can't extract ID
-		return s.spreadsheetURL
+// createSheetURL creates a URL that opens a specific sheet in the request's spreadsheet:
+// its own one if it has one, the shared one otherwise
+func (s *Scheduler) createSheetURL(req *db.Request, sheetID int64) string {
+	if req.SpreadsheetID.Valid {
+		return sheets.TabURL(sheets.SpreadsheetURL(req.SpreadsheetID.String), sheetID)
 	}
-
-	// Create URL with gid parameter to open specific sheet
-	// Format: https://docs.google.com/spreadsheets/d/SPREADSHEET_ID/edit#gid=SHEET_ID
-	return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit#gid=%d", spreadsheetID, sheetID)
+	return sheets.TabURL(s.spreadsheetURL, sheetID)
 }
 
 // extractURLPath extracts the path from a URL, removing the domain
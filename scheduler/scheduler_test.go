@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"bnb-fetcher/db"
+	"bnb-fetcher/fetcher"
+	"bnb-fetcher/models"
+)
+
+func TestDecideLinkFailure(t *testing.T) {
+	navigationErr := fmt.Errorf("fetch failed: %w", fmt.Errorf("%w: timeout", fetcher.ErrNavigation))
+	challengeErr := fmt.Errorf("fetch failed: %w", fetcher.ErrBotChallenge)
+	rateLimitErr := fmt.Errorf("fetch failed: %w", fetcher.ErrRateLimited)
+
+	tests := []struct {
+		name                string
+		err                 error
+		consecutiveFailures int
+		retryCount          int
+		want                linkFailureAction
+	}{
+		{"navigation error is retried", navigationErr, 1, 0, retryLink},
+		{"rate limit is retried", rateLimitErr, 1, 1, retryLink},
+		{"parse failure is retried", errors.New("all 3 fetched pages failed to parse"), 1, 2, retryLink},
+		{"retries exhausted", navigationErr, 1, 3, failLink},
+		{"bot challenge pauses at once", challengeErr, 1, 0, pauseRequest},
+		{"second failure in a row pauses", navigationErr, 2, 0, pauseRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideLinkFailure(tt.err, tt.consecutiveFailures, tt.retryCount); got != tt.want {
+				t.Errorf("decideLinkFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWidenedSearchURLs(t *testing.T) {
+	requestURL := "https://www.airbnb.com/s/Hanoi/homes?adults=2&price_max=100\n" +
+		"https://www.airbnb.com/s/Hanoi/homes?price_max=150&adults=2\n" + // same search once widened
+		"https://www.airbnb.com/s/Hue/homes?adults=2\n" + // no price_max, nothing to widen
+		"https://www.airbnb.com/s/Hoi-An/homes?price_min=20&price_max=80"
+
+	got := widenedSearchURLs(requestURL)
+	want := []string{
+		"https://www.airbnb.com/s/Hanoi/homes?adults=2",
+		"https://www.airbnb.com/s/Hoi-An/homes?price_min=20",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("widenedSearchURLs() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("widenedSearchURLs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffRuns(t *testing.T) {
+	prev := []models.Listing{
+		{URL: "https://www.airbnb.com/rooms/1?check_in=2026-06-01", Price: 100, Currency: "USD"}, // unchanged
+		{URL: "https://www.airbnb.com/rooms/2", Price: 100, Currency: "USD"},                     // cheaper now
+		{URL: "https://www.airbnb.com/rooms/3", Price: 100, Currency: "USD"},                     // more expensive now
+		{URL: "https://www.airbnb.com/rooms/4", Price: 100, Currency: "USD"},                     // removed
+		{URL: "https://www.airbnb.com/rooms/5", Price: 100, Currency: "USD"},                     // now in another currency
+		{URL: "https://www.airbnb.com/rooms/plus/8", Price: 100, Currency: "USD"},                // now linked without plus
+	}
+	curr := []models.Listing{
+		{URL: "https://www.airbnb.com/rooms/1?check_in=2026-07-01", Price: 100, Currency: "USD"},
+		{URL: "https://www.airbnb.com/rooms/2", Price: 80, Currency: "USD"},
+		{URL: "https://www.airbnb.com/rooms/3", Price: 120, Currency: "USD"},
+		{URL: "https://www.airbnb.com/rooms/5", Price: 90, Currency: "EUR"},
+		{URL: "https://www.airbnb.com/rooms/6", Price: 50, Currency: "USD"}, // new
+		{URL: "https://www.airbnb.com/rooms/6", Price: 50, Currency: "USD"}, // found again by another link
+		{URL: "https://www.airbnb.com/rooms/7"},                             // new, without a price
+		{URL: "https://www.airbnb.de/rooms/8", Price: 100, Currency: "USD"},
+	}
+
+	added, pricedDown := diffRuns(prev, curr)
+	paths := func(listings []models.Listing) string {
+		var out []string
+		for _, listing := range listings {
+			out = append(out, extractURLPath(listing.URL))
+		}
+		return strings.Join(out, ",")
+	}
+	if got := paths(added); got != "/rooms/6,/rooms/7" {
+		t.Errorf("diffRuns() added = %s, want /rooms/6,/rooms/7", got)
+	}
+	if got := paths(pricedDown); got != "/rooms/2" {
+		t.Errorf("diffRuns() pricedDown = %s, want /rooms/2", got)
+	}
+
+	if added, pricedDown := diffRuns(prev, nil); len(added) != 0 || len(pricedDown) != 0 {
+		t.Errorf("diffRuns() of an empty run = (%v, %v), want nothing", added, pricedDown)
+	}
+}
+
+func TestFormatRunChanges(t *testing.T) {
+	if got := formatRunChanges(3, nil, nil); got != "" {
+		t.Errorf("formatRunChanges() without changes = %q, want empty", got)
+	}
+
+	added := make([]models.Listing, maxRunChangeEntries+2)
+	for i := range added {
+		added[i] = models.Listing{Title: fmt.Sprintf("Home %d", i), URL: fmt.Sprintf("https://www.airbnb.com/rooms/%d", i)}
+	}
+	got := formatRunChanges(3, added, nil)
+	for _, want := range []string{"Saved search #3", fmt.Sprintf("%d new listing(s)", len(added)), ">Home 0</a>", "…and 2 more in the sheet"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatRunChanges() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestFormatPriceDrops(t *testing.T) {
+	drops := []priceDrop{
+		{listing: models.Listing{Title: "Loft <river view>", URL: "https://www.airbnb.com/rooms/1?a=1&b=2", Price: 1200, Currency: "THB"}, oldPrice: 1500},
+		{listing: models.Listing{URL: "https://www.airbnb.com/rooms/2", Price: 75, Currency: "USD"}, oldPrice: 100},
+	}
+
+	got := formatPriceDrops(drops)
+	for _, want := range []string{
+		"Price drop on 2 listing(s)",
+		`<a href="https://www.airbnb.com/rooms/1?a=1&amp;b=2">Loft &lt;river view&gt;</a>: ฿1500 → ฿1200 (-20%)`,
+		`>/rooms/2</a>: $100.00 → $75.00 (-25%)`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatPriceDrops() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestFormatLinkNumbers(t *testing.T) {
+	tests := []struct {
+		numbers []int
+		want    string
+	}{
+		{[]int{4}, "4"},
+		{[]int{3, 4, 5}, "3-5"},
+		{[]int{2, 5, 6, 7, 9}, "2, 5-7, 9"},
+	}
+
+	for _, tt := range tests {
+		if got := formatLinkNumbers(tt.numbers); got != tt.want {
+			t.Errorf("formatLinkNumbers(%v) = %q, want %q", tt.numbers, got, tt.want)
+		}
+	}
+}
+
+func TestStayDatesInfo(t *testing.T) {
+	checkIn := sql.NullTime{Time: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	checkOut := sql.NullTime{Time: time.Date(2026, 6, 5, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	if got, want := stayDatesInfo(&db.Request{CheckIn: checkIn, CheckOut: checkOut}), "2026-06-01 to 2026-06-05 (4 nights)"; got != want {
+		t.Errorf("stayDatesInfo() = %q, want %q", got, want)
+	}
+	if got := stayDatesInfo(&db.Request{}); !strings.Contains(got, "estimates") {
+		t.Errorf("stayDatesInfo() without dates = %q, want a note that prices are estimates", got)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	p := &progressState{link: 2, links: 3, page: 5, pages: 20}
+	if got, want := p.bar(), "Link 2/3 · Page 5/20 (25%)"; got != want {
+		t.Errorf("bar() = %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct{ done, total, want int }{{0, 20, 0}, {20, 20, 100}, {1, 3, 33}, {3, 0, 0}, {25, 20, 100}} {
+		if got := percent(tt.done, tt.total); got != tt.want {
+			t.Errorf("percent(%d, %d) = %d, want %d", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestPageThrottle(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := &pageThrottle{interval: 5 * time.Second}
+
+	steps := []struct {
+		page  int
+		after time.Duration
+		want  bool
+	}{
+		{1, 0, true},                // first page
+		{2, 2 * time.Second, false}, // too soon
+		{3, 6 * time.Second, true},
+		{4, 8 * time.Second, false},
+		{5, 9 * time.Second, true}, // last page
+	}
+	for _, step := range steps {
+		if got := throttle.allow(step.page, 5, start.Add(step.after)); got != step.want {
+			t.Errorf("allow(page %d after %s) = %t, want %t", step.page, step.after, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package scoring
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"bnb-fetcher/models"
+)
+
+// DefaultHalfLifeDays is the review age at which a listing's activity score halves
+const DefaultHalfLifeDays = 90.0
+
+// ActivityScore combines review volume and review recency into a single sortable
+// "active and well-reviewed" signal:
+//
+//	score = ln(1 + reviewCount) * 0.5^(ageDays / halfLifeDays)
+//
+// where ageDays is the number of days between newestReview and now. The log keeps
+// listings with thousands of reviews from drowning out everything else, and the
+// decay halves the score for every halfLifeDays since the last review. Listings with
+// no reviews, or whose newest review date is unknown, score 0. Reviews dated in the
+// future are treated as written now.
+func ActivityScore(reviewCount int, newestReview *time.Time, now time.Time, halfLifeDays float64) float64 {
+	if reviewCount <= 0 || newestReview == nil {
+		return 0
+	}
+	if halfLifeDays <= 0 {
+		halfLifeDays = DefaultHalfLifeDays
+	}
+
+	ageDays := now.Sub(*newestReview).Hours() / 24
+	if ageDays < 0 {
+		ageDays = 0
+	}
+
+	return math.Log1p(float64(reviewCount)) * math.Pow(0.5, ageDays/halfLifeDays)
+}
+
+// ApplyActivityScores sets ActivityScore on each listing
+func ApplyActivityScores(listings []models.Listing, now time.Time, halfLifeDays float64) {
+	for i := range listings {
+		listings[i].ActivityScore = ActivityScore(listings[i].ReviewCount, listings[i].NewestReviewDate, now, halfLifeDays)
+	}
+}
+
+// HalfLifeDaysFromEnv returns the decay half-life from ACTIVITY_HALF_LIFE_DAYS,
+// or DefaultHalfLifeDays when it is unset or not a positive number
+func HalfLifeDaysFromEnv() float64 {
+	value := os.Getenv("ACTIVITY_HALF_LIFE_DAYS")
+	if value == "" {
+		return DefaultHalfLifeDays
+	}
+	days, err := strconv.ParseFloat(value, 64)
+	if err != nil || days <= 0 {
+		log.Printf("Warning: Invalid ACTIVITY_HALF_LIFE_DAYS=%q, using default %.0f\n", value, DefaultHalfLifeDays)
+		return DefaultHalfLifeDays
+	}
+	return days
+}
@@ -0,0 +1,57 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestActivityScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		d := now.AddDate(0, 0, -days)
+		return &d
+	}
+
+	tests := []struct {
+		name         string
+		reviewCount  int
+		newestReview *time.Time
+		halfLifeDays float64
+		expected     float64
+	}{
+		{"no reviews", 0, daysAgo(1), 90, 0},
+		{"no reviews and no date", 0, nil, 90, 0},
+		{"unknown newest review date", 25, nil, 90, 0},
+		{"reviewed today", 99, daysAgo(0), 90, math.Log(100)},
+		{"one half-life old", 99, daysAgo(90), 90, math.Log(100) / 2},
+		{"two half-lives old", 99, daysAgo(180), 90, math.Log(100) / 4},
+		{"future date treated as today", 99, daysAgo(-10), 90, math.Log(100)},
+		{"zero half-life falls back to default", 99, daysAgo(90), 0, math.Log(100) / 2},
+		{"custom half-life", 99, daysAgo(30), 30, math.Log(100) / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ActivityScore(tt.reviewCount, tt.newestReview, now, tt.halfLifeDays)
+			if math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("ActivityScore() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestActivityScoreVeryOldReviews(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(-10, 0, 0)
+
+	got := ActivityScore(5000, &old, now, DefaultHalfLifeDays)
+	if got <= 0 || got > 0.001 {
+		t.Errorf("ActivityScore() for a 10-year-old review = %v, want a small positive value", got)
+	}
+
+	recent := now.AddDate(0, 0, -7)
+	if ActivityScore(10, &recent, now, DefaultHalfLifeDays) <= got {
+		t.Error("a recently reviewed listing should outscore a heavily reviewed but stale one")
+	}
+}
@@ -0,0 +1,253 @@
+package searchurl
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultTrackingParams are the query parameters Normalize drops when TRACKING_PARAMS is
+// unset: session, impression and click IDs that change on every visit but not the
+// results. A trailing * matches a prefix.
+const defaultTrackingParams = "federated_search_session_id,federated_search_id,source_impression_id,search_id," +
+	"search_type,pagination_search,previous_page_section_name,_set_bev_on_new_domain,channel,gclid,fbclid,utm_*"
+
+// trackingParams are the parameters Normalize drops, set with Configure at startup
+var trackingParams = parseParamList(defaultTrackingParams)
+
+// Settings are the searchurl options taken from the environment
+type Settings struct {
+	TrackingParams []string // query parameters Normalize drops; a trailing * matches a prefix
+}
+
+// SettingsFromEnv reads the searchurl settings from TRACKING_PARAMS, a comma separated
+// list that replaces defaultTrackingParams
+func SettingsFromEnv() Settings {
+	return Settings{TrackingParams: trackingParamsFromEnv()}
+}
+
+// Configure replaces the searchurl settings. Call it once at startup, before URLs are
+// normalized: the settings are read without locking.
+func Configure(s Settings) {
+	trackingParams = s.TrackingParams
+}
+
+// trackingParamsFromEnv reads TRACKING_PARAMS, falling back to defaultTrackingParams
+func trackingParamsFromEnv() []string {
+	value, set := os.LookupEnv("TRACKING_PARAMS")
+	if !set {
+		return parseParamList(defaultTrackingParams)
+	}
+	log.Printf("Using TRACKING_PARAMS=%s\n", value)
+	return parseParamList(value)
+}
+
+// parseParamList parses a comma separated list of parameter names, ignoring blanks
+func parseParamList(text string) []string {
+	var names []string
+	for _, name := range strings.Split(text, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isTrackingParam reports whether the query parameter is on the tracking denylist
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range trackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns a canonical form of a URL so that the same search or listing
+// pasted twice compares equal: scheme and host are lowercased, the fragment is
+// dropped, a trailing slash is trimmed, tracking parameters (TRACKING_PARAMS) are
+// removed, check-in/check-out dates are zero-padded and the remaining query parameters
+// are sorted. Other search parameters such as guests, price bounds, room types and map
+// bounds are kept as they are.
+// If the URL can't be parsed, the trimmed input is returned unchanged.
+func Normalize(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if len(parsed.Path) > 1 {
+		parsed.Path = strings.TrimRight(parsed.Path, "/")
+		parsed.RawPath = ""
+	}
+
+	query := parsed.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+	// "2026-3-7" and "2026-03-07" are the same stay
+	for _, name := range append(checkInParams, checkOutParams...) {
+		if date, ok := parseDate(query.Get(name)); ok {
+			query.Set(name, date.Format(DateLayout))
+		}
+	}
+	// Values.Encode sorts by key, which makes parameter order irrelevant
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// The query parameters a search takes its stay dates from; app share links use the
+// underscored names
+var (
+	checkInParams  = []string{"checkin", "check_in"}
+	checkOutParams = []string{"checkout", "check_out"}
+)
+
+// DateLayout is the format of check-in and check-out dates in search URLs
+const DateLayout = "2006-01-02"
+
+// parseDate parses a date parameter, accepting days and months without leading zeros
+func parseDate(value string) (time.Time, bool) {
+	date, err := time.Parse("2006-1-2", strings.TrimSpace(value))
+	return date, err == nil
+}
+
+// firstDate returns the first of the named parameters that holds a valid date
+func firstDate(query url.Values, names []string) (time.Time, bool) {
+	for _, name := range names {
+		if date, ok := parseDate(query.Get(name)); ok {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Dates returns the check-in and check-out dates of a search URL. ok is false when the
+// URL has no dates, only one of them or check-out is not after check-in: Airbnb then
+// shows "from" prices that are not for a specific stay.
+func Dates(rawURL string) (checkIn, checkOut time.Time, ok bool) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	query := parsed.Query()
+	checkIn, inOK := firstDate(query, checkInParams)
+	checkOut, outOK := firstDate(query, checkOutParams)
+	if !inOK || !outOK || !checkOut.After(checkIn) {
+		return time.Time{}, time.Time{}, false
+	}
+	return checkIn, checkOut, true
+}
+
+// ListingPath returns the path identifying a listing across searches ("/rooms/123"):
+// the query of a listing link carries search and tracking parameters that change on
+// every run. If the URL can't be parsed, the trimmed input is returned unchanged.
+func ListingPath(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Path == "" {
+		return rawURL
+	}
+	return strings.ToLower(strings.TrimRight(parsed.Path, "/"))
+}
+
+// roomIDPattern matches the numeric listing ID in a listing path, "/rooms/123" as well as
+// "/rooms/plus/123" and localized hosts' paths
+var roomIDPattern = regexp.MustCompile(`/rooms/(?:plus/)?(\d+)`)
+
+// RoomID returns the numeric ID of the listing a link points to ("123" for
+// https://www.airbnb.com/rooms/123?check_in=...), or "" if the URL is not a listing link.
+// The ID is the same whatever dates, guests, tracking parameters or Airbnb domain the
+// link carries.
+func RoomID(rawURL string) string {
+	match := roomIDPattern.FindStringSubmatch(ListingPath(rawURL))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// ListingKey returns the key identifying a listing across links and searches: the path
+// "/rooms/<id>" of its room ID, so "/rooms/plus/123" and a localized host's link match
+// "/rooms/123". Links without a room ID fall back to ListingPath.
+func ListingKey(rawURL string) string {
+	if id := RoomID(rawURL); id != "" {
+		return "/rooms/" + id
+	}
+	return ListingPath(rawURL)
+}
+
+// searchParams are query parameters only search pages take, so a URL with one of them
+// is a search even when its path is unusual (e.g. a map link)
+var searchParams = []string{
+	"query", "place_id", "checkin", "checkout", "check_in", "check_out", "adults", "price_min", "price_max",
+	"ne_lat", "sw_lat", "refinement_paths[]", "search_type",
+}
+
+// Validate reports why a URL is not an Airbnb search, or nil if it is one. The host must
+// be an Airbnb domain, localized ones included (airbnb.de, fr.airbnb.ca, airbnb.co.uk),
+// and the URL must either be a /s/ search page or carry search parameters. Anything
+// stricter would reject searches pasted from the apps' share links.
+func Validate(rawURL string) error {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("not a web address")
+	}
+	if !isAirbnbHost(parsed.Hostname()) {
+		return fmt.Errorf("%s is not an Airbnb site", parsed.Hostname())
+	}
+
+	path := strings.ToLower(parsed.Path)
+	if strings.HasPrefix(path, "/s/") || path == "/s" {
+		return nil
+	}
+	query := parsed.Query()
+	for _, param := range searchParams {
+		if query.Has(param) {
+			return nil
+		}
+	}
+	if strings.HasPrefix(path, "/rooms/") {
+		return errors.New("this is a single listing, not a search")
+	}
+	return errors.New("this is not a search page")
+}
+
+// isAirbnbHost reports whether host is airbnb.<tld> or airbnb.<sld>.<cc>, or a subdomain of one
+func isAirbnbHost(host string) bool {
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	for i, label := range labels {
+		if label != "airbnb" {
+			continue
+		}
+		suffix := labels[i+1:]
+		if len(suffix) == 0 || len(suffix) > 2 {
+			return false
+		}
+		for _, part := range suffix {
+			if len(part) < 2 || len(part) > 3 || strings.Trim(part, "abcdefghijklmnopqrstuvwxyz") != "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package searchurl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListingPath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.airbnb.com/rooms/123?adults=2&source_impression_id=p3_1", "/rooms/123"},
+		{"https://www.airbnb.com/rooms/123/?search_id=abc#photos", "/rooms/123"},
+		{"https://WWW.airbnb.com/Rooms/123", "/rooms/123"},
+		{"/rooms/456?check_in=2026-01-01", "/rooms/456"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := ListingPath(tt.input); got != tt.expected {
+			t.Errorf("ListingPath(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRoomID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.airbnb.com/rooms/123?check_in=2026-01-01&source_impression_id=p3_1", "123"},
+		{"https://www.airbnb.de/rooms/123/?adults=2#photos", "123"},
+		{"https://www.airbnb.com/rooms/plus/456", "456"},
+		{"/rooms/789?check_in=2026-01-01", "789"},
+		{"https://www.airbnb.com/s/Lisbon/homes", ""},
+		{"https://www.airbnb.com/rooms/", ""},
+		{"not a url", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RoomID(tt.input); got != tt.expected {
+			t.Errorf("RoomID(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestListingKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.airbnb.com/rooms/123?check_in=2026-01-01", "/rooms/123"},
+		{"https://www.airbnb.com/rooms/plus/123", "/rooms/123"},
+		{"https://www.airbnb.de/rooms/123/?adults=2", "/rooms/123"},
+		{"https://www.airbnb.com/experiences/456?x=1", "/experiences/456"},
+	}
+
+	for _, tt := range tests {
+		if got := ListingKey(tt.input); got != tt.expected {
+			t.Errorf("ListingKey(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"https://www.airbnb.com/s/Lisbon/homes?adults=2", false},
+		{"https://www.airbnb.co.uk/s/London/homes", false},
+		{"https://fr.airbnb.ca/s/Montréal/homes", false},
+		{"https://www.airbnb.com.au/s/homes?place_id=ChIJ", false},
+		{"https://airbnb.de/?checkin=2026-06-01&checkout=2026-06-05", false},
+		{"https://www.airbnb.com/rooms/123", true},
+		{"https://www.airbnb.com/help", true},
+		{"https://www.booking.com/s/Lisbon/homes", true},
+		{"https://airbnb.com.evil.example/s/Lisbon/homes", true},
+		{"https://notairbnb.com/s/Lisbon/homes", true},
+		{"ftp://www.airbnb.com/s/Lisbon/homes", true},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(tt.input); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeStripsTrackingParams(t *testing.T) {
+	messy := "https://WWW.airbnb.com/s/Lisbon--Portugal/homes/?tab_id=home_tab&refinement_paths%5B%5D=%2Fhomes" +
+		"&flexible_trip_lengths%5B%5D=one_week&price_filter_input_type=0&price_filter_num_nights=4&channel=EXPLORE" +
+		"&query=Lisbon%2C%20Portugal&place_id=ChIJO_PkYRozGQ0R0DaQ5L3rAAQ&date_picker_type=calendar" +
+		"&checkin=2026-06-01&checkout=2026-06-05&adults=2&source=structured_search_input_header&search_type=filter_change" +
+		"&price_min=40&price_max=120&room_types%5B%5D=Entire%20home%2Fapt&ne_lat=38.79&sw_lat=38.69" +
+		"&federated_search_session_id=9a1b2c3d-4e5f&pagination_search=true&utm_source=newsletter&utm_campaign=spring" +
+		"&source_impression_id=p3_1717171717_abc#photos"
+
+	got := Normalize(messy)
+	for _, dropped := range []string{"federated_search_session_id", "source_impression_id", "search_type", "pagination_search",
+		"channel", "utm_source", "utm_campaign", "#photos"} {
+		if strings.Contains(got, dropped) {
+			t.Errorf("Normalize() kept %q: %s", dropped, got)
+		}
+	}
+	for _, kept := range []string{"checkin=2026-06-01", "checkout=2026-06-05", "adults=2", "price_min=40", "price_max=120",
+		"room_types%5B%5D=Entire+home%2Fapt", "ne_lat=38.79", "sw_lat=38.69", "place_id=", "refinement_paths%5B%5D=%2Fhomes"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Normalize() dropped %q: %s", kept, got)
+		}
+	}
+	if !strings.HasPrefix(got, "https://www.airbnb.com/s/Lisbon--Portugal/homes?") {
+		t.Errorf("Normalize() = %s, want the lowercased host and the path without trailing slash", got)
+	}
+
+	// The same search pasted from another session compares equal
+	other := strings.Replace(messy, "9a1b2c3d-4e5f", "0f0f0f0f-1111", 1)
+	if Normalize(other) != got {
+		t.Errorf("Normalize() differs between sessions:\n%s\n%s", Normalize(other), got)
+	}
+}
+
+func TestDates(t *testing.T) {
+	tests := []struct {
+		input        string
+		wantCheckIn  string
+		wantCheckOut string
+		wantOK       bool
+	}{
+		{"https://www.airbnb.com/s/Lisbon/homes?checkin=2026-06-01&checkout=2026-06-05", "2026-06-01", "2026-06-05", true},
+		{"https://www.airbnb.com/s/Lisbon/homes?check_in=2026-6-1&check_out=2026-6-5", "2026-06-01", "2026-06-05", true},
+		{"https://www.airbnb.com/s/Lisbon/homes?adults=2", "", "", false},
+		{"https://www.airbnb.com/s/Lisbon/homes?checkin=2026-06-01", "", "", false},
+		{"https://www.airbnb.com/s/Lisbon/homes?checkin=2026-06-05&checkout=2026-06-01", "", "", false},
+		{"https://www.airbnb.com/s/Lisbon/homes?checkin=soon&checkout=2026-06-05", "", "", false},
+	}
+
+	for _, tt := range tests {
+		checkIn, checkOut, ok := Dates(tt.input)
+		if ok != tt.wantOK {
+			t.Errorf("Dates(%q) ok = %v, want %v", tt.input, ok, tt.wantOK)
+			continue
+		}
+		if ok && (checkIn.Format(DateLayout) != tt.wantCheckIn || checkOut.Format(DateLayout) != tt.wantCheckOut) {
+			t.Errorf("Dates(%q) = %s, %s, want %s, %s", tt.input, checkIn.Format(DateLayout), checkOut.Format(DateLayout), tt.wantCheckIn, tt.wantCheckOut)
+		}
+	}
+}
+
+func TestNormalizeDates(t *testing.T) {
+	got := Normalize("https://www.airbnb.com/s/Lisbon/homes?check_out=2026-6-5&checkin=2026-06-01&currency=USD")
+	want := "https://www.airbnb.com/s/Lisbon/homes?check_out=2026-06-05&checkin=2026-06-01&currency=USD"
+	if got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,227 @@
+package sheets
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"bnb-fetcher/models"
+	"bnb-fetcher/sanitize"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// column is one listing column of the result sheets: its header and the cell value of a listing
+type column struct {
+	header string
+	value  func(listing models.Listing) interface{}
+}
+
+// columns returns the listing columns of result sheets, in the order new tabs get them.
+// Tabs written by older versions may lack the newer columns or have them in another
+// order; appends follow the tab's own header, see readLayout.
+func columns() []column {
+	return []column{
+		{"Title", func(l models.Listing) interface{} { return l.Title }},
+		{"Link", func(l models.Listing) interface{} { return l.URL }},
+		{"Price", func(l models.Listing) interface{} { return l.Price }},
+		{"Currency", func(l models.Listing) interface{} { return l.Currency }},
+		// Empty when the price could not be converted
+		{"Price (USD)", func(l models.Listing) interface{} { return rounded(l.PriceUSD) }},
+		{"Rating", func(l models.Listing) interface{} { return l.Stars }},
+		{"Review Count", func(l models.Listing) interface{} { return l.ReviewCount }},
+		{"Page Number", func(l models.Listing) interface{} { return l.PageNumber }},
+		// Empty if 0 for backwards compatibility
+		{"Link #", func(l models.Listing) interface{} { return positive(l.LinkNumber) }},
+		{"Price Range", func(l models.Listing) interface{} { return nonEmpty(l.PriceRangeLabel) }},
+		{"Superhost", func(l models.Listing) interface{} { return l.IsSuperhost }},
+		{"Guest Favorite", func(l models.Listing) interface{} { return l.IsGuestFavorite }},
+		{"Bedrooms", func(l models.Listing) interface{} { return l.Bedrooms }},
+		{"Bathrooms", func(l models.Listing) interface{} { return l.Bathrooms }},
+		{"Beds", func(l models.Listing) interface{} { return l.Beds }},
+		{"Description", func(l models.Listing) interface{} { return sanitize.Text(l.Description, sanitize.MaxLength) }},
+		{"House Rules", func(l models.Listing) interface{} { return sanitize.Text(l.HouseRules, sanitize.MaxLength) }},
+		{"Newest Review Date", func(l models.Listing) interface{} {
+			if l.NewestReviewDate == nil {
+				return nil
+			}
+			return l.NewestReviewDate.Format("2006-01-02")
+		}},
+		// Empty when there is no score (no reviews or not enriched)
+		{"Activity Score", func(l models.Listing) interface{} { return rounded(l.ActivityScore) }},
+		// Empty when guest capacity or USD price is unknown; in USD so listings of
+		// different currencies compare
+		{"Max Guests", func(l models.Listing) interface{} { return positive(l.MaxGuests) }},
+		{"Price per Guest (USD)", func(l models.Listing) interface{} { return rounded(l.PricePerGuestUSD()) }},
+		{"Location", func(l models.Listing) interface{} { return l.Location }},
+		// Empty when there is no minimum stay or it is unknown
+		{"Min Nights", func(l models.Listing) interface{} { return positive(l.MinNights) }},
+		{"Check-in", func(l models.Listing) interface{} { return l.CheckIn }},
+		{"Check-out", func(l models.Listing) interface{} { return l.CheckOut }},
+		{"Self Check-in", func(l models.Listing) interface{} { return l.SelfCheckIn }},
+		// Empty when the card has no discount badge
+		{"Discount %", func(l models.Listing) interface{} { return positive(l.DiscountPercent) }},
+		{"Badges", func(l models.Listing) interface{} { return strings.Join(l.Badges, ", ") }},
+		// Empty when the bedroom count or USD price is unknown
+		{"Price per Bedroom (USD)", func(l models.Listing) interface{} { return rounded(l.PricePerBedroomUSD()) }},
+		{"Thumbnail", func(l models.Listing) interface{} { return imageCell(l.ThumbnailURL) }},
+	}
+}
+
+// positive returns v, or nil for an empty cell when it is zero or negative
+func positive[T int | float64](v T) interface{} {
+	if v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// rounded returns v rounded to cents, or nil for an empty cell when it is zero or negative
+func rounded(v float64) interface{} {
+	if v <= 0 {
+		return nil
+	}
+	return math.Round(v*100) / 100
+}
+
+// nonEmpty returns s, or nil for an empty cell
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// headerRow returns the column headers for listing sheets
+func headerRow() []interface{} {
+	var row []interface{}
+	for _, c := range columns() {
+		row = append(row, c.header)
+	}
+	return row
+}
+
+// listingRow returns the cell values for a listing, in headerRow order
+func listingRow(listing models.Listing) []interface{} {
+	var row []interface{}
+	for _, c := range columns() {
+		row = append(row, c.value(listing))
+	}
+	return row
+}
+
+// listingCells returns the cell values for a listing by column header
+func listingCells(listing models.Listing) map[string]interface{} {
+	cells := make(map[string]interface{})
+	for _, c := range columns() {
+		cells[c.header] = c.value(listing)
+	}
+	return cells
+}
+
+// headerSearchRows is how many rows at the top of a tab are searched for its header row,
+// which follows the optional metadata and summary rows
+const headerSearchRows = 5
+
+// sheetLayout is the column order of an existing tab, as found in its header row
+type sheetLayout struct {
+	headerRow int      // 1-based row number of the header
+	headers   []string // column headers from column A on
+}
+
+// readLayout reads the header row of a tab: the first of the top rows starting with the
+// Title header
+func (w *Writer) readLayout(sheetName string) (*sheetLayout, error) {
+	resp, err := w.getValues(fmt.Sprintf("%s!1:%d", sheetName, headerSearchRows))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet header area: %w", err)
+	}
+	layout := findLayout(resp.Values)
+	if layout == nil {
+		return nil, fmt.Errorf("header row not found in sheet '%s'", sheetName)
+	}
+	return layout, nil
+}
+
+// findLayout returns the layout of the first row of values starting with the Title
+// header, or nil if there is none
+func findLayout(values [][]interface{}) *sheetLayout {
+	title := columns()[0].header
+	for i, row := range values {
+		if len(row) == 0 || fmt.Sprint(row[0]) != title {
+			continue
+		}
+		headers := make([]string, len(row))
+		for j, cell := range row {
+			headers[j] = fmt.Sprint(cell)
+		}
+		return &sheetLayout{headerRow: i + 1, headers: headers}
+	}
+	return nil
+}
+
+// missing returns the headers in want the tab doesn't have, in want order
+func (l *sheetLayout) missing(want []interface{}) []interface{} {
+	have := make(map[string]bool, len(l.headers))
+	for _, header := range l.headers {
+		have[header] = true
+	}
+	var missing []interface{}
+	for _, header := range want {
+		if !have[fmt.Sprint(header)] {
+			missing = append(missing, header)
+		}
+	}
+	return missing
+}
+
+// arrange returns the cells of a row in the tab's column order. Columns the tab has but
+// cells doesn't (removed since the tab was created) are left empty.
+func (l *sheetLayout) arrange(cells map[string]interface{}) []interface{} {
+	row := make([]interface{}, len(l.headers))
+	for i, header := range l.headers {
+		row[i] = cells[header]
+	}
+	return row
+}
+
+// migrateLayout appends the headers of want that a tab created by an older version lacks
+// to the end of its header row, so appends to the tab can fill the new columns too. The
+// rows already in the tab are left as they are, with the new columns empty.
+func (w *Writer) migrateLayout(sheetName string, layout *sheetLayout, want []interface{}) error {
+	missing := layout.missing(want)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	range_ := fmt.Sprintf("%s!%s%d", sheetName, columnLetter(len(layout.headers)+1), layout.headerRow)
+	valueRange := &sheets.ValueRange{Values: [][]interface{}{missing}}
+	if _, err := w.updateValues(range_, valueRange, "RAW"); err != nil {
+		return fmt.Errorf("failed to add new columns to the header of sheet '%s': %w", sheetName, err)
+	}
+	for _, header := range missing {
+		layout.headers = append(layout.headers, fmt.Sprint(header))
+	}
+
+	log.Printf("Added %d new column(s) to the header of sheet '%s'\n", len(missing), sheetName)
+	return nil
+}
+
+// appendArranged appends rows given as cells by column header to a tab, in the tab's own
+// column order, after adding the columns of want its header lacks
+func (w *Writer) appendArranged(sheetName string, rows []map[string]interface{}, want []interface{}) error {
+	layout, err := w.readLayout(sheetName)
+	if err != nil {
+		return err
+	}
+	if err := w.migrateLayout(sheetName, layout, want); err != nil {
+		return err
+	}
+
+	values := make([][]interface{}, len(rows))
+	for i, cells := range rows {
+		values[i] = layout.arrange(cells)
+	}
+	return w.appendRows(sheetName, values, len(layout.headers))
+}
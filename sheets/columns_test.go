@@ -0,0 +1,112 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"bnb-fetcher/models"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestColumnsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range columns() {
+		if seen[c.header] {
+			t.Errorf("column %q is defined twice", c.header)
+		}
+		seen[c.header] = true
+	}
+	if seen[runAtHeader] {
+		t.Errorf("listing column %q clashes with the run time column", runAtHeader)
+	}
+}
+
+func TestFindLayout(t *testing.T) {
+	values := [][]interface{}{
+		{"URL", "https://www.airbnb.com/s/Lisbon/homes"},
+		{summaryLabel, "pending"},
+		{"Title", "Link", "Price"},
+	}
+	layout := findLayout(values)
+	if layout == nil || layout.headerRow != 3 || !reflect.DeepEqual(layout.headers, []string{"Title", "Link", "Price"}) {
+		t.Fatalf("findLayout() = %+v, want the header in row 3", layout)
+	}
+	if layout := findLayout(values[:2]); layout != nil {
+		t.Errorf("findLayout() without header = %+v, want nil", layout)
+	}
+}
+
+func TestSheetLayoutArrange(t *testing.T) {
+	// A tab of an older version: fewer columns, one since removed, another order
+	layout := &sheetLayout{headerRow: 1, headers: []string{"Title", "Price", "Removed", "Link"}}
+
+	missing := layout.missing(headerRow())
+	if len(missing) != len(headerRow())-3 || missing[0] != "Currency" {
+		t.Errorf("missing() = %v, want every column but Title, Link and Price", missing)
+	}
+
+	cells := listingCells(models.Listing{Title: "Loft", URL: "https://www.airbnb.com/rooms/1", Price: 80})
+	got := layout.arrange(cells)
+	want := []interface{}{"Loft", 80.0, nil, "https://www.airbnb.com/rooms/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("arrange() = %v, want %v", got, want)
+	}
+}
+
+func TestAppendListingsToOlderSheet(t *testing.T) {
+	var headerUpdate, appended sheets.ValueRange
+	var appendRange string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"values": [["URL", "https://www.airbnb.com/s/Lisbon/homes"], ["Title", "Link", "Price"]]}`)
+		case r.Method == http.MethodPut:
+			json.NewDecoder(r.Body).Decode(&headerUpdate)
+			fmt.Fprint(w, `{}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":append"):
+			appendRange = strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], ":append")
+			json.NewDecoder(r.Body).Decode(&appended)
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("sheets.NewService() error = %v", err)
+	}
+	w := &Writer{service: service, spreadsheetID: "abc123"}
+
+	listing := models.Listing{Title: "Loft", URL: "https://www.airbnb.com/rooms/1", Price: 80, Location: "Alfama"}
+	if err := w.AppendListingsToSheet("Request_1", []models.Listing{listing}); err != nil {
+		t.Fatalf("AppendListingsToSheet() error = %v", err)
+	}
+
+	if len(headerUpdate.Values) != 1 || len(headerUpdate.Values[0]) != len(headerRow())-3 || headerUpdate.Values[0][0] != "Currency" {
+		t.Errorf("header update = %v, want the columns the tab lacks", headerUpdate.Values)
+	}
+	if want := "Request_1!A:" + columnLetter(len(headerRow())); appendRange != want {
+		t.Errorf("appended to %q, want %q", appendRange, want)
+	}
+	if len(appended.Values) != 1 {
+		t.Fatalf("appended %d rows, want 1", len(appended.Values))
+	}
+	row := appended.Values[0]
+	// Title, Link and Price lead headerRow too, so the migrated tab has its column order
+	location := slices.Index(headerRow(), interface{}("Location"))
+	if row[0] != "'Loft" || row[1] != "'https://www.airbnb.com/rooms/1" || row[2] != 80.0 || row[location] != "'Alfama" {
+		t.Errorf("appended row = %v, want Title, Link and Price first and Location at %d", row, location)
+	}
+}
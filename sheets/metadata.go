@@ -0,0 +1,169 @@
+package sheets
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Where new tabs keep the search URL, stay dates, filters and summary, from the
+// SHEET_METADATA environment variable:
+//
+//	note  in a note on the header's first cell (A1), with the header frozen in row 1 (default)
+//	rows  in rows above the header, as tabs of older versions do
+//
+// With notes, row 1 is the header and the data region starts right below it, so
+// sorting, filter views and tools reading the tab need no offset. Tabs created with
+// rows keep working either way: the header is looked up, not assumed. The zero setting
+// means note.
+const (
+	sheetMetadataNote = "note"
+	sheetMetadataRows = "rows"
+)
+
+// sheetMetadataFromEnv reads SHEET_METADATA, falling back to notes when it is unset or invalid
+func sheetMetadataFromEnv() string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("SHEET_METADATA")))
+	switch value {
+	case "":
+		return sheetMetadataNote
+	case sheetMetadataNote, sheetMetadataRows:
+		log.Printf("Using SHEET_METADATA=%s\n", value)
+		return value
+	}
+	log.Printf("Warning: Unknown SHEET_METADATA=%q (expected note or rows), using %s\n", value, sheetMetadataNote)
+	return sheetMetadataNote
+}
+
+// headerArea returns the first rows of a new tab, down to and including header, and the
+// note for the header's first cell ("" for none). summary is the summary row, or nil for
+// tabs without one.
+func (w *Writer) headerArea(header []interface{}, url string, dates string, filterInfo string, summary []interface{}) ([][]interface{}, string) {
+	if w.settings.Metadata == sheetMetadataRows {
+		values := metadataRows(url, dates, filterInfo)
+		if summary != nil {
+			values = append(values, summary)
+		}
+		return append(values, header), ""
+	}
+
+	var lines []string
+	for _, row := range metadataRows(url, dates, filterInfo) {
+		lines = append(lines, pairLines(row)...)
+	}
+	if summary != nil {
+		lines = append(lines, summaryNoteLine(summary))
+	}
+	return [][]interface{}{header}, strings.Join(lines, "\n")
+}
+
+// pairLines turns a label/value row like metadataRows' into "Label: value" lines
+func pairLines(row []interface{}) []string {
+	var lines []string
+	for i := 0; i+1 < len(row); i += 2 {
+		lines = append(lines, fmt.Sprintf("%v: %v", row[i], row[i+1]))
+	}
+	return lines
+}
+
+// summaryNoteLine formats a summary row as one note line, e.g.
+// "Summary: Listings 12 · Avg Price 80.5", leaving out empty aggregates
+func summaryNoteLine(summary []interface{}) string {
+	if len(summary) == 2 {
+		// The placeholder written before the aggregates are known
+		return fmt.Sprintf("%s: %v", summaryLabel, summary[1])
+	}
+	var parts []string
+	for i := 1; i+1 < len(summary); i += 2 {
+		if summary[i+1] != nil {
+			parts = append(parts, fmt.Sprintf("%v %v", summary[i], summary[i+1]))
+		}
+	}
+	return summaryLabel + ": " + strings.Join(parts, " · ")
+}
+
+// replaceSummaryLine replaces the summary line of a header note, or adds one at the end
+func replaceSummaryLine(note string, line string) string {
+	if note == "" {
+		return line
+	}
+	lines := strings.Split(note, "\n")
+	for i, existing := range lines {
+		if strings.HasPrefix(existing, summaryLabel+":") {
+			lines[i] = line
+			return strings.Join(lines, "\n")
+		}
+	}
+	return note + "\n" + line
+}
+
+// setHeaderNote puts note on the first cell of a tab and freezes its first row, the header
+func (w *Writer) setHeaderNote(sheetID int64, note string) error {
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				UpdateCells: &sheets.UpdateCellsRequest{
+					Start: &sheets.GridCoordinate{
+						SheetId:         sheetID,
+						ForceSendFields: []string{"SheetId", "RowIndex", "ColumnIndex"},
+					},
+					Rows:   []*sheets.RowData{{Values: []*sheets.CellData{{Note: note}}}},
+					Fields: "note",
+				},
+			},
+			{
+				UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+					Properties: &sheets.SheetProperties{
+						SheetId:         sheetID,
+						GridProperties:  &sheets.GridProperties{FrozenRowCount: 1},
+						ForceSendFields: []string{"SheetId"},
+					},
+					Fields: "gridProperties.frozenRowCount",
+				},
+			},
+		},
+	}
+	if _, err := w.batchUpdate(batchUpdateRequest); err != nil {
+		return fmt.Errorf("failed to write the header note: %w", err)
+	}
+	return nil
+}
+
+// getHeaderNote returns the note on the first cell of a tab, "" if it has none
+func (w *Writer) getHeaderNote(sheetName string) (string, error) {
+	spreadsheet, err := retryWithBackoff("read the header note", func() (*sheets.Spreadsheet, error) {
+		return w.service.Spreadsheets.Get(w.spreadsheetID).
+			Ranges(fmt.Sprintf("%s!A1", sheetName)).
+			Fields("sheets(data(rowData(values(note))))").
+			Do()
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the header note: %w", err)
+	}
+	// One range of one cell: the note is in the first value of the first row, if any
+	if len(spreadsheet.Sheets) == 0 || len(spreadsheet.Sheets[0].Data) == 0 {
+		return "", nil
+	}
+	rows := spreadsheet.Sheets[0].Data[0].RowData
+	if len(rows) == 0 || len(rows[0].Values) == 0 {
+		return "", nil
+	}
+	return rows[0].Values[0].Note, nil
+}
+
+// writeSummaryNote replaces the summary line of the header note of a tab created with
+// SHEET_METADATA=note
+func (w *Writer) writeSummaryNote(sheetName string, summary []interface{}) error {
+	note, err := w.getHeaderNote(sheetName)
+	if err != nil {
+		return err
+	}
+	sheetID, err := w.GetSheetIDByName(sheetName)
+	if err != nil {
+		return err
+	}
+	return w.setHeaderNote(sheetID, replaceSummaryLine(note, summaryNoteLine(summary)))
+}
@@ -0,0 +1,53 @@
+package sheets
+
+import (
+	"reflect"
+	"testing"
+
+	"bnb-fetcher/models"
+)
+
+func TestHeaderArea(t *testing.T) {
+	url := "https://www.airbnb.com/s/Lisbon/homes"
+	placeholder := []interface{}{summaryLabel, "pending"}
+
+	w := &Writer{settings: Settings{Metadata: sheetMetadataNote}}
+	values, note := w.headerArea(headerRow(), url, "Jun 1 - Jun 5", "2+ bedrooms", placeholder)
+	if !reflect.DeepEqual(values, [][]interface{}{headerRow()}) {
+		t.Errorf("note mode rows = %v, want only the header", values)
+	}
+	want := "URL: " + url + "\nDates: Jun 1 - Jun 5\nFilters: 2+ bedrooms\nSummary: pending"
+	if note != want {
+		t.Errorf("note mode note = %q, want %q", note, want)
+	}
+	if layout := findLayout(values); layout == nil || layout.headerRow != 1 {
+		t.Errorf("note mode header layout = %+v, want row 1", layout)
+	}
+
+	w.settings.Metadata = sheetMetadataRows
+	values, note = w.headerArea(runHeaderRow(), url, "", "", nil)
+	if note != "" || len(values) != 2 || values[0][0] != "URL" || !reflect.DeepEqual(values[1], runHeaderRow()) {
+		t.Errorf("rows mode = %v, %q; want the metadata row above the header", values, note)
+	}
+}
+
+func TestReplaceSummaryLine(t *testing.T) {
+	line := summaryNoteLine(summaryRow([]models.Listing{{Price: 80, Stars: 4.5}, {Price: 100}}))
+	if want := "Summary: Listings 2 · Avg Price 90 · Median Price 90 · Avg Rating 4.5 · Superhost % 0"; line != want {
+		t.Fatalf("summaryNoteLine() = %q, want %q", line, want)
+	}
+
+	tests := []struct {
+		note string
+		want string
+	}{
+		{"URL: x\nSummary: pending", "URL: x\n" + line},
+		{"URL: x", "URL: x\n" + line},
+		{"", line},
+	}
+	for _, tt := range tests {
+		if got := replaceSummaryLine(tt.note, line); got != tt.want {
+			t.Errorf("replaceSummaryLine(%q) = %q, want %q", tt.note, got, tt.want)
+		}
+	}
+}
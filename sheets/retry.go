@@ -0,0 +1,72 @@
+package sheets
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Backoff of retryWithBackoff: up to retryAttempts calls, waiting retryBaseDelay after the
+// first failure and twice as long after each further one, at most retryMaxDelay. Variables
+// so tests can shorten them.
+var (
+	retryAttempts  = 5
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// retryWithBackoff calls a Sheets API call until it succeeds, fails with an error that
+// retrying won't fix, or runs out of attempts; it returns the last result. Writing the
+// sheet is the last step of a request that may have scraped for many minutes, so a
+// passing 503 or rate limit shouldn't fail the whole request. what names the call in the
+// logs.
+//
+// A 5xx may come after the call was applied, so only calls that can be repeated safely
+// (reads, Update, Clear) go through retryWithBackoff; see retryRateLimited.
+func retryWithBackoff[T any](what string, call func() (T, error)) (T, error) {
+	return retryIf(what, isTransient, call)
+}
+
+// retryRateLimited is retryWithBackoff for calls that must not be repeated once applied,
+// like appending rows: only rate limiting is retried, it rejects a call before applying it
+func retryRateLimited[T any](what string, call func() (T, error)) (T, error) {
+	return retryIf(what, isRateLimited, call)
+}
+
+// retryIf runs the backoff of retryWithBackoff for the errors retryable accepts
+func retryIf[T any](what string, retryable func(error) bool, call func() (T, error)) (T, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := call()
+		if err == nil || !retryable(err) || attempt >= retryAttempts {
+			return result, err
+		}
+		log.Printf("Warning: Sheets call to %s failed (attempt %d/%d), retrying in %s: %v\n", what, attempt, retryAttempts, delay, err)
+		time.Sleep(delay)
+		delay = min(2*delay, retryMaxDelay)
+	}
+}
+
+// isTransient reports whether err is a Sheets API error that may go away on its own:
+// rate limiting or a server-side failure
+func isTransient(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRateLimited reports whether err is a Sheets API rate limit (429)
+func isRateLimited(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
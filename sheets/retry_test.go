@@ -0,0 +1,140 @@
+package sheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeSheetsWriter returns a Writer talking to a fake Sheets API that answers the first
+// `failures` requests with an error of the given status and then lists one sheet, and
+// the counter of requests the fake got
+func fakeSheetsWriter(t *testing.T, failures int, status int) (*Writer, *atomic.Int32) {
+	t.Helper()
+	requests := new(atomic.Int32)
+	service := fakeSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if int(requests.Add(1)) <= failures {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error": {"code": %d, "message": "try again"}}`, status)
+			return
+		}
+		fmt.Fprint(w, `{"sheets": [{"properties": {"sheetId": 7, "title": "Request_1"}}]}`)
+	})
+	return &Writer{service: service, spreadsheetID: "abc123"}, requests
+}
+
+// fakeSheetsService returns a Sheets service talking to handler, with the retry backoff
+// shortened for the test
+func fakeSheetsService(t *testing.T, handler http.HandlerFunc) *sheets.Service {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("sheets.NewService() error = %v", err)
+	}
+
+	savedDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = savedDelay })
+	return service
+}
+
+func TestRetryWithBackoffTransientErrors(t *testing.T) {
+	w, requests := fakeSheetsWriter(t, 2, http.StatusServiceUnavailable)
+
+	gid, err := w.GetSheetIDByName("Request_1")
+	if err != nil || gid != 7 {
+		t.Fatalf("GetSheetIDByName() = %d, %v; want 7 after the 503s", gid, err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("server got %d requests, want 3", got)
+	}
+}
+
+func TestRetryWithBackoffPermanentError(t *testing.T) {
+	w, requests := fakeSheetsWriter(t, 1, http.StatusForbidden)
+
+	_, err := w.GetSheetIDByName("Request_1")
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Fatalf("GetSheetIDByName() error = %v, want the 403", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server got %d requests, want 1: a 403 is not retried", got)
+	}
+}
+
+func TestRetryWithBackoffGivesUp(t *testing.T) {
+	w, requests := fakeSheetsWriter(t, retryAttempts+1, http.StatusTooManyRequests)
+
+	if _, err := w.GetSheetIDByName("Request_1"); err == nil {
+		t.Fatal("GetSheetIDByName() succeeded, want the 429 after the last attempt")
+	}
+	if got := int(requests.Load()); got != retryAttempts {
+		t.Errorf("server got %d requests, want %d", got, retryAttempts)
+	}
+}
+
+func TestAppendRowsRetriesOnlyRateLimits(t *testing.T) {
+	for _, tt := range []struct {
+		status       int
+		wantRequests int32
+	}{
+		{http.StatusServiceUnavailable, 1}, // may have been applied: appending again would duplicate the rows
+		{http.StatusTooManyRequests, 2},
+	} {
+		requests := new(atomic.Int32)
+		service := fakeSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if requests.Add(1) == 1 {
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"error": {"code": %d, "message": "try again"}}`, tt.status)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		})
+		w := &Writer{service: service, spreadsheetID: "abc123"}
+
+		err := w.appendRows("Request_1", [][]interface{}{{"Loft"}}, 1)
+		if got := requests.Load(); got != tt.wantRequests {
+			t.Errorf("status %d: server got %d requests, want %d (err %v)", tt.status, got, tt.wantRequests, err)
+		}
+	}
+}
+
+func TestAddSheetChecksBeforeRetrying(t *testing.T) {
+	var adds, gets atomic.Int32
+	service := fakeSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+			fmt.Fprint(w, `{"sheets": [{"properties": {"sheetId": 42, "title": "Request_1"}}]}`)
+			return
+		}
+		// The sheet gets added, but the response is lost in a 503
+		adds.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error": {"code": 503, "message": "try again"}}`)
+	})
+	w := &Writer{service: service, spreadsheetID: "abc123"}
+
+	sheetID, err := w.addSheet("Request_1")
+	if err != nil || sheetID != 42 {
+		t.Fatalf("addSheet() = %d, %v; want the gid of the sheet the failed attempt added", sheetID, err)
+	}
+	if adds.Load() != 1 || gets.Load() != 1 {
+		t.Errorf("server got %d adds and %d lookups, want 1 each", adds.Load(), gets.Load())
+	}
+}
@@ -0,0 +1,116 @@
+package sheets
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Spreadsheets created with CreateSpreadsheet (one per request, for users who turned that
+// on) are owned by the service account: they live in its Drive, count against its
+// storage quota and are invisible to everyone else. Nobody can ask the service account
+// for access, so they are shared by link right after creation, as chosen with the
+// NEW_SPREADSHEET_SHARING environment variable:
+//
+//	view  anyone with the link can view (default)
+//	edit  anyone with the link can edit
+//	off   not shared; only the service account (and its Workspace admins) can open them
+//
+// The zero setting means view. Link sharing means anyone the link is forwarded to can
+// open the listings. The files can't be transferred to a personal Google account; users
+// wanting their own copy use File > Make a copy. Deleting them is up to the bot admin,
+// from the service account.
+const (
+	newSpreadsheetView = "view"
+	newSpreadsheetEdit = "edit"
+	newSpreadsheetOff  = "off"
+)
+
+// newSpreadsheetSharingFromEnv reads NEW_SPREADSHEET_SHARING, falling back to link viewing
+// when it is unset or invalid
+func newSpreadsheetSharingFromEnv() string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("NEW_SPREADSHEET_SHARING")))
+	switch value {
+	case "":
+		return newSpreadsheetView
+	case newSpreadsheetView, newSpreadsheetEdit, newSpreadsheetOff:
+		log.Printf("Using NEW_SPREADSHEET_SHARING=%s\n", value)
+		return value
+	}
+	log.Printf("Warning: Unknown NEW_SPREADSHEET_SHARING=%q (expected view, edit or off), using %s\n", value, newSpreadsheetView)
+	return newSpreadsheetView
+}
+
+// NewSpreadsheetAccess tells users who can open the spreadsheets CreateSpreadsheet makes
+// with these settings
+func (s Settings) NewSpreadsheetAccess() string {
+	switch s.NewSpreadsheetSharing {
+	case newSpreadsheetEdit:
+		return "anyone with the link can edit them"
+	case newSpreadsheetOff:
+		return "they are not shared, the bot admin has to share them with you"
+	}
+	return "anyone with the link can view them"
+}
+
+// CreateSpreadsheet creates a new spreadsheet with the given title, owned by the service
+// account, and shares it as NEW_SPREADSHEET_SHARING says. The spreadsheet starts with
+// one empty default tab (gid 0). A failed share is only logged: the spreadsheet is still
+// usable by the bot, and the admin can share it by hand.
+func (w *Writer) CreateSpreadsheet(title string) (string, error) {
+	// Not retried: a create that timed out may still have made the spreadsheet, and
+	// retrying would leave an orphaned copy in the service account's Drive
+	spreadsheet, err := w.service.Spreadsheets.Create(&sheets.Spreadsheet{
+		Properties: &sheets.SpreadsheetProperties{Title: title},
+	}).Fields("spreadsheetId").Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create spreadsheet: %w", err)
+	}
+	log.Printf("Created spreadsheet '%s' (%s)\n", title, spreadsheet.SpreadsheetId)
+
+	if err := w.shareSpreadsheet(spreadsheet.SpreadsheetId); err != nil {
+		log.Printf("Warning: Failed to share spreadsheet %s: %v\n", spreadsheet.SpreadsheetId, err)
+	}
+	return spreadsheet.SpreadsheetId, nil
+}
+
+// shareSpreadsheet gives anyone with the link access to the spreadsheet, see
+// NEW_SPREADSHEET_SHARING
+func (w *Writer) shareSpreadsheet(spreadsheetID string) error {
+	role := "reader"
+	switch w.settings.NewSpreadsheetSharing {
+	case newSpreadsheetOff:
+		return nil
+	case newSpreadsheetEdit:
+		role = "writer"
+	}
+	if w.drive == nil {
+		return fmt.Errorf("no Drive service to share with")
+	}
+
+	permission := &drive.Permission{Type: "anyone", Role: role}
+	_, err := retryWithBackoff("share the spreadsheet", func() (*drive.Permission, error) {
+		return w.drive.Permissions.Create(spreadsheetID, permission).Fields("id").Do()
+	})
+	return err
+}
+
+// ForSpreadsheet returns a writer for the spreadsheet with the given ID using w's
+// credentials, or w itself when spreadsheetID is empty
+func (w *Writer) ForSpreadsheet(spreadsheetID string) *Writer {
+	if spreadsheetID == "" || spreadsheetID == w.spreadsheetID {
+		return w
+	}
+	other := *w
+	other.spreadsheetID = spreadsheetID
+	return &other
+}
+
+// SpreadsheetURL returns the link opening the spreadsheet with the given ID
+func SpreadsheetURL(spreadsheetID string) string {
+	return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit", spreadsheetID)
+}
@@ -0,0 +1,74 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestCreateSpreadsheetSharesByLink(t *testing.T) {
+	var created string
+	var shared *drive.Permission
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/v4/spreadsheets"):
+			var spreadsheet sheets.Spreadsheet
+			json.NewDecoder(r.Body).Decode(&spreadsheet)
+			created = spreadsheet.Properties.Title
+			fmt.Fprint(w, `{"spreadsheetId": "new123"}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/files/new123/permissions"):
+			shared = new(drive.Permission)
+			json.NewDecoder(r.Body).Decode(shared)
+			fmt.Fprint(w, `{"id": "anyoneWithLink"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sheetsService, err := sheets.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("sheets.NewService() error = %v", err)
+	}
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	w := &Writer{service: sheetsService, drive: driveService, spreadsheetID: "shared", settings: Settings{NewSpreadsheetSharing: newSpreadsheetView}}
+
+	id, err := w.CreateSpreadsheet("Airbnb request #7")
+	if err != nil || id != "new123" {
+		t.Fatalf("CreateSpreadsheet() = %q, %v; want new123", id, err)
+	}
+	if created != "Airbnb request #7" {
+		t.Errorf("created spreadsheet titled %q", created)
+	}
+	if shared == nil || shared.Type != "anyone" || shared.Role != "reader" {
+		t.Errorf("shared with %+v, want anyone as reader", shared)
+	}
+}
+
+func TestForSpreadsheet(t *testing.T) {
+	w := &Writer{spreadsheetID: "shared", serviceAccountEmail: "bot@example.iam.gserviceaccount.com"}
+
+	if got := w.ForSpreadsheet(""); got != w {
+		t.Error("ForSpreadsheet(\"\") returned a new writer, want the shared one")
+	}
+	other := w.ForSpreadsheet("own123")
+	if other.spreadsheetID != "own123" || other.serviceAccountEmail != w.serviceAccountEmail {
+		t.Errorf("ForSpreadsheet(own123) = %+v", other)
+	}
+	if w.spreadsheetID != "shared" {
+		t.Errorf("ForSpreadsheet changed the shared writer to %q", w.spreadsheetID)
+	}
+}
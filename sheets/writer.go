@@ -3,25 +3,54 @@ package sheets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
+	neturl "net/url"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"bnb-fetcher/models"
+	"bnb-fetcher/sanitize"
 
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // Writer handles writing listings to Google Sheets
 type Writer struct {
-	service       *sheets.Service
-	spreadsheetID string
+	service             *sheets.Service
+	drive               *drive.Service // shares the spreadsheets made by CreateSpreadsheet
+	spreadsheetID       string
+	serviceAccountEmail string
+	settings            Settings
+}
+
+// Settings are the sheet options taken from the environment. The zero value uses the
+// defaults.
+type Settings struct {
+	NewSpreadsheetSharing string // how CreateSpreadsheet shares, see NEW_SPREADSHEET_SHARING
+	Metadata              string // where new tabs keep their metadata, see SHEET_METADATA
+}
+
+// SettingsFromEnv reads the sheet settings from NEW_SPREADSHEET_SHARING and SHEET_METADATA
+func SettingsFromEnv() Settings {
+	return Settings{
+		NewSpreadsheetSharing: newSpreadsheetSharingFromEnv(),
+		Metadata:              sheetMetadataFromEnv(),
+	}
 }
 
 // NewWriter creates a new Google Sheets writer
-func NewWriter(spreadsheetID string, credentialsPath string) (*Writer, error) {
+func NewWriter(spreadsheetID string, credentialsPath string, settings Settings) (*Writer, error) {
 	ctx := context.Background()
 
 	// Read credentials from file or environment variable
@@ -64,13 +93,83 @@ func NewWriter(spreadsheetID string, credentialsPath string) (*Writer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sheets service: %w", err)
 	}
+	driveService, err := drive.NewService(ctx, option.WithCredentialsJSON(credsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create drive service: %w", err)
+	}
+
+	// Remember the service account email so access errors can tell the user who to share the sheet with
+	serviceAccountEmail, _ := creds["client_email"].(string)
 
 	return &Writer{
-		service:       service,
-		spreadsheetID: spreadsheetID,
+		service:             service,
+		drive:               driveService,
+		spreadsheetID:       spreadsheetID,
+		serviceAccountEmail: serviceAccountEmail,
+		settings:            settings,
 	}, nil
 }
 
+// ServiceAccountEmail returns the client_email from the service account credentials
+func (w *Writer) ServiceAccountEmail() string {
+	return w.serviceAccountEmail
+}
+
+// VerifyAccess checks that the service account can open and edit the spreadsheet.
+// It reads the spreadsheet title and writes the same title back, so a sheet that is
+// not shared (or shared read-only) fails here instead of deep inside a request.
+func (w *Writer) VerifyAccess() error {
+	shareWith := w.serviceAccountEmail
+	if shareWith == "" {
+		shareWith = "the service account"
+	}
+
+	spreadsheet, err := retryWithBackoff("read the spreadsheet title", func() (*sheets.Spreadsheet, error) {
+		return w.service.Spreadsheets.Get(w.spreadsheetID).Fields("properties.title").Do()
+	})
+	if err != nil {
+		return accessError("cannot open", w.spreadsheetID, shareWith, err)
+	}
+
+	title := ""
+	if spreadsheet.Properties != nil {
+		title = spreadsheet.Properties.Title
+	}
+
+	// No-op metadata update: requires edit permission but changes nothing
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				UpdateSpreadsheetProperties: &sheets.UpdateSpreadsheetPropertiesRequest{
+					Properties: &sheets.SpreadsheetProperties{Title: title},
+					Fields:     "title",
+				},
+			},
+		},
+	}
+	if _, err := w.batchUpdate(batchUpdateRequest); err != nil {
+		return accessError("no write access to", w.spreadsheetID, shareWith, err)
+	}
+
+	return nil
+}
+
+// accessError explains a failed VerifyAccess call with the fix its status code points to:
+// a missing spreadsheet means a wrong SPREADSHEET_URL and rejected credentials a bad key,
+// anything else is most likely a sheet not shared with the service account.
+func accessError(action string, spreadsheetID string, shareWith string, err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			return fmt.Errorf("%s spreadsheet %s: it does not exist, check SPREADSHEET_URL: %w", action, spreadsheetID, err)
+		case http.StatusUnauthorized:
+			return fmt.Errorf("%s spreadsheet %s: the credentials of %s were rejected, check GOOGLE_SHEETS_CREDENTIALS: %w", action, spreadsheetID, shareWith, err)
+		}
+	}
+	return fmt.Errorf("%s spreadsheet %s, share the sheet with %s as Editor: %w", action, spreadsheetID, shareWith, err)
+}
+
 // WriteListings writes listings to Google Sheets
 // If clearFirst is true, clears existing data before writing
 func (w *Writer) WriteListings(listings []models.Listing, clearFirst bool) error {
@@ -83,49 +182,11 @@ func (w *Writer) WriteListings(listings []models.Listing, clearFirst bool) error
 	var values [][]interface{}
 
 	// Add header row
-	header := []interface{}{"Title", "Link", "Price", "Currency", "Rating", "Review Count", "Page Number", "Link #", "Price Range",
-		"Superhost", "Guest Favorite", "Bedrooms", "Bathrooms", "Beds", "Description", "House Rules", "Newest Review Date"}
-	values = append(values, header)
+	values = append(values, headerRow())
 
 	// Add listing rows
 	for _, listing := range listings {
-		var newestReviewDate interface{}
-		if listing.NewestReviewDate != nil {
-			newestReviewDate = listing.NewestReviewDate.Format("2006-01-02")
-		}
-
-		// Format link number (empty if 0 for backwards compatibility)
-		var linkNumber interface{}
-		if listing.LinkNumber > 0 {
-			linkNumber = listing.LinkNumber
-		}
-
-		// Format price range label
-		var priceRangeLabel interface{}
-		if listing.PriceRangeLabel != "" {
-			priceRangeLabel = listing.PriceRangeLabel
-		}
-
-		row := []interface{}{
-			listing.Title,
-			listing.URL,
-			listing.Price,
-			listing.Currency,
-			listing.Stars,
-			listing.ReviewCount,
-			listing.PageNumber,
-			linkNumber,
-			priceRangeLabel,
-			listing.IsSuperhost,
-			listing.IsGuestFavorite,
-			listing.Bedrooms,
-			listing.Bathrooms,
-			listing.Beds,
-			listing.Description,
-			listing.HouseRules,
-			newestReviewDate,
-		}
-		values = append(values, row)
+		values = append(values, listingRow(listing))
 	}
 
 	// Determine range (use Sheet1 by default, or first sheet)
@@ -134,7 +195,9 @@ func (w *Writer) WriteListings(listings []models.Listing, clearFirst bool) error
 	// Clear existing data if requested
 	if clearFirst {
 		clearReq := &sheets.ClearValuesRequest{}
-		_, err := w.service.Spreadsheets.Values.Clear(w.spreadsheetID, range_, clearReq).Do()
+		_, err := retryWithBackoff("clear "+range_, func() (*sheets.ClearValuesResponse, error) {
+			return w.service.Spreadsheets.Values.Clear(w.spreadsheetID, range_, clearReq).Do()
+		})
 		if err != nil {
 			log.Printf("Warning: Failed to clear existing data: %v\n", err)
 			// Continue anyway
@@ -142,15 +205,7 @@ func (w *Writer) WriteListings(listings []models.Listing, clearFirst bool) error
 	}
 
 	// Write data
-	valueRange := &sheets.ValueRange{
-		Values: values,
-	}
-
-	_, err := w.service.Spreadsheets.Values.Update(w.spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		Do()
-
-	if err != nil {
+	if err := w.writeRowsInChunks("Sheet1", 1, values); err != nil {
 		return fmt.Errorf("failed to write to sheets: %w", err)
 	}
 
@@ -167,7 +222,7 @@ func (w *Writer) AppendListings(listings []models.Listing) error {
 
 	// First, find the last row with data
 	range_ := "Sheet1!A:A" // Check column A for last row
-	resp, err := w.service.Spreadsheets.Values.Get(w.spreadsheetID, range_).Do()
+	resp, err := w.getValues(range_)
 	if err != nil {
 		return fmt.Errorf("failed to read existing data: %w", err)
 	}
@@ -181,56 +236,11 @@ func (w *Writer) AppendListings(listings []models.Listing) error {
 	// Prepare data (no header when appending)
 	var values [][]interface{}
 	for _, listing := range listings {
-		var newestReviewDate interface{}
-		if listing.NewestReviewDate != nil {
-			newestReviewDate = listing.NewestReviewDate.Format("2006-01-02")
-		}
-
-		// Format link number (empty if 0 for backwards compatibility)
-		var linkNumber interface{}
-		if listing.LinkNumber > 0 {
-			linkNumber = listing.LinkNumber
-		}
-
-		// Format price range label
-		var priceRangeLabel interface{}
-		if listing.PriceRangeLabel != "" {
-			priceRangeLabel = listing.PriceRangeLabel
-		}
-
-		row := []interface{}{
-			listing.Title,
-			listing.URL,
-			listing.Price,
-			listing.Currency,
-			listing.Stars,
-			listing.ReviewCount,
-			listing.PageNumber,
-			linkNumber,
-			priceRangeLabel,
-			listing.IsSuperhost,
-			listing.IsGuestFavorite,
-			listing.Bedrooms,
-			listing.Bathrooms,
-			listing.Beds,
-			listing.Description,
-			listing.HouseRules,
-			newestReviewDate,
-		}
-		values = append(values, row)
+		values = append(values, listingRow(listing))
 	}
 
 	// Write to the next row
-	updateRange := fmt.Sprintf("Sheet1!A%d", nextRow)
-	valueRange := &sheets.ValueRange{
-		Values: values,
-	}
-
-	_, err = w.service.Spreadsheets.Values.Update(w.spreadsheetID, updateRange, valueRange).
-		ValueInputOption("RAW").
-		Do()
-
-	if err != nil {
+	if err := w.writeRowsInChunks("Sheet1", nextRow, values); err != nil {
 		return fmt.Errorf("failed to append to sheets: %w", err)
 	}
 
@@ -240,20 +250,75 @@ func (w *Writer) AppendListings(listings []models.Listing) error {
 
 // CreateSheetAndWriteListings creates a new sheet and writes listings to it
 // The sheet is inserted at the beginning (index 0) of the spreadsheet
-// url and filterInfo are optional - if provided, they will be added as metadata in the first row
+// url and filterInfo are optional - if provided, they will be added as metadata (see SHEET_METADATA)
 // unfilteredListings will be added at the bottom of the sheet after filtered listings
 // Returns the sheet name and sheet ID (gid) that was created
 func (w *Writer) CreateSheetAndWriteListings(sheetName string, listings []models.Listing, unfilteredListings []models.Listing, url string, filterInfo string) (string, int64, error) {
 	// Sanitize sheet name (Google Sheets has restrictions)
 	sheetName = sanitizeSheetName(sheetName)
-	if len(sheetName) > 100 {
-		sheetName = sheetName[:100]
+	sheetID, err := w.addSheet(sheetName)
+	if err != nil {
+		return "", 0, err
 	}
 
-	// Determine the index for the new sheet (0 = beginning)
-	insertIndex := int64(0)
+	// Metadata with URL and filter information if provided, and aggregates over the
+	// listings, above the header or in its note (see SHEET_METADATA)
+	values, note := w.headerArea(headerRow(), url, "", filterInfo, summaryRow(listings))
+
+	// Add listing rows, the unfiltered ones last
+	for _, listing := range listings {
+		values = append(values, listingRow(listing))
+	}
+	for _, listing := range unfilteredListings {
+		values = append(values, listingRow(listing))
+	}
+
+	// Write to the new sheet (metadata and header go out with the first chunk)
+	if err := w.writeRowsInChunks(sheetName, 1, values); err != nil {
+		return "", 0, fmt.Errorf("failed to write to sheet: %w", err)
+	}
+	if note != "" {
+		if err := w.setHeaderNote(sheetID, note); err != nil {
+			return "", 0, err
+		}
+	}
+
+	log.Printf("Successfully wrote %d listings to sheet '%s'\n", len(listings), sheetName)
+	return sheetName, sheetID, nil
+}
 
-	// Create the sheet with insertion index
+// CreateEmptySheet creates a new sheet at index 0 with metadata, summary placeholder and header only (no listing data).
+// The metadata and summary go in rows above the header or in its note, see SHEET_METADATA.
+// dates describes the stay dates the prices are for, see metadataRows.
+// Returns the sheet name and sheet ID (gid).
+func (w *Writer) CreateEmptySheet(sheetName string, url string, dates string, filterInfo string) (string, int64, error) {
+	sheetName = sanitizeSheetName(sheetName)
+	sheetID, err := w.addSheet(sheetName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Placeholder until WriteSummary fills in the aggregates
+	values, note := w.headerArea(headerRow(), url, dates, filterInfo, []interface{}{summaryLabel, "pending"})
+
+	range_ := fmt.Sprintf("%s!A1", sheetName)
+	valueRange := &sheets.ValueRange{Values: values}
+	_, err = w.updateValues(range_, valueRange, "RAW")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write header to sheet: %w", err)
+	}
+	if note != "" {
+		if err := w.setHeaderNote(sheetID, note); err != nil {
+			return "", 0, err
+		}
+	}
+
+	return sheetName, sheetID, nil
+}
+
+// addSheet creates an empty sheet at index 0 and returns its sheet ID (gid)
+func (w *Writer) addSheet(sheetName string) (int64, error) {
+	insertIndex := int64(0)
 	addSheetRequest := &sheets.AddSheetRequest{
 		Properties: &sheets.SheetProperties{
 			Title: sheetName,
@@ -263,213 +328,537 @@ func (w *Writer) CreateSheetAndWriteListings(sheetName string, listings []models
 
 	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
 		Requests: []*sheets.Request{
-			{
-				AddSheet: addSheetRequest,
-			},
+			{AddSheet: addSheetRequest},
 		},
 	}
 
-	batchUpdateResp, err := w.service.Spreadsheets.BatchUpdate(w.spreadsheetID, batchUpdateRequest).Do()
+	// Adding a sheet twice fails on the duplicate name, so before trying again look for
+	// the sheet a failed attempt may have added anyway
+	attempted := false
+	sheetID, err := retryWithBackoff("create sheet "+sheetName, func() (int64, error) {
+		if attempted {
+			if sheetID, err := w.GetSheetIDByName(sheetName); err == nil {
+				return sheetID, nil
+			}
+		}
+		attempted = true
+		batchUpdateResp, err := w.service.Spreadsheets.BatchUpdate(w.spreadsheetID, batchUpdateRequest).Do()
+		if err != nil {
+			return 0, err
+		}
+		if len(batchUpdateResp.Replies) > 0 && batchUpdateResp.Replies[0].AddSheet != nil {
+			return batchUpdateResp.Replies[0].AddSheet.Properties.SheetId, nil
+		}
+		return 0, nil
+	})
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to create sheet: %w", err)
+		return 0, fmt.Errorf("failed to create sheet: %w", err)
 	}
 
-	// Get the sheet ID from the response
-	var sheetID int64
-	if len(batchUpdateResp.Replies) > 0 && batchUpdateResp.Replies[0].AddSheet != nil {
-		sheetID = batchUpdateResp.Replies[0].AddSheet.Properties.SheetId
-	}
+	log.Printf("Created empty sheet '%s' with ID %d at index %d\n", sheetName, sheetID, insertIndex)
+	return sheetID, nil
+}
 
-	log.Printf("Created sheet '%s' with ID %d at index %d\n", sheetName, sheetID, insertIndex)
+// metadataRows returns the optional metadata row with the search URL, the stay dates and
+// the filters. dates tells whether prices are for specific dates or "from" estimates.
+func metadataRows(url string, dates string, filterInfo string) [][]interface{} {
+	if url == "" && dates == "" && filterInfo == "" {
+		return nil
+	}
+	metadataRow := []interface{}{"URL", url}
+	if dates != "" {
+		metadataRow = append(metadataRow, "Dates", dates)
+	}
+	if filterInfo != "" {
+		metadataRow = append(metadataRow, "Filters", filterInfo)
+	}
+	return [][]interface{}{metadataRow}
+}
 
-	// Prepare data
-	var values [][]interface{}
+// WriteSummary replaces the summary row of a sheet created by CreateEmptySheet with
+// aggregates computed from listings, or the summary line of its header note for sheets
+// with the header in row 1. Other sheets without a summary row are left unchanged.
+func (w *Writer) WriteSummary(sheetName string, listings []models.Listing) error {
+	// The summary row sits in the header area, below the optional metadata row
+	resp, err := w.getValues(fmt.Sprintf("%s!A1:A5", sheetName))
+	if err != nil {
+		return fmt.Errorf("failed to read sheet header area: %w", err)
+	}
 
-	// Add metadata row with URL and filter information if provided
-	if url != "" || filterInfo != "" {
-		metadataRow := []interface{}{"URL", url}
-		if filterInfo != "" {
-			metadataRow = append(metadataRow, "Filters", filterInfo)
+	summaryRowNumber := 0
+	for i, row := range resp.Values {
+		if len(row) > 0 && fmt.Sprint(row[0]) == summaryLabel {
+			summaryRowNumber = i + 1
+			break
 		}
-		values = append(values, metadataRow)
 	}
-
-	// Add header row
-	header := []interface{}{"Title", "Link", "Price", "Currency", "Rating", "Review Count", "Page Number", "Link #", "Price Range",
-		"Superhost", "Guest Favorite", "Bedrooms", "Bathrooms", "Beds", "Description", "House Rules", "Newest Review Date"}
-	values = append(values, header)
-
-	// Add listing rows
-	for _, listing := range listings {
-		var newestReviewDate interface{}
-		if listing.NewestReviewDate != nil {
-			newestReviewDate = listing.NewestReviewDate.Format("2006-01-02")
+	if summaryRowNumber == 0 {
+		if len(resp.Values) > 0 && findLayout(resp.Values[:1]) != nil {
+			// Header in row 1: created with SHEET_METADATA=note, the summary is in its note
+			if err := w.writeSummaryNote(sheetName, summaryRow(listings)); err != nil {
+				return fmt.Errorf("failed to write summary: %w", err)
+			}
+			return nil
 		}
+		log.Printf("No summary row found in sheet '%s', skipping summary\n", sheetName)
+		return nil
+	}
 
-		// Format link number (empty if 0 for backwards compatibility)
-		var linkNumber interface{}
-		if listing.LinkNumber > 0 {
-			linkNumber = listing.LinkNumber
-		}
+	range_ := fmt.Sprintf("%s!A%d", sheetName, summaryRowNumber)
+	valueRange := &sheets.ValueRange{Values: [][]interface{}{summaryRow(listings)}}
+	_, err = w.updateValues(range_, valueRange, "RAW")
+	if err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
 
-		// Format price range label
-		var priceRangeLabel interface{}
-		if listing.PriceRangeLabel != "" {
-			priceRangeLabel = listing.PriceRangeLabel
-		}
+// SortListingRows sorts the listing rows below the header row of a sheet by the header
+// column with the given label. Rows above the header (metadata, summary) are left in place.
+func (w *Writer) SortListingRows(sheetName string, column string, descending bool) error {
+	// The column is looked up in the tab's own header: tabs of older versions may have
+	// their columns in another order
+	layout, err := w.readLayout(sheetName)
+	if err != nil {
+		return err
+	}
+	columnIndex := slices.Index(layout.headers, column)
+	if columnIndex < 0 {
+		return fmt.Errorf("unknown sort column %q", column)
+	}
 
-		row := []interface{}{
-			listing.Title,
-			listing.URL,
-			listing.Price,
-			listing.Currency,
-			listing.Stars,
-			listing.ReviewCount,
-			listing.PageNumber,
-			linkNumber,
-			priceRangeLabel,
-			listing.IsSuperhost,
-			listing.IsGuestFavorite,
-			listing.Bedrooms,
-			listing.Bathrooms,
-			listing.Beds,
-			listing.Description,
-			listing.HouseRules,
-			newestReviewDate,
-		}
-		values = append(values, row)
+	sheetID, err := w.GetSheetIDByName(sheetName)
+	if err != nil {
+		return err
 	}
 
-	// Write to the new sheet
-	range_ := fmt.Sprintf("%s!A1", sheetName)
-	valueRange := &sheets.ValueRange{
-		Values: values,
+	sortOrder := "ASCENDING"
+	if descending {
+		sortOrder = "DESCENDING"
 	}
+	request := &sheets.Request{
+		SortRange: &sheets.SortRangeRequest{
+			Range: &sheets.GridRange{
+				SheetId:          sheetID,
+				StartRowIndex:    int64(layout.headerRow), // the row below the header, 0-based
+				StartColumnIndex: 0,
+				EndColumnIndex:   int64(len(layout.headers)),
+				ForceSendFields:  []string{"SheetId", "StartColumnIndex"},
+			},
+			SortSpecs: []*sheets.SortSpec{{
+				DimensionIndex:  int64(columnIndex),
+				SortOrder:       sortOrder,
+				ForceSendFields: []string{"DimensionIndex"},
+			}},
+		},
+	}
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{Requests: []*sheets.Request{request}}
+	if _, err := w.batchUpdate(batchUpdateRequest); err != nil {
+		return fmt.Errorf("failed to sort sheet: %w", err)
+	}
+	return nil
+}
 
-	_, err = w.service.Spreadsheets.Values.Update(w.spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		Do()
+// ErrSheetNotFound is returned by GetSheetIDByName when no sheet has the title
+var ErrSheetNotFound = errors.New("sheet not found")
 
+// GetSheetIDByName returns the numeric sheet ID (gid) of the sheet with the given title,
+// for tabs whose gid wasn't kept from the AddSheet reply. Only sheet properties are
+// fetched, so the lookup stays cheap on spreadsheets with hundreds of tabs.
+func (w *Writer) GetSheetIDByName(sheetName string) (int64, error) {
+	spreadsheet, err := w.listSheets()
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to write to sheet: %w", err)
+		return 0, fmt.Errorf("failed to list sheets: %w", err)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.Title == sheetName {
+			return sheet.Properties.SheetId, nil
+		}
 	}
+	return 0, fmt.Errorf("%w: '%s'", ErrSheetNotFound, sheetName)
+}
 
-	log.Printf("Successfully wrote %d listings to sheet '%s'\n", len(listings), sheetName)
-	return sheetName, sheetID, nil
+// listSheets returns the spreadsheet with only the ID and title of each sheet
+func (w *Writer) listSheets() (*sheets.Spreadsheet, error) {
+	return retryWithBackoff("list sheets", func() (*sheets.Spreadsheet, error) {
+		return w.service.Spreadsheets.Get(w.spreadsheetID).Fields("sheets.properties(sheetId,title)").Do()
+	})
 }
 
-// CreateEmptySheet creates a new sheet at index 0 with metadata row and header row only (no listing data).
-// Returns the sheet name and sheet ID (gid).
-func (w *Writer) CreateEmptySheet(sheetName string, url string, filterInfo string) (string, int64, error) {
-	sheetName = sanitizeSheetName(sheetName)
-	if len(sheetName) > 100 {
-		sheetName = sheetName[:100]
-	}
+// batchUpdate sends a BatchUpdate to the spreadsheet, retrying transient errors. Only
+// for requests that can be repeated: setting properties, notes or the sort order.
+func (w *Writer) batchUpdate(request *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	return retryWithBackoff("update the spreadsheet", func() (*sheets.BatchUpdateSpreadsheetResponse, error) {
+		return w.service.Spreadsheets.BatchUpdate(w.spreadsheetID, request).Do()
+	})
+}
 
-	insertIndex := int64(0)
-	addSheetRequest := &sheets.AddSheetRequest{
-		Properties: &sheets.SheetProperties{
-			Title: sheetName,
-			Index: insertIndex,
-		},
-	}
+// deleteSheets sends a BatchUpdate deleting sheets. Deleting a sheet a failed attempt
+// already deleted fails, so only rate limiting is retried.
+func (w *Writer) deleteSheets(request *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	return retryRateLimited("delete sheets", func() (*sheets.BatchUpdateSpreadsheetResponse, error) {
+		return w.service.Spreadsheets.BatchUpdate(w.spreadsheetID, request).Do()
+	})
+}
 
-	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
-		Requests: []*sheets.Request{
-			{AddSheet: addSheetRequest},
-		},
-	}
+// getValues reads the values of an A1 range, retrying transient errors
+func (w *Writer) getValues(range_ string) (*sheets.ValueRange, error) {
+	return retryWithBackoff("read "+range_, func() (*sheets.ValueRange, error) {
+		return w.service.Spreadsheets.Values.Get(w.spreadsheetID, range_).Do()
+	})
+}
 
-	batchUpdateResp, err := w.service.Spreadsheets.BatchUpdate(w.spreadsheetID, batchUpdateRequest).Do()
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to create sheet: %w", err)
+// updateValues writes values at an A1 range with the given value input option (RAW or
+// USER_ENTERED), retrying transient errors
+func (w *Writer) updateValues(range_ string, values *sheets.ValueRange, inputOption string) (*sheets.UpdateValuesResponse, error) {
+	return retryWithBackoff("write "+range_, func() (*sheets.UpdateValuesResponse, error) {
+		return w.service.Spreadsheets.Values.Update(w.spreadsheetID, range_, values).ValueInputOption(inputOption).Do()
+	})
+}
+
+// AppendListingsToSheet appends listing rows to a named sheet. Uses the Append API to add rows after existing content.
+func (w *Writer) AppendListingsToSheet(sheetName string, listings []models.Listing) error {
+	if len(listings) == 0 {
+		return nil
 	}
 
-	var sheetID int64
-	if len(batchUpdateResp.Replies) > 0 && batchUpdateResp.Replies[0].AddSheet != nil {
-		sheetID = batchUpdateResp.Replies[0].AddSheet.Properties.SheetId
+	rows := make([]map[string]interface{}, len(listings))
+	for i, listing := range listings {
+		rows[i] = listingCells(listing)
+	}
+	if err := w.appendArranged(sheetName, rows, headerRow()); err != nil {
+		return err
 	}
 
-	log.Printf("Created empty sheet '%s' with ID %d at index %d\n", sheetName, sheetID, insertIndex)
+	log.Printf("Appended %d listings to sheet '%s'\n", len(listings), sheetName)
+	return nil
+}
 
-	var values [][]interface{}
-	if url != "" || filterInfo != "" {
-		metadataRow := []interface{}{"URL", url}
-		if filterInfo != "" {
-			metadataRow = append(metadataRow, "Filters", filterInfo)
-		}
-		values = append(values, metadataRow)
-	}
+// runAtHeader labels the column that tells the runs apart in a shared tab
+const runAtHeader = "Run At"
 
-	header := []interface{}{"Title", "Link", "Price", "Currency", "Rating", "Review Count", "Page Number", "Link #", "Price Range",
-		"Superhost", "Guest Favorite", "Bedrooms", "Bathrooms", "Beds", "Description", "House Rules", "Newest Review Date"}
-	values = append(values, header)
+// SavedSearchTabName returns the name of the tab all runs of a saved search append to
+func SavedSearchTabName(savedSearchID int) string {
+	return fmt.Sprintf("Saved_Search_%d", savedSearchID)
+}
 
-	range_ := fmt.Sprintf("%s!A1", sheetName)
+// EnsureRunSheet returns the sheet ID (gid) of the named tab that runs append to with
+// AppendRunListingsToSheet, creating it at index 0 with metadata and header if it
+// doesn't exist yet. The tab has no summary row: it would only describe one run.
+func (w *Writer) EnsureRunSheet(sheetName string, url string, dates string, filterInfo string) (string, int64, error) {
+	sheetName = sanitizeSheetName(sheetName)
+	sheetID, err := w.GetSheetIDByName(sheetName)
+	if err == nil {
+		log.Printf("Appending to existing sheet '%s'\n", sheetName)
+		return sheetName, sheetID, nil
+	}
+	if !errors.Is(err, ErrSheetNotFound) {
+		return "", 0, err
+	}
+
+	sheetID, err = w.addSheet(sheetName)
+	if err != nil {
+		return "", 0, err
+	}
+	values, note := w.headerArea(runHeaderRow(), url, dates, filterInfo, nil)
 	valueRange := &sheets.ValueRange{Values: values}
-	_, err = w.service.Spreadsheets.Values.Update(w.spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		Do()
+	_, err = w.updateValues(fmt.Sprintf("%s!A1", sheetName), valueRange, "RAW")
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to write header to sheet: %w", err)
 	}
-
+	if note != "" {
+		if err := w.setHeaderNote(sheetID, note); err != nil {
+			return "", 0, err
+		}
+	}
 	return sheetName, sheetID, nil
 }
 
-// AppendListingsToSheet appends listing rows to a named sheet. Uses the Append API to add rows after existing content.
-func (w *Writer) AppendListingsToSheet(sheetName string, listings []models.Listing) error {
+// AppendRunListingsToSheet appends listing rows stamped with the run time to a tab
+// created by EnsureRunSheet
+func (w *Writer) AppendRunListingsToSheet(sheetName string, listings []models.Listing, runAt time.Time) error {
 	if len(listings) == 0 {
 		return nil
 	}
 
-	var values [][]interface{}
+	rows := make([]map[string]interface{}, len(listings))
+	for i, listing := range listings {
+		rows[i] = listingCells(listing)
+		rows[i][runAtHeader] = runAt.Format("2006-01-02 15:04")
+	}
+	if err := w.appendArranged(sheetName, rows, runHeaderRow()); err != nil {
+		return err
+	}
+
+	log.Printf("Appended %d listings of the %s run to sheet '%s'\n", len(listings), runAt.Format("2006-01-02 15:04"), sheetName)
+	return nil
+}
+
+// runHeaderRow returns the column headers of tabs shared by several runs: the listing
+// columns followed by the run time
+func runHeaderRow() []interface{} {
+	return append(headerRow(), runAtHeader)
+}
+
+// appendRows appends rows of the given column count after the existing content of a sheet
+func (w *Writer) appendRows(sheetName string, values [][]interface{}, columns int) error {
+	// Each Append lands after the previous one, so chunks can be sent as-is. A repeated
+	// Append would add the rows twice, so only rate limiting is retried.
+	range_ := fmt.Sprintf("%s!A:%s", sheetName, columnLetter(columns))
+	for start := 0; start < len(values); start += maxRowsPerWrite {
+		end := start + maxRowsPerWrite
+		if end > len(values) {
+			end = len(values)
+		}
+		valueRange := &sheets.ValueRange{Values: userEnteredRows(values[start:end])}
+		_, err := retryRateLimited("append to "+range_, func() (*sheets.AppendValuesResponse, error) {
+			return w.service.Spreadsheets.Values.Append(w.spreadsheetID, range_, valueRange).
+				ValueInputOption("USER_ENTERED").
+				InsertDataOption("INSERT_ROWS").
+				Do()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to append to sheet (rows %d-%d): %w", start+1, end, err)
+		}
+	}
+	return nil
+}
+
+// formula is a cell value written as a formula. All other strings are stored as plain
+// text, so scraped titles starting with "=" or "+" can't turn into formulas.
+type formula string
+
+// imageCell returns an IMAGE formula showing url in the cell, or nil for an empty cell
+func imageCell(url string) interface{} {
+	if url == "" {
+		return nil
+	}
+	return formula(fmt.Sprintf(`=IMAGE("%s")`, strings.ReplaceAll(url, `"`, `""`)))
+}
+
+// userEnteredRows prepares rows for the USER_ENTERED input option, which parses cells as
+// if they were typed in: formulas are kept, strings get a leading apostrophe so they stay
+// text exactly like RAW would store them (the apostrophe is not part of the value).
+func userEnteredRows(values [][]interface{}) [][]interface{} {
+	rows := make([][]interface{}, len(values))
+	for i, row := range values {
+		rows[i] = make([]interface{}, len(row))
+		for j, cell := range row {
+			switch v := cell.(type) {
+			case formula:
+				rows[i][j] = string(v)
+			case string:
+				if v != "" {
+					rows[i][j] = "'" + v
+				} else {
+					rows[i][j] = v
+				}
+			default:
+				rows[i][j] = cell
+			}
+		}
+	}
+	return rows
+}
+
+// summaryLabel is the first cell of the summary row, used to find it again
+const summaryLabel = "Summary"
+
+// summaryRow returns label/value pairs with aggregates over listings: count, average and
+// median price, average rating and superhost share. Zero prices and ratings mean
+// "unknown" and are left out of the averages; aggregates with no data are left empty.
+func summaryRow(listings []models.Listing) []interface{} {
+	var prices []float64
+	var ratingSum float64
+	var ratingCount, superhostCount int
 	for _, listing := range listings {
-		var newestReviewDate interface{}
-		if listing.NewestReviewDate != nil {
-			newestReviewDate = listing.NewestReviewDate.Format("2006-01-02")
+		if listing.Price > 0 {
+			prices = append(prices, listing.Price)
+		}
+		if listing.Stars > 0 {
+			ratingSum += listing.Stars
+			ratingCount++
 		}
-		var linkNumber interface{}
-		if listing.LinkNumber > 0 {
-			linkNumber = listing.LinkNumber
+		if listing.IsSuperhost {
+			superhostCount++
 		}
-		var priceRangeLabel interface{}
-		if listing.PriceRangeLabel != "" {
-			priceRangeLabel = listing.PriceRangeLabel
+	}
+
+	var avgPrice, medianPrice, avgRating, superhostPct interface{}
+	if len(prices) > 0 {
+		var sum float64
+		for _, price := range prices {
+			sum += price
 		}
-		row := []interface{}{
-			listing.Title,
-			listing.URL,
-			listing.Price,
-			listing.Currency,
-			listing.Stars,
-			listing.ReviewCount,
-			listing.PageNumber,
-			linkNumber,
-			priceRangeLabel,
-			listing.IsSuperhost,
-			listing.IsGuestFavorite,
-			listing.Bedrooms,
-			listing.Bathrooms,
-			listing.Beds,
-			listing.Description,
-			listing.HouseRules,
-			newestReviewDate,
+		avgPrice = math.Round(sum/float64(len(prices))*100) / 100
+
+		sort.Float64s(prices)
+		mid := len(prices) / 2
+		if len(prices)%2 == 0 {
+			medianPrice = math.Round((prices[mid-1]+prices[mid])/2*100) / 100
+		} else {
+			medianPrice = prices[mid]
 		}
-		values = append(values, row)
+	}
+	if ratingCount > 0 {
+		avgRating = math.Round(ratingSum/float64(ratingCount)*100) / 100
+	}
+	if len(listings) > 0 {
+		superhostPct = math.Round(float64(superhostCount)/float64(len(listings))*1000) / 10
 	}
 
-	range_ := fmt.Sprintf("%s!A:Q", sheetName)
-	valueRange := &sheets.ValueRange{Values: values}
-	_, err := w.service.Spreadsheets.Values.Append(w.spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Do()
+	return []interface{}{
+		summaryLabel,
+		"Listings", len(listings),
+		"Avg Price", avgPrice,
+		"Median Price", medianPrice,
+		"Avg Rating", avgRating,
+		"Superhost %", superhostPct,
+	}
+}
+
+// columnLetter converts a 1-based column number to its A1 letter (1 -> A, 27 -> AA)
+func columnLetter(column int) string {
+	letters := ""
+	for column > 0 {
+		column--
+		letters = string(rune('A'+column%26)) + letters
+		column /= 26
+	}
+	return letters
+}
+
+// sheetTimestampPattern matches the creation timestamp suffix of generated sheet names
+// (e.g. "Request_12_20250101_093000" or "CLI_20250101_093000")
+var sheetTimestampPattern = regexp.MustCompile(`_(\d{8}_\d{6})$`)
+
+// sheetCreatedAt extracts the creation time from a generated sheet name
+func sheetCreatedAt(title string) (time.Time, bool) {
+	match := sheetTimestampPattern.FindStringSubmatch(title)
+	if match == nil {
+		return time.Time{}, false
+	}
+	createdAt, err := time.ParseInLocation("20060102_150405", match[1], time.Local)
 	if err != nil {
-		return fmt.Errorf("failed to append to sheet: %w", err)
+		return time.Time{}, false
 	}
+	return createdAt, true
+}
 
-	log.Printf("Appended %d listings to sheet '%s'\n", len(listings), sheetName)
+// DeleteSheetsOlderThan deletes generated sheets whose name timestamp is before cutoff.
+// Sheet1, sheets without a timestamp and sheets listed in keep are never deleted, and
+// at least one sheet is always left in the spreadsheet. Returns the titles of the sheets
+// deleted.
+func (w *Writer) DeleteSheetsOlderThan(cutoff time.Time, keep map[string]bool) ([]string, error) {
+	spreadsheet, err := w.listSheets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sheets: %w", err)
+	}
+
+	var requests []*sheets.Request
+	var titles []string
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil {
+			continue
+		}
+		title := sheet.Properties.Title
+		if title == "Sheet1" || keep[title] {
+			continue
+		}
+		createdAt, ok := sheetCreatedAt(title)
+		if !ok || !createdAt.Before(cutoff) {
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			DeleteSheet: &sheets.DeleteSheetRequest{SheetId: sheet.Properties.SheetId},
+		})
+		titles = append(titles, title)
+	}
+
+	// A spreadsheet must keep at least one sheet
+	if len(requests) == len(spreadsheet.Sheets) {
+		requests = requests[:len(requests)-1]
+		titles = titles[:len(titles)-1]
+	}
+
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	if _, err := w.deleteSheets(batchUpdateRequest); err != nil {
+		return nil, fmt.Errorf("failed to delete sheets: %w", err)
+	}
+
+	log.Printf("Deleted %d sheets created before %s\n", len(titles), cutoff.Format("2006-01-02 15:04"))
+	return titles, nil
+}
+
+// DeleteSheet deletes the sheet (tab) with the given gid. A spreadsheet must keep at
+// least one sheet, so deleting the last remaining one is refused.
+func (w *Writer) DeleteSheet(gid int64) error {
+	spreadsheet, err := w.listSheets()
+	if err != nil {
+		return fmt.Errorf("failed to list sheets: %w", err)
+	}
+
+	title := ""
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.SheetId == gid {
+			title = sheet.Properties.Title
+			break
+		}
+	}
+	if title == "" {
+		return fmt.Errorf("sheet with gid %d not found", gid)
+	}
+	if len(spreadsheet.Sheets) <= 1 {
+		return fmt.Errorf("sheet '%s' is the only sheet left in the spreadsheet", title)
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: gid}}},
+	}
+	if _, err := w.deleteSheets(batchUpdateRequest); err != nil {
+		return fmt.Errorf("failed to delete sheet '%s': %w", title, err)
+	}
+
+	log.Printf("Deleted sheet '%s' (gid %d)\n", title, gid)
+	return nil
+}
+
+// maxRowsPerWrite caps the rows sent in a single Values request. Requests with
+// thousands of listings and long descriptions otherwise exceed the Sheets
+// request size limit.
+const maxRowsPerWrite = 500
+
+// chunkRanges returns the A1 start cell for each chunk when writing totalRows rows
+// to sheetName beginning at startRow (1-based), chunkSize rows at a time
+func chunkRanges(sheetName string, startRow, totalRows, chunkSize int) []string {
+	var ranges []string
+	for offset := 0; offset < totalRows; offset += chunkSize {
+		ranges = append(ranges, fmt.Sprintf("%s!A%d", sheetName, startRow+offset))
+	}
+	return ranges
+}
+
+// writeRowsInChunks writes values to sheetName starting at startRow (1-based),
+// splitting them into sequential Update calls of at most maxRowsPerWrite rows
+func (w *Writer) writeRowsInChunks(sheetName string, startRow int, values [][]interface{}) error {
+	for i, range_ := range chunkRanges(sheetName, startRow, len(values), maxRowsPerWrite) {
+		start := i * maxRowsPerWrite
+		end := start + maxRowsPerWrite
+		if end > len(values) {
+			end = len(values)
+		}
+
+		// USER_ENTERED so the thumbnail IMAGE formulas are evaluated
+		valueRange := &sheets.ValueRange{Values: userEnteredRows(values[start:end])}
+		_, err := w.updateValues(range_, valueRange, "USER_ENTERED")
+		if err != nil {
+			return fmt.Errorf("failed to write rows %d-%d at %s: %w", start+1, end, range_, err)
+		}
+	}
 	return nil
 }
 
@@ -481,6 +870,8 @@ func sanitizeSheetName(name string) string {
 	for _, char := range invalidChars {
 		result = strings.ReplaceAll(result, char, "_")
 	}
+	// Sheet names are limited to 100 characters
+	result = sanitize.Truncate(result, 100)
 	// Remove leading/trailing spaces
 	result = strings.TrimSpace(result)
 	// If empty after sanitization, use default
@@ -490,27 +881,68 @@ func sanitizeSheetName(name string) string {
 	return result
 }
 
-// ExtractSpreadsheetID extracts the spreadsheet ID from a Google Sheets URL
-func ExtractSpreadsheetID(url string) string {
-	// Handle various URL formats:
-	// https://docs.google.com/spreadsheets/d/SPREADSHEET_ID/edit
-	// https://docs.google.com/spreadsheets/d/SPREADSHEET_ID/edit?usp=sharing
-	// etc.
-
-	// Find the ID between /d/ and /edit or ?
-	parts := strings.Split(url, "/d/")
-	if len(parts) < 2 {
+// spreadsheetIDPattern matches the characters spreadsheet IDs are made of
+var spreadsheetIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// minBareSpreadsheetIDLength is the length below which input without a URL shape isn't
+// taken for a spreadsheet ID; real IDs are 44 characters
+const minBareSpreadsheetIDLength = 25
+
+// ExtractSpreadsheetID extracts the spreadsheet ID from a Google Sheets URL. It handles:
+//
+//	https://docs.google.com/spreadsheets/d/ID/edit?usp=sharing   edit and sharing links
+//	https://docs.google.com/spreadsheets/d/ID#gid=0              with a fragment and no path after the ID
+//	https://docs.google.com/spreadsheets/u/1/d/ID/edit           links with an account index
+//	https://docs.google.com/spreadsheet/ccc?key=ID               legacy links
+//	https://drive.google.com/open?id=ID                          Drive links
+//	ID                                                           the bare ID
+//
+// It returns "" when there is no usable ID. Published links (/spreadsheets/d/e/2PACX-.../pubhtml)
+// carry a publishing ID the Sheets API doesn't accept, so they give "" too.
+func ExtractSpreadsheetID(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if len(rawURL) >= minBareSpreadsheetIDLength && spreadsheetIDPattern.MatchString(rawURL) {
+		return rawURL
+	}
+
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
 		return ""
 	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment != "d" || i+1 >= len(segments) {
+			continue
+		}
+		if segments[i+1] == "e" {
+			return ""
+		}
+		return validSpreadsheetID(segments[i+1])
+	}
 
-	idPart := parts[1]
-	// Remove everything after / or ?
-	if idx := strings.Index(idPart, "/"); idx != -1 {
-		idPart = idPart[:idx]
+	query := parsed.Query()
+	for _, key := range []string{"key", "id"} {
+		if id := validSpreadsheetID(query.Get(key)); id != "" {
+			return id
+		}
 	}
-	if idx := strings.Index(idPart, "?"); idx != -1 {
-		idPart = idPart[:idx]
+	return ""
+}
+
+// validSpreadsheetID returns id if it only has spreadsheet ID characters, "" otherwise
+func validSpreadsheetID(id string) string {
+	if !spreadsheetIDPattern.MatchString(id) {
+		return ""
 	}
+	return id
+}
 
-	return strings.TrimSpace(idPart)
+// TabURL returns the link opening the sheet tab with the given ID (gid) in the spreadsheet,
+// or the spreadsheet URL itself if it has no recognizable spreadsheet ID
+func TabURL(spreadsheetURL string, sheetID int64) string {
+	spreadsheetID := ExtractSpreadsheetID(spreadsheetURL)
+	if spreadsheetID == "" {
+		return spreadsheetURL
+	}
+	return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit#gid=%d", spreadsheetID, sheetID)
 }
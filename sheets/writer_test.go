@@ -0,0 +1,224 @@
+package sheets
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"bnb-fetcher/models"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestChunkRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		startRow  int
+		totalRows int
+		chunkSize int
+		want      []string
+	}{
+		{"empty", 1, 0, 500, nil},
+		{"single chunk", 1, 3, 500, []string{"Data!A1"}},
+		{"exact multiple", 1, 1000, 500, []string{"Data!A1", "Data!A501"}},
+		{"partial last chunk", 1, 1201, 500, []string{"Data!A1", "Data!A501", "Data!A1001"}},
+		{"offset start row", 42, 12, 5, []string{"Data!A42", "Data!A47", "Data!A52"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkRanges("Data", tt.startRow, tt.totalRows, tt.chunkSize)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkRanges(%d, %d, %d) = %v, want %v", tt.startRow, tt.totalRows, tt.chunkSize, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryRow(t *testing.T) {
+	listings := []models.Listing{
+		{Price: 100, Stars: 4.5, IsSuperhost: true},
+		{Price: 300, Stars: 5.0},
+		{Price: 0, Stars: 0}, // unknown price and rating
+		{Price: 200, Stars: 4.0, IsSuperhost: true},
+	}
+
+	got := summaryRow(listings)
+	want := []interface{}{
+		"Summary",
+		"Listings", 4,
+		"Avg Price", 200.0,
+		"Median Price", 200.0,
+		"Avg Rating", 4.5,
+		"Superhost %", 50.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summaryRow() = %v, want %v", got, want)
+	}
+}
+
+func TestSummaryRowEmpty(t *testing.T) {
+	got := summaryRow(nil)
+	want := []interface{}{
+		"Summary",
+		"Listings", 0,
+		"Avg Price", nil,
+		"Median Price", nil,
+		"Avg Rating", nil,
+		"Superhost %", nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summaryRow(nil) = %v, want %v", got, want)
+	}
+}
+
+func TestExtractSpreadsheetID(t *testing.T) {
+	const id = "1AbCdEfGhIjKlMnOpQrStUvWxYz0123456789_-AbCd"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"edit", "https://docs.google.com/spreadsheets/d/" + id + "/edit", id},
+		{"sharing", "https://docs.google.com/spreadsheets/d/" + id + "/edit?usp=sharing", id},
+		{"edit with gid", "https://docs.google.com/spreadsheets/d/" + id + "/edit#gid=123", id},
+		{"gid fragment without path", "https://docs.google.com/spreadsheets/d/" + id + "#gid=0", id},
+		{"query without path", "https://docs.google.com/spreadsheets/d/" + id + "?usp=sharing", id},
+		{"account index", "https://docs.google.com/spreadsheets/u/1/d/" + id + "/edit", id},
+		{"legacy key", "https://docs.google.com/spreadsheet/ccc?key=" + id + "#gid=0", id},
+		{"drive link", "https://drive.google.com/open?id=" + id, id},
+		{"bare ID", "  " + id + "\n", id},
+		{"published", "https://docs.google.com/spreadsheets/d/e/2PACX-1vQabc/pubhtml", ""},
+		{"published gid", "https://docs.google.com/spreadsheets/d/e/2PACX-1vQabc/pubhtml?gid=0&single=true", ""},
+		{"no ID", "https://docs.google.com/spreadsheets/", ""},
+		{"other site", "https://example.com/sheet", ""},
+		{"short word", "spreadsheet", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractSpreadsheetID(tt.input); got != tt.want {
+				t.Errorf("ExtractSpreadsheetID(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTabURL(t *testing.T) {
+	tests := []struct {
+		spreadsheetURL string
+		expected       string
+	}{
+		{"https://docs.google.com/spreadsheets/d/abc123/edit?usp=sharing", "https://docs.google.com/spreadsheets/d/abc123/edit#gid=42"},
+		{"https://example.com/sheet", "https://example.com/sheet"},
+	}
+
+	for _, tt := range tests {
+		if got := TabURL(tt.spreadsheetURL, 42); got != tt.expected {
+			t.Errorf("TabURL(%q, 42) = %q, want %q", tt.spreadsheetURL, got, tt.expected)
+		}
+	}
+}
+
+func TestSanitizeSheetNameMultiByte(t *testing.T) {
+	name := sanitizeSheetName(strings.Repeat("เชียงใหม่", 20))
+	if !utf8.ValidString(name) {
+		t.Fatalf("sanitizeSheetName() = %q is not valid UTF-8", name)
+	}
+	if n := utf8.RuneCountInString(name); n != 100 {
+		t.Errorf("sanitizeSheetName() has %d characters, want 100", n)
+	}
+}
+
+func TestListingRowMatchesHeader(t *testing.T) {
+	reviewed := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	listings := map[string]models.Listing{
+		"empty": {},
+		"full": {
+			Title: "Loft", URL: "https://www.airbnb.com/rooms/1", Price: 80, Currency: "EUR", PriceUSD: 86.4,
+			Stars: 4.9, ReviewCount: 120, PageNumber: 2, LinkNumber: 3, PriceRangeLabel: "$50-$100",
+			IsSuperhost: true, Bedrooms: 2, MaxGuests: 4, MinNights: 2, DiscountPercent: 10,
+			Description: "Quiet", NewestReviewDate: &reviewed, Badges: []string{"Rare find"},
+			ThumbnailURL: "https://a0.muscache.com/im/pictures/1.jpeg",
+		},
+	}
+
+	header := headerRow()
+	for name, listing := range listings {
+		if row := listingRow(listing); len(row) != len(header) {
+			t.Errorf("%s listing: row has %d cells, header has %d", name, len(row), len(header))
+		}
+	}
+}
+
+func TestRunListingRowAlignment(t *testing.T) {
+	runAt := time.Date(2026, 3, 14, 7, 30, 0, 0, time.UTC)
+	header := runHeaderRow()
+	// Arranged like AppendRunListingsToSheet does for a new run tab
+	layout := &sheetLayout{headerRow: 1}
+	for _, h := range header {
+		layout.headers = append(layout.headers, fmt.Sprint(h))
+	}
+	cells := listingCells(models.Listing{Title: "Loft", URL: "https://www.airbnb.com/rooms/1", Price: 80, Description: "Quiet"})
+	cells[runAtHeader] = runAt.Format("2006-01-02 15:04")
+	row := layout.arrange(cells)
+
+	if len(row) != len(header) {
+		t.Fatalf("run row has %d cells, header has %d", len(row), len(header))
+	}
+	if header[len(header)-1] != runAtHeader || row[len(row)-1] != "2026-03-14 07:30" {
+		t.Errorf("last column = %v / %v, want %q / the run time", header[len(header)-1], row[len(row)-1], runAtHeader)
+	}
+	// The listing columns keep the positions of one-off tabs
+	if !reflect.DeepEqual(header[:len(header)-1], headerRow()) {
+		t.Errorf("run header changed the listing columns: %v", header)
+	}
+}
+
+func TestUserEnteredRows(t *testing.T) {
+	row := listingRow(models.Listing{Title: "=HYPERLINK(\"x\")", Price: 80, ThumbnailURL: `https://a0.muscache.com/im/pictures/a"b.jpeg`})
+	got := userEnteredRows([][]interface{}{row})[0]
+
+	thumbnail := len(headerRow()) - 1
+	if want := `=IMAGE("https://a0.muscache.com/im/pictures/a""b.jpeg")`; got[thumbnail] != want {
+		t.Errorf("thumbnail cell = %v, want %q", got[thumbnail], want)
+	}
+	if got[0] != "'=HYPERLINK(\"x\")" {
+		t.Errorf("title cell = %v, want it escaped as text", got[0])
+	}
+	if got[2] != 80.0 {
+		t.Errorf("price cell = %v, want the number unchanged", got[2])
+	}
+
+	if cell := listingRow(models.Listing{Title: "Loft"})[thumbnail]; cell != nil {
+		t.Errorf("thumbnail cell without a photo = %v, want empty", cell)
+	}
+}
+
+func TestAccessError(t *testing.T) {
+	const email = "bot@project.iam.gserviceaccount.com"
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not shared", &googleapi.Error{Code: 403}, "share the sheet with " + email + " as Editor"},
+		{"wrong ID", &googleapi.Error{Code: 404}, "check SPREADSHEET_URL"},
+		{"bad key", &googleapi.Error{Code: 401}, "check GOOGLE_SHEETS_CREDENTIALS"},
+		{"network", errors.New("dial tcp: timeout"), "share the sheet with " + email},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := accessError("cannot open", "abc123", email, tt.err)
+			if !strings.Contains(err.Error(), tt.want) || !errors.Is(err, tt.err) {
+				t.Errorf("accessError() = %v, want it to mention %q and wrap the cause", err, tt.want)
+			}
+		})
+	}
+}